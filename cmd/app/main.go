@@ -1,37 +1,74 @@
-package main
-
-import (
-	"github.com/spksupakorn/Currency-Converter/config"
-	"github.com/spksupakorn/Currency-Converter/database"
-	"github.com/spksupakorn/Currency-Converter/internal/server"
-	"github.com/spksupakorn/Currency-Converter/pkg/logger"
-)
-
-// @title           Currency Converter API
-// @version         1.0
-// @description     server for a currency converter application.
-// @termsOfService  http://swagger.io/terms/
-// @contact.name    API Support
-// @contact.url     http://swagger.io/contact/
-// @license.name    MIT
-// @license.url     https://opensource.org/licenses/MIT
-// @host            localhost:8080
-// @BasePath        /api/v1
-// @securityDefinitions.apikey BearerAuth
-// @in header
-// @name Authorization
-// @description Type "Bearer {token}" to authenticate.
-func main() {
-	// Load config and logger
-	cfg := config.Load()
-	log := logger.New(cfg.Env)
-
-	db := database.NewPostgresDatabase(cfg, log)
-	err := db.MigrateDB()
-	if err != nil {
-		log.Fatal("Failed to migrate database", logger.Fields{"error": err.Error()})
-	}
-
-	server := server.NewGinServer(db, log, cfg)
-	server.Start()
-}
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/database"
+	"github.com/spksupakorn/Currency-Converter/internal/lifecycle"
+	"github.com/spksupakorn/Currency-Converter/internal/server"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// @title           Currency Converter API
+// @version         1.0
+// @description     server for a currency converter application.
+// @termsOfService  http://swagger.io/terms/
+// @contact.name    API Support
+// @contact.url     http://swagger.io/contact/
+// @license.name    MIT
+// @license.url     https://opensource.org/licenses/MIT
+// @host            localhost:8080
+// @BasePath        /api/v1
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Type "Bearer {token}" to authenticate.
+func main() {
+	// Load config and logger
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log := logger.New(logger.Options{
+		Env:    cfg.Env,
+		Level:  cfg.LogLevel,
+		Format: cfg.LogFormat,
+	})
+
+	db, err := database.New(cfg, log)
+	if err != nil {
+		log.Fatal("failed to open database", logger.Fields{"error": err.Error()})
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			log.Fatal("migrate failed", logger.Fields{"error": err.Error()})
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "users" {
+		if err := runUsers(cfg, db, log, os.Args[2:]); err != nil {
+			log.Fatal("users failed", logger.Fields{"error": err.Error()})
+		}
+		return
+	}
+
+	if cfg.MigrateOnStart {
+		if err := db.MigrateDB(); err != nil {
+			log.Fatal("Failed to migrate database", logger.Fields{"error": err.Error()})
+		}
+	}
+
+	// Closed last on shutdown, once every worker has stopped using it.
+	lc := lifecycle.New(log)
+	lc.OnStop("database", db.Close)
+
+	settings := config.NewReloader(cfg, config.Load)
+	server := server.NewGinServer(db, log, settings, lc)
+	if err := server.Start(); err != nil {
+		log.Fatal("failed to start server", logger.Fields{"error": err.Error()})
+	}
+}
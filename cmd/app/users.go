@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/database"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+const usersUsage = `usage: app users <command> <email>
+
+commands:
+  grant-admin   give an existing account the admin role
+  revoke-admin  take the admin role away (ADMIN_EMAILS still applies)`
+
+// runUsers implements the "users" subcommand.
+func runUsers(cfg config.Config, db database.Database, log *logger.Logger, args []string) error {
+	if len(args) != 2 {
+		return errors.New(usersUsage)
+	}
+	var role string
+	switch args[0] {
+	case "grant-admin":
+		role = models.RoleAdmin
+	case "revoke-admin":
+		role = models.RoleUser
+	default:
+		return errors.New(usersUsage)
+	}
+
+	repos := repositories.NewRepositories(db.ConnectDB(), db.Reader())
+	// Going through the cache broadcasts the change, so running instances
+	// drop the account instead of serving the old role until the TTL.
+	users := repositories.NewCachedUserRepository(repos.Users, cfg.AuthCacheTTL, cfg.AuthCacheSize, db.Notifier(), log)
+	ctx := repositories.ReadPrimary(context.Background())
+
+	email := strings.ToLower(strings.TrimSpace(args[1]))
+	u, err := users.FindByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("find %s: %w", email, err)
+	}
+	if err := users.SetRole(ctx, u.ID, role); err != nil {
+		return err
+	}
+	if err := repos.Audit.Create(ctx, &models.AuditEvent{
+		Action:     models.AuditActionRoleChanged,
+		TargetType: "user",
+		TargetID:   strconv.FormatUint(uint64(u.ID), 10),
+		Outcome:    models.AuditOutcomeSuccess,
+		Reason:     "cli: " + args[0],
+	}); err != nil {
+		return fmt.Errorf("record audit event: %w", err)
+	}
+	fmt.Printf("%s is now %s\n", email, role)
+	return nil
+}
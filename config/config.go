@@ -1,97 +1,498 @@
-package config
-
-import (
-	"os"
-	"strconv"
-	"strings"
-	"time"
-
-	"github.com/joho/godotenv"
-)
-
-type Config struct {
-	Env                 string
-	Port                int
-	DBHost              string
-	DBPort              int
-	DBUser              string
-	DBPassword          string
-	DBName              string
-	DBSSLMODE           string
-	DBTimeZone          string
-	JWTSecret           string
-	JWTExpiry           time.Duration
-	ExchangeAPIURL      string
-	ExchangeAPIKey      string
-	RateBaseCurrency    string
-	RateRefreshInterval time.Duration
-	HTTPClientTimeout   time.Duration
-	RateLimitRequests   int
-	RateLimitWindow     time.Duration
-}
-
-func Load() Config {
-	_ = godotenv.Load()
-
-	cfg := Config{
-		Env:                 getEnv("APP_ENV", "development"),
-		Port:                getInt("PORT", 8080),
-		DBHost:              getEnv("DB_HOST", "localhost"),
-		DBPort:              getInt("DB_PORT", 5432),
-		DBUser:              getEnv("DB_USER", "postgres"),
-		DBPassword:          mustEnv("DB_PASSWORD"),
-		DBName:              getEnv("DB_NAME", "currencydb"),
-		DBSSLMODE:           getEnv("DB_SSLMODE", "disable"),
-		DBTimeZone:          getEnv("DB_TIMEZONE", "UTC"),
-		JWTSecret:           mustEnv("JWT_SECRET"),
-		JWTExpiry:           getDuration("JWT_EXPIRY", 24*time.Hour),
-		RateBaseCurrency:    strings.ToUpper(getEnv("RATE_BASE_CURRENCY", "USD")),
-		ExchangeAPIURL:      getEnv("EXCHANGE_API_URL", "https://v6.exchangerate-api.com/v6/"),
-		ExchangeAPIKey:      getEnv("EXCHANGE_API_KEY", "f1f7a18d707dad8dbd854c9d"),
-		RateRefreshInterval: getDuration("RATE_REFRESH_INTERVAL", 6*time.Hour),
-		HTTPClientTimeout:   getDuration("HTTP_CLIENT_TIMEOUT", 10*time.Second),
-		RateLimitRequests:   getInt("RATE_LIMIT_REQUESTS", 100),
-		RateLimitWindow:     getDuration("RATE_LIMIT_WINDOW", time.Minute),
-	}
-	return cfg
-}
-
-func getEnv(key, def string) string {
-	v := os.Getenv(key)
-	if v == "" {
-		return def
-	}
-	return v
-}
-
-func mustEnv(key string) string {
-	v := os.Getenv(key)
-	if v == "" {
-		panic("missing required env: " + key)
-	}
-	return v
-}
-
-func getDuration(key string, def time.Duration) time.Duration {
-	v := os.Getenv(key)
-	if v == "" {
-		return def
-	}
-	d, err := time.ParseDuration(v)
-	if err != nil {
-		return def
-	}
-	return d
-}
-
-func getInt(key string, def int) int {
-	v := os.Getenv(key)
-	if v == "" {
-		return def
-	}
-	i, err := strconv.Atoi(v)
-	if err != nil {
-		return def
-	}
-	return i
-}
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/netip"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/spksupakorn/Currency-Converter/internal/schedule"
+)
+
+// Policies for rates older than RATE_MAX_AGE. Stale rates are always flagged
+// in responses; warn also logs once per snapshot, refuse answers 503.
+const (
+	StalePolicyWarn   = "warn"
+	StalePolicyServe  = "serve"
+	StalePolicyRefuse = "refuse"
+)
+
+type Config struct {
+	Env                 string
+	Port                int
+	DBDriver            string
+	DBPath              string
+	DBHost              string
+	DBPort              int
+	DBUser              string
+	DBPassword          string
+	DBName              string
+	DBSSLMODE           string
+	DBTimeZone          string
+	DBReplicaHost       string
+	DBReplicaPort       int
+	DBMaxOpenConns      int
+	DBMaxIdleConns      int
+	DBConnMaxLifetime   time.Duration
+	DBConnMaxIdleTime   time.Duration
+	DBConnectTimeout    time.Duration
+	JWTSecret           string
+	JWTExpiry           time.Duration
+	AuthCacheTTL        time.Duration
+	AuthCacheSize       int
+	ExchangeAPIURL      string
+	ExchangeAPIKey      string
+	RateBaseCurrency    string
+	RateRefreshInterval time.Duration
+	RateMaxAge          time.Duration
+	RateStalePolicy     string
+
+	RateRetryAttempts        int
+	RateRetryBaseDelay       time.Duration
+	RateRetryMaxDelay        time.Duration
+	RateFailureRetryInterval time.Duration
+	RateBreakerThreshold     int
+	RateBreakerCooldown      time.Duration
+
+	RateMaxChange          float64
+	RateRequiredCurrencies []string
+
+	RateRefreshSchedule      string
+	RateScheduleTimezone     string
+	RateMarketCalendar       string
+	RateMarketHolidays       []string
+	RateMarketClosedInterval time.Duration
+
+	AlertMaxRules         int
+	WebhookTimeout        time.Duration
+	WebhookMaxAttempts    int
+	WebhookRetryBaseDelay time.Duration
+	WebhookRetryMaxDelay  time.Duration
+	WebhookPollInterval   time.Duration
+
+	// WebhookAllowedNetworks lists private or local networks webhooks may
+	// still be delivered to, e.g. a receiver in the same cluster.
+	WebhookAllowedNetworks []netip.Prefix
+
+	EventsSink         string
+	EventsPollInterval time.Duration
+	EventsRetention    time.Duration
+
+	// LeaderElection makes one instance refresh rates, deliver webhooks and
+	// relay events; the others reload what it stores. When false every
+	// instance does all of it.
+	LeaderElection   bool
+	LeaderLeaseTTL   time.Duration
+	InstanceID       string
+	RateSyncInterval time.Duration
+
+	HTTPClientTimeout   time.Duration
+	RequestTimeout      time.Duration
+	RouteTimeouts       map[string]time.Duration
+	RateLimitRequests   int
+	RateLimitWindow     time.Duration
+	LogLevel            string
+	LogFormat           string
+	LogRequestSample    int
+	AdminEmails         []string
+	UsageBufferSize     int
+	UsageFlushInterval  time.Duration
+	UsageRetention      time.Duration
+	MigrateOnStart      bool
+	ConfigFile          string
+	ConfigWatchInterval time.Duration
+
+	// ShutdownTimeout bounds draining requests and stopping the background
+	// workers together.
+	ShutdownTimeout time.Duration
+}
+
+// Load reads the configuration from the environment (including .env) layered
+// over the optional file named by CONFIG_FILE, and validates the result. All
+// problems are reported together in a *ValidationError.
+func Load() (Config, error) {
+	_ = godotenv.Load()
+
+	path := os.Getenv("CONFIG_FILE")
+	l, err := newLoader(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg := Config{
+		Env:                 l.getEnv("APP_ENV", "development"),
+		Port:                l.getInt("PORT", 8080),
+		DBDriver:            strings.ToLower(l.getEnv("DB_DRIVER", "postgres")),
+		DBPath:              l.getEnv("DB_PATH", "data/currency.db"),
+		DBHost:              l.getEnv("DB_HOST", "localhost"),
+		DBPort:              l.getInt("DB_PORT", 5432),
+		DBUser:              l.getEnv("DB_USER", "postgres"),
+		DBPassword:          l.getEnv("DB_PASSWORD", ""),
+		DBName:              l.getEnv("DB_NAME", "currencydb"),
+		DBSSLMODE:           l.getEnv("DB_SSLMODE", "disable"),
+		DBTimeZone:          l.getEnv("DB_TIMEZONE", "UTC"),
+		DBReplicaHost:       l.getEnv("DB_REPLICA_HOST", ""),
+		DBReplicaPort:       l.getInt("DB_REPLICA_PORT", 5432),
+		DBMaxOpenConns:      l.getInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:      l.getInt("DB_MAX_IDLE_CONNS", 10),
+		DBConnMaxLifetime:   l.getDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		DBConnMaxIdleTime:   l.getDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+		DBConnectTimeout:    l.getDuration("DB_CONNECT_TIMEOUT", time.Minute),
+		JWTSecret:           l.mustEnv("JWT_SECRET"),
+		JWTExpiry:           l.getDuration("JWT_EXPIRY", 24*time.Hour),
+		AuthCacheTTL:        l.getDuration("AUTH_CACHE_TTL", 30*time.Second),
+		AuthCacheSize:       l.getInt("AUTH_CACHE_SIZE", 10000),
+		RateBaseCurrency:    strings.ToUpper(l.getEnv("RATE_BASE_CURRENCY", "USD")),
+		ExchangeAPIURL:      l.getEnv("EXCHANGE_API_URL", "https://v6.exchangerate-api.com/v6/"),
+		ExchangeAPIKey:      l.getEnv("EXCHANGE_API_KEY", "f1f7a18d707dad8dbd854c9d"),
+		RateRefreshInterval: l.getDuration("RATE_REFRESH_INTERVAL", 6*time.Hour),
+		RateMaxAge:          l.getDuration("RATE_MAX_AGE", 0),
+		RateStalePolicy:     strings.ToLower(l.getEnv("RATE_STALE_POLICY", StalePolicyWarn)),
+		HTTPClientTimeout:   l.getDuration("HTTP_CLIENT_TIMEOUT", 10*time.Second),
+
+		RateRetryAttempts:        l.getInt("RATE_RETRY_ATTEMPTS", 3),
+		RateRetryBaseDelay:       l.getDuration("RATE_RETRY_BASE_DELAY", time.Second),
+		RateRetryMaxDelay:        l.getDuration("RATE_RETRY_MAX_DELAY", 30*time.Second),
+		RateFailureRetryInterval: l.getDuration("RATE_FAILURE_RETRY_INTERVAL", time.Minute),
+		RateBreakerThreshold:     l.getInt("RATE_BREAKER_THRESHOLD", 3),
+		RateBreakerCooldown:      l.getDuration("RATE_BREAKER_COOLDOWN", 5*time.Minute),
+
+		RateMaxChange:          l.getFloat("RATE_MAX_CHANGE", 0.5),
+		RateRequiredCurrencies: l.getCurrencies("RATE_REQUIRED_CURRENCIES", "USD,EUR,GBP,JPY"),
+
+		RateRefreshSchedule:      strings.TrimSpace(l.getEnv("RATE_REFRESH_SCHEDULE", "")),
+		RateScheduleTimezone:     l.getEnv("RATE_SCHEDULE_TIMEZONE", "UTC"),
+		RateMarketCalendar:       strings.ToLower(l.getEnv("RATE_MARKET_CALENDAR", schedule.CalendarAlways)),
+		RateMarketHolidays:       l.getList("RATE_MARKET_HOLIDAYS"),
+		RateMarketClosedInterval: l.getDuration("RATE_MARKET_CLOSED_INTERVAL", 0),
+
+		AlertMaxRules:         l.getInt("ALERT_MAX_RULES", 20),
+		WebhookTimeout:        l.getDuration("WEBHOOK_TIMEOUT", 5*time.Second),
+		WebhookMaxAttempts:    l.getInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRetryBaseDelay: l.getDuration("WEBHOOK_RETRY_BASE_DELAY", 30*time.Second),
+		WebhookRetryMaxDelay:  l.getDuration("WEBHOOK_RETRY_MAX_DELAY", time.Hour),
+		WebhookPollInterval:   l.getDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+
+		WebhookAllowedNetworks: l.getNetworks("WEBHOOK_ALLOWED_NETWORKS"),
+
+		EventsSink:         strings.TrimSpace(l.getEnv("EVENTS_SINK", "")),
+		EventsPollInterval: l.getDuration("EVENTS_POLL_INTERVAL", time.Second),
+		EventsRetention:    l.getDuration("EVENTS_RETENTION", 7*24*time.Hour),
+
+		LeaderElection:   l.getBool("LEADER_ELECTION", true),
+		LeaderLeaseTTL:   l.getDuration("LEADER_LEASE_TTL", 15*time.Second),
+		InstanceID:       strings.TrimSpace(l.getEnv("INSTANCE_ID", defaultInstanceID())),
+		RateSyncInterval: l.getDuration("RATE_SYNC_INTERVAL", 30*time.Second),
+
+		RequestTimeout:      l.getDuration("REQUEST_TIMEOUT", 10*time.Second),
+		RouteTimeouts:       l.getDurationMap("REQUEST_TIMEOUTS"),
+		RateLimitRequests:   l.getInt("RATE_LIMIT_REQUESTS", 100),
+		RateLimitWindow:     l.getDuration("RATE_LIMIT_WINDOW", time.Minute),
+		LogLevel:            strings.ToLower(l.getEnv("LOG_LEVEL", "")),
+		LogFormat:           strings.ToLower(l.getEnv("LOG_FORMAT", "")),
+		LogRequestSample:    l.getInt("LOG_REQUEST_SAMPLE", 1),
+		AdminEmails:         l.getList("ADMIN_EMAILS"),
+		UsageBufferSize:     l.getInt("USAGE_BUFFER_SIZE", 4096),
+		UsageFlushInterval:  l.getDuration("USAGE_FLUSH_INTERVAL", 5*time.Second),
+		UsageRetention:      l.getDuration("USAGE_RETENTION", 0),
+		MigrateOnStart:      l.getBool("MIGRATE_ON_START", true),
+		ConfigFile:          path,
+		ConfigWatchInterval: l.getDuration("CONFIG_WATCH_INTERVAL", 10*time.Second),
+
+		ShutdownTimeout: l.getDuration("SHUTDOWN_TIMEOUT", 20*time.Second),
+	}
+	cfg.RouteTimeouts = withRouteDefaults(cfg.RouteTimeouts, cfg.RequestTimeout)
+
+	problems := l.errs
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, err.(*ValidationError).Problems...)
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return cfg, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// Validate checks value ranges and cross-field constraints. It returns a
+// *ValidationError listing every problem, or nil.
+func (c Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Port <= 0 || c.Port > 65535 {
+		add("PORT: must be between 1 and 65535, got %d", c.Port)
+	}
+	switch c.DBDriver {
+	case "postgres":
+		if c.DBPort <= 0 || c.DBPort > 65535 {
+			add("DB_PORT: must be between 1 and 65535, got %d", c.DBPort)
+		}
+		if c.DBPassword == "" {
+			add("DB_PASSWORD is required (set DB_PASSWORD or DB_PASSWORD_FILE)")
+		}
+		if c.DBReplicaHost != "" && (c.DBReplicaPort <= 0 || c.DBReplicaPort > 65535) {
+			add("DB_REPLICA_PORT: must be between 1 and 65535, got %d", c.DBReplicaPort)
+		}
+		if c.DBConnectTimeout <= 0 {
+			add("DB_CONNECT_TIMEOUT: must be positive, got %s", c.DBConnectTimeout)
+		}
+	case "sqlite":
+		if c.DBPath == "" {
+			add("DB_PATH: must not be empty when DB_DRIVER is sqlite")
+		}
+	default:
+		add("DB_DRIVER: must be postgres or sqlite, got %q", c.DBDriver)
+	}
+	if c.DBMaxOpenConns < 0 {
+		add("DB_MAX_OPEN_CONNS: must not be negative, got %d", c.DBMaxOpenConns)
+	}
+	if c.DBMaxIdleConns < 0 {
+		add("DB_MAX_IDLE_CONNS: must not be negative, got %d", c.DBMaxIdleConns)
+	}
+	if c.DBMaxOpenConns > 0 && c.DBMaxIdleConns > c.DBMaxOpenConns {
+		add("DB_MAX_IDLE_CONNS: must not exceed DB_MAX_OPEN_CONNS (%d), got %d", c.DBMaxOpenConns, c.DBMaxIdleConns)
+	}
+	if c.DBConnMaxLifetime < 0 {
+		add("DB_CONN_MAX_LIFETIME: must not be negative, got %s", c.DBConnMaxLifetime)
+	}
+	if c.DBConnMaxIdleTime < 0 {
+		add("DB_CONN_MAX_IDLE_TIME: must not be negative, got %s", c.DBConnMaxIdleTime)
+	}
+	if c.JWTExpiry <= 0 {
+		add("JWT_EXPIRY: must be positive, got %s", c.JWTExpiry)
+	}
+	if c.AuthCacheTTL < 0 {
+		add("AUTH_CACHE_TTL: must not be negative, got %s", c.AuthCacheTTL)
+	}
+	if c.AuthCacheSize <= 0 {
+		add("AUTH_CACHE_SIZE: must be positive, got %d", c.AuthCacheSize)
+	}
+	if !isCurrencyCode(c.RateBaseCurrency) {
+		add("RATE_BASE_CURRENCY: must be a 3-letter currency code, got %q", c.RateBaseCurrency)
+	}
+	if c.ExchangeAPIURL == "" {
+		add("EXCHANGE_API_URL: must not be empty")
+	}
+	if c.RateRefreshInterval <= 0 {
+		add("RATE_REFRESH_INTERVAL: must be positive, got %s", c.RateRefreshInterval)
+	}
+	if c.RateMaxAge < 0 {
+		add("RATE_MAX_AGE: must not be negative, got %s", c.RateMaxAge)
+	}
+	switch c.RateStalePolicy {
+	case StalePolicyWarn, StalePolicyServe, StalePolicyRefuse:
+	default:
+		add("RATE_STALE_POLICY: must be warn, serve or refuse, got %q", c.RateStalePolicy)
+	}
+	if c.RateRetryAttempts < 1 {
+		add("RATE_RETRY_ATTEMPTS: must be at least 1, got %d", c.RateRetryAttempts)
+	}
+	if c.RateRetryBaseDelay <= 0 {
+		add("RATE_RETRY_BASE_DELAY: must be positive, got %s", c.RateRetryBaseDelay)
+	}
+	if c.RateRetryMaxDelay < c.RateRetryBaseDelay {
+		add("RATE_RETRY_MAX_DELAY: must be at least RATE_RETRY_BASE_DELAY, got %s", c.RateRetryMaxDelay)
+	}
+	if c.RateFailureRetryInterval <= 0 {
+		add("RATE_FAILURE_RETRY_INTERVAL: must be positive, got %s", c.RateFailureRetryInterval)
+	}
+	if c.RateBreakerThreshold < 1 {
+		add("RATE_BREAKER_THRESHOLD: must be at least 1, got %d", c.RateBreakerThreshold)
+	}
+	if c.RateBreakerCooldown <= 0 {
+		add("RATE_BREAKER_COOLDOWN: must be positive, got %s", c.RateBreakerCooldown)
+	}
+	if c.RateMaxChange < 0 || math.IsNaN(c.RateMaxChange) {
+		add("RATE_MAX_CHANGE: must not be negative, got %v", c.RateMaxChange)
+	}
+	for _, cur := range c.RateRequiredCurrencies {
+		if !isCurrencyCode(cur) {
+			add("RATE_REQUIRED_CURRENCIES: %q is not a 3-letter currency code", cur)
+		}
+	}
+	if c.RateRefreshSchedule != "" {
+		if _, err := schedule.ParseCron(c.RateRefreshSchedule); err != nil {
+			add("RATE_REFRESH_SCHEDULE: %v", err)
+		}
+	}
+	if loc, err := time.LoadLocation(c.RateScheduleTimezone); err != nil {
+		add("RATE_SCHEDULE_TIMEZONE: unknown time zone %q", c.RateScheduleTimezone)
+	} else if _, err := schedule.NewCalendar(c.RateMarketCalendar, c.RateMarketHolidays, loc); err != nil {
+		add("RATE_MARKET_CALENDAR: %v", err)
+	}
+	if c.RateMarketClosedInterval < 0 {
+		add("RATE_MARKET_CLOSED_INTERVAL: must not be negative, got %s", c.RateMarketClosedInterval)
+	}
+	if c.AlertMaxRules <= 0 {
+		add("ALERT_MAX_RULES: must be positive, got %d", c.AlertMaxRules)
+	}
+	if c.WebhookTimeout <= 0 {
+		add("WEBHOOK_TIMEOUT: must be positive, got %s", c.WebhookTimeout)
+	}
+	if c.WebhookMaxAttempts < 1 {
+		add("WEBHOOK_MAX_ATTEMPTS: must be at least 1, got %d", c.WebhookMaxAttempts)
+	}
+	if c.WebhookRetryBaseDelay <= 0 {
+		add("WEBHOOK_RETRY_BASE_DELAY: must be positive, got %s", c.WebhookRetryBaseDelay)
+	}
+	if c.WebhookRetryMaxDelay < c.WebhookRetryBaseDelay {
+		add("WEBHOOK_RETRY_MAX_DELAY: must be at least WEBHOOK_RETRY_BASE_DELAY, got %s", c.WebhookRetryMaxDelay)
+	}
+	if c.WebhookPollInterval <= 0 {
+		add("WEBHOOK_POLL_INTERVAL: must be positive, got %s", c.WebhookPollInterval)
+	}
+	if c.EventsSink != "" {
+		if problem := sinkProblem(c.EventsSink); problem != "" {
+			add("EVENTS_SINK: %s, got %q", problem, c.EventsSink)
+		}
+	}
+	if c.EventsPollInterval <= 0 {
+		add("EVENTS_POLL_INTERVAL: must be positive, got %s", c.EventsPollInterval)
+	}
+	if c.EventsRetention < 0 {
+		add("EVENTS_RETENTION: must not be negative, got %s", c.EventsRetention)
+	}
+	if c.LeaderLeaseTTL <= 0 {
+		add("LEADER_LEASE_TTL: must be positive, got %s", c.LeaderLeaseTTL)
+	}
+	if c.InstanceID == "" || len(c.InstanceID) > 128 {
+		add("INSTANCE_ID: must be 1 to 128 characters, got %q", c.InstanceID)
+	}
+	if c.RateSyncInterval < 0 {
+		add("RATE_SYNC_INTERVAL: must not be negative, got %s", c.RateSyncInterval)
+	}
+	if c.HTTPClientTimeout <= 0 {
+		add("HTTP_CLIENT_TIMEOUT: must be positive, got %s", c.HTTPClientTimeout)
+	}
+	if c.RequestTimeout < 0 {
+		add("REQUEST_TIMEOUT: must not be negative, got %s", c.RequestTimeout)
+	}
+	for route, d := range c.RouteTimeouts {
+		if !strings.HasPrefix(route, "/") {
+			add("REQUEST_TIMEOUTS: route %q must start with /", route)
+		}
+		if d <= 0 {
+			add("REQUEST_TIMEOUTS: timeout for %s must be positive, got %s", route, d)
+		}
+	}
+	if c.RateLimitRequests <= 0 {
+		add("RATE_LIMIT_REQUESTS: must be positive, got %d", c.RateLimitRequests)
+	}
+	if c.RateLimitWindow <= 0 {
+		add("RATE_LIMIT_WINDOW: must be positive, got %s", c.RateLimitWindow)
+	}
+	switch c.LogLevel {
+	case "", "debug", "info", "warn", "error":
+	default:
+		add("LOG_LEVEL: must be one of debug, info, warn, error, got %q", c.LogLevel)
+	}
+	switch c.LogFormat {
+	case "", "json", "console":
+	default:
+		add("LOG_FORMAT: must be json or console, got %q", c.LogFormat)
+	}
+	if c.LogRequestSample < 1 {
+		add("LOG_REQUEST_SAMPLE: must be at least 1, got %d", c.LogRequestSample)
+	}
+	if c.UsageBufferSize <= 0 {
+		add("USAGE_BUFFER_SIZE: must be positive, got %d", c.UsageBufferSize)
+	}
+	if c.UsageFlushInterval <= 0 {
+		add("USAGE_FLUSH_INTERVAL: must be positive, got %s", c.UsageFlushInterval)
+	}
+	if c.ConfigWatchInterval < 0 {
+		add("CONFIG_WATCH_INTERVAL: must not be negative, got %s", c.ConfigWatchInterval)
+	}
+	if c.UsageRetention < 0 {
+		add("USAGE_RETENTION: must not be negative, got %s", c.UsageRetention)
+	}
+	if c.ShutdownTimeout <= 0 {
+		add("SHUTDOWN_TIMEOUT: must be positive, got %s", c.ShutdownTimeout)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, ch := range s {
+		if ch < 'A' || ch > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultRouteTimeouts apply to the routes REQUEST_TIMEOUTS does not name,
+// when they are longer than REQUEST_TIMEOUT. A manual rate refresh retries the
+// provider, so with the default retry settings it can take well over 10s.
+var defaultRouteTimeouts = map[string]time.Duration{
+	"/api/v1/admin/rates/refresh": 2 * time.Minute,
+}
+
+func withRouteDefaults(m map[string]time.Duration, fallback time.Duration) map[string]time.Duration {
+	for route, d := range defaultRouteTimeouts {
+		if _, ok := m[route]; ok || fallback <= 0 || fallback >= d {
+			continue
+		}
+		if m == nil {
+			m = map[string]time.Duration{}
+		}
+		m[route] = d
+	}
+	return m
+}
+
+// defaultInstanceID is the host name with a random suffix, so that two
+// processes on one host do not share a leader lease. It is chosen once, so a
+// reload keeps it.
+var defaultInstanceID = sync.OnceValue(func() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "instance"
+	}
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	return host + "-" + hex.EncodeToString(b[:])
+})
+
+// sinkProblem describes what keeps the relay from building an events sink
+// from s, or returns "" when nothing does.
+func sinkProblem(s string) string {
+	if s == "stdout" {
+		return ""
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return "not a valid URL"
+	}
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" && u.Opaque == "" {
+			return "file: URL has no path"
+		}
+	case "nats", "kafka+http", "kafka+https":
+		if u.Hostname() == "" {
+			return u.Scheme + ":// URL has no host"
+		}
+	default:
+		return "must be stdout or a file:, nats://, kafka+http:// or kafka+https:// URL"
+	}
+	return ""
+}
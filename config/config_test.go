@@ -0,0 +1,251 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+)
+
+// setEnv sets the minimal environment Load accepts, then env on top of it.
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("JWT_SECRET", "test-secret")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func problems(t *testing.T, err error) []string {
+	t.Helper()
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error = %v, want a *config.ValidationError", err)
+	}
+	return verr.Problems
+}
+
+func TestLoadReportsMalformedValues(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "duration in words",
+			env:  map[string]string{"RATE_REFRESH_INTERVAL": "6 hours"},
+			want: []string{`RATE_REFRESH_INTERVAL: "6 hours" is not a valid duration`},
+		},
+		{
+			name: "integer",
+			env:  map[string]string{"PORT": "eighty"},
+			want: []string{`PORT: "eighty" is not a valid integer`},
+		},
+		{
+			name: "boolean",
+			env:  map[string]string{"MIGRATE_ON_START": "sometimes"},
+			want: []string{`MIGRATE_ON_START: "sometimes" is not a valid boolean`},
+		},
+		{
+			name: "network",
+			env:  map[string]string{"WEBHOOK_ALLOWED_NETWORKS": "10.0.0.0/8, 192.168.1.5, not-an-ip"},
+			want: []string{`WEBHOOK_ALLOWED_NETWORKS: "not-an-ip" is not an IP address or CIDR prefix`},
+		},
+		{
+			name: "events sink",
+			env:  map[string]string{"EVENTS_SINK": "nats:///events"},
+			want: []string{`EVENTS_SINK: nats:// URL has no host, got "nats:///events"`},
+		},
+		{
+			name: "out of range",
+			env:  map[string]string{"PORT": "70000"},
+			want: []string{"PORT: must be between 1 and 65535, got 70000"},
+		},
+		{
+			name: "every problem at once",
+			env: map[string]string{
+				"RATE_REFRESH_INTERVAL": "6 hours",
+				"PORT":                  "eighty",
+				"DB_DRIVER":             "mysql",
+				"JWT_SECRET":            "",
+			},
+			want: []string{
+				`RATE_REFRESH_INTERVAL: "6 hours" is not a valid duration`,
+				`PORT: "eighty" is not a valid integer`,
+				`DB_DRIVER: must be postgres or sqlite, got "mysql"`,
+				"JWT_SECRET is required",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			_, err := config.Load()
+			got := problems(t, err)
+			if len(got) != len(tt.want) {
+				t.Errorf("problems = %q, want %d", got, len(tt.want))
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	secret := writeFile(t, "jwt", "from-file\n")
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		problem string
+	}{
+		{
+			name: "file with trailing newline",
+			env:  map[string]string{"JWT_SECRET": "", "JWT_SECRET_FILE": secret},
+			want: "from-file",
+		},
+		{
+			name: "variable wins over file",
+			env:  map[string]string{"JWT_SECRET": "from-env", "JWT_SECRET_FILE": secret},
+			want: "from-env",
+		},
+		{
+			name:    "missing file",
+			env:     map[string]string{"JWT_SECRET": "", "JWT_SECRET_FILE": filepath.Join(t.TempDir(), "nope")},
+			problem: "JWT_SECRET_FILE: open",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			cfg, err := config.Load()
+			if tt.problem != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.problem) {
+					t.Fatalf("error = %v, want one mentioning %q", err, tt.problem)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.JWTSecret != tt.want {
+				t.Errorf("JWTSecret = %q, want %q", cfg.JWTSecret, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFilePrecedence(t *testing.T) {
+	yamlFile := writeFile(t, "app.yaml", `
+port: 9000
+rate:
+  refresh-interval: 2h
+  required-currencies: [eur, thb]
+admin_emails:
+  - Ops@Example.com
+`)
+	tomlFile := writeFile(t, "app.toml", `
+port = 9000
+admin_emails = ["Ops@Example.com"]
+
+[rate]
+refresh_interval = "2h"
+required_currencies = ["eur", "thb"]
+`)
+	for _, file := range []string{yamlFile, tomlFile} {
+		t.Run(filepath.Ext(file), func(t *testing.T) {
+			tests := []struct {
+				name     string
+				env      map[string]string
+				port     int
+				interval time.Duration
+			}{
+				{name: "file over defaults", port: 9000, interval: 2 * time.Hour},
+				{
+					name:     "environment over file",
+					env:      map[string]string{"PORT": "9100", "RATE_REFRESH_INTERVAL": "30m"},
+					port:     9100,
+					interval: 30 * time.Minute,
+				},
+				{
+					name:     "secret file over config file",
+					env:      map[string]string{"RATE_REFRESH_INTERVAL_FILE": writeFile(t, "interval", "45m\n")},
+					port:     9000,
+					interval: 45 * time.Minute,
+				},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					setEnv(t, tt.env)
+					t.Setenv("CONFIG_FILE", file)
+					cfg, err := config.Load()
+					if err != nil {
+						t.Fatal(err)
+					}
+					if cfg.Port != tt.port || cfg.RateRefreshInterval != tt.interval {
+						t.Errorf("port, interval = %d, %s, want %d, %s", cfg.Port, cfg.RateRefreshInterval, tt.port, tt.interval)
+					}
+					if strings.Join(cfg.RateRequiredCurrencies, ",") != "EUR,THB" {
+						t.Errorf("required currencies = %v, want nested lists flattened", cfg.RateRequiredCurrencies)
+					}
+					if strings.Join(cfg.AdminEmails, ",") != "ops@example.com" {
+						t.Errorf("admin emails = %v", cfg.AdminEmails)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestLoadRouteTimeoutDefaults(t *testing.T) {
+	const refresh = "/api/v1/admin/rates/refresh"
+	tests := []struct {
+		name string
+		env  map[string]string
+		want time.Duration
+	}{
+		{name: "defaults", want: 2 * time.Minute},
+		{name: "named route", env: map[string]string{"REQUEST_TIMEOUTS": refresh + "=5s"}, want: 5 * time.Second},
+		{name: "other routes named", env: map[string]string{"REQUEST_TIMEOUTS": "/api/v1/convert=2s"}, want: 2 * time.Minute},
+		{name: "longer request timeout", env: map[string]string{"REQUEST_TIMEOUT": "5m"}},
+		{name: "deadlines disabled", env: map[string]string{"REQUEST_TIMEOUT": "0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			cfg, err := config.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.RouteTimeouts[refresh]; got != tt.want {
+				t.Errorf("refresh timeout = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsUnknownFileFormat(t *testing.T) {
+	setEnv(t, nil)
+	t.Setenv("CONFIG_FILE", writeFile(t, "app.ini", "port=1"))
+	if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "unsupported format") {
+		t.Errorf("error = %v, want unsupported format", err)
+	}
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Subscriber is notified after a reload changed at least one setting. If it
+// returns an error the reload is rolled back and subscribers that already
+// accepted the new settings are called again with the old ones.
+type Subscriber func(old, new Config) error
+
+// Change describes one setting that differs after a reload. Settings that
+// need a restart (connections, secrets, ports) are reported with Applied set
+// to false and keep their running value.
+type Change struct {
+	Key     string
+	Old     string
+	New     string
+	Applied bool
+}
+
+// reloadable lists the settings that may change at runtime.
+var reloadable = []struct {
+	key string
+	get func(Config) string
+	set func(dst *Config, src Config)
+}{
+	{"RATE_BASE_CURRENCY", func(c Config) string { return c.RateBaseCurrency }, func(d *Config, s Config) { d.RateBaseCurrency = s.RateBaseCurrency }},
+	{"RATE_REFRESH_INTERVAL", func(c Config) string { return c.RateRefreshInterval.String() }, func(d *Config, s Config) { d.RateRefreshInterval = s.RateRefreshInterval }},
+	{"RATE_MAX_AGE", func(c Config) string { return c.RateMaxAge.String() }, func(d *Config, s Config) { d.RateMaxAge = s.RateMaxAge }},
+	{"RATE_STALE_POLICY", func(c Config) string { return c.RateStalePolicy }, func(d *Config, s Config) { d.RateStalePolicy = s.RateStalePolicy }},
+	{"RATE_RETRY_ATTEMPTS", func(c Config) string { return fmt.Sprint(c.RateRetryAttempts) }, func(d *Config, s Config) { d.RateRetryAttempts = s.RateRetryAttempts }},
+	{"RATE_RETRY_BASE_DELAY", func(c Config) string { return c.RateRetryBaseDelay.String() }, func(d *Config, s Config) { d.RateRetryBaseDelay = s.RateRetryBaseDelay }},
+	{"RATE_RETRY_MAX_DELAY", func(c Config) string { return c.RateRetryMaxDelay.String() }, func(d *Config, s Config) { d.RateRetryMaxDelay = s.RateRetryMaxDelay }},
+	{"RATE_FAILURE_RETRY_INTERVAL", func(c Config) string { return c.RateFailureRetryInterval.String() }, func(d *Config, s Config) { d.RateFailureRetryInterval = s.RateFailureRetryInterval }},
+	{"RATE_BREAKER_THRESHOLD", func(c Config) string { return fmt.Sprint(c.RateBreakerThreshold) }, func(d *Config, s Config) { d.RateBreakerThreshold = s.RateBreakerThreshold }},
+	{"RATE_BREAKER_COOLDOWN", func(c Config) string { return c.RateBreakerCooldown.String() }, func(d *Config, s Config) { d.RateBreakerCooldown = s.RateBreakerCooldown }},
+	{"RATE_MAX_CHANGE", func(c Config) string { return fmt.Sprint(c.RateMaxChange) }, func(d *Config, s Config) { d.RateMaxChange = s.RateMaxChange }},
+	{"RATE_REQUIRED_CURRENCIES", func(c Config) string { return strings.Join(c.RateRequiredCurrencies, ",") }, func(d *Config, s Config) { d.RateRequiredCurrencies = s.RateRequiredCurrencies }},
+	{"RATE_REFRESH_SCHEDULE", func(c Config) string { return c.RateRefreshSchedule }, func(d *Config, s Config) { d.RateRefreshSchedule = s.RateRefreshSchedule }},
+	{"RATE_SCHEDULE_TIMEZONE", func(c Config) string { return c.RateScheduleTimezone }, func(d *Config, s Config) { d.RateScheduleTimezone = s.RateScheduleTimezone }},
+	{"RATE_MARKET_CALENDAR", func(c Config) string { return c.RateMarketCalendar }, func(d *Config, s Config) { d.RateMarketCalendar = s.RateMarketCalendar }},
+	{"RATE_MARKET_HOLIDAYS", func(c Config) string { return strings.Join(c.RateMarketHolidays, ",") }, func(d *Config, s Config) { d.RateMarketHolidays = s.RateMarketHolidays }},
+	{"RATE_MARKET_CLOSED_INTERVAL", func(c Config) string { return c.RateMarketClosedInterval.String() }, func(d *Config, s Config) { d.RateMarketClosedInterval = s.RateMarketClosedInterval }},
+	{"RATE_LIMIT_REQUESTS", func(c Config) string { return fmt.Sprint(c.RateLimitRequests) }, func(d *Config, s Config) { d.RateLimitRequests = s.RateLimitRequests }},
+	{"RATE_LIMIT_WINDOW", func(c Config) string { return c.RateLimitWindow.String() }, func(d *Config, s Config) { d.RateLimitWindow = s.RateLimitWindow }},
+}
+
+// restartOnly lists settings that are reported, but never applied, when they
+// change. Secrets are compared but their values are not echoed. Every setting
+// is in one of the two lists.
+var restartOnly = []struct {
+	key    string
+	get    func(Config) string
+	secret bool
+}{
+	{"APP_ENV", func(c Config) string { return c.Env }, false},
+	{"PORT", func(c Config) string { return fmt.Sprint(c.Port) }, false},
+	{"DB_DRIVER", func(c Config) string { return c.DBDriver }, false},
+	{"DB_PATH", func(c Config) string { return c.DBPath }, false},
+	{"DB_HOST", func(c Config) string { return c.DBHost }, false},
+	{"DB_PORT", func(c Config) string { return fmt.Sprint(c.DBPort) }, false},
+	{"DB_USER", func(c Config) string { return c.DBUser }, false},
+	{"DB_PASSWORD", func(c Config) string { return c.DBPassword }, true},
+	{"DB_NAME", func(c Config) string { return c.DBName }, false},
+	{"DB_SSLMODE", func(c Config) string { return c.DBSSLMODE }, false},
+	{"DB_TIMEZONE", func(c Config) string { return c.DBTimeZone }, false},
+	{"DB_REPLICA_HOST", func(c Config) string { return c.DBReplicaHost }, false},
+	{"DB_REPLICA_PORT", func(c Config) string { return fmt.Sprint(c.DBReplicaPort) }, false},
+	{"DB_MAX_OPEN_CONNS", func(c Config) string { return fmt.Sprint(c.DBMaxOpenConns) }, false},
+	{"DB_MAX_IDLE_CONNS", func(c Config) string { return fmt.Sprint(c.DBMaxIdleConns) }, false},
+	{"DB_CONN_MAX_LIFETIME", func(c Config) string { return c.DBConnMaxLifetime.String() }, false},
+	{"DB_CONN_MAX_IDLE_TIME", func(c Config) string { return c.DBConnMaxIdleTime.String() }, false},
+	{"DB_CONNECT_TIMEOUT", func(c Config) string { return c.DBConnectTimeout.String() }, false},
+	{"JWT_SECRET", func(c Config) string { return c.JWTSecret }, true},
+	{"JWT_EXPIRY", func(c Config) string { return c.JWTExpiry.String() }, false},
+	{"AUTH_CACHE_TTL", func(c Config) string { return c.AuthCacheTTL.String() }, false},
+	{"AUTH_CACHE_SIZE", func(c Config) string { return fmt.Sprint(c.AuthCacheSize) }, false},
+	{"ADMIN_EMAILS", func(c Config) string { return strings.Join(c.AdminEmails, ",") }, false},
+	{"EXCHANGE_API_URL", func(c Config) string { return c.ExchangeAPIURL }, false},
+	{"EXCHANGE_API_KEY", func(c Config) string { return c.ExchangeAPIKey }, true},
+	{"HTTP_CLIENT_TIMEOUT", func(c Config) string { return c.HTTPClientTimeout.String() }, false},
+	{"ALERT_MAX_RULES", func(c Config) string { return fmt.Sprint(c.AlertMaxRules) }, false},
+	{"WEBHOOK_TIMEOUT", func(c Config) string { return c.WebhookTimeout.String() }, false},
+	{"WEBHOOK_MAX_ATTEMPTS", func(c Config) string { return fmt.Sprint(c.WebhookMaxAttempts) }, false},
+	{"WEBHOOK_RETRY_BASE_DELAY", func(c Config) string { return c.WebhookRetryBaseDelay.String() }, false},
+	{"WEBHOOK_RETRY_MAX_DELAY", func(c Config) string { return c.WebhookRetryMaxDelay.String() }, false},
+	{"WEBHOOK_POLL_INTERVAL", func(c Config) string { return c.WebhookPollInterval.String() }, false},
+	{"WEBHOOK_ALLOWED_NETWORKS", func(c Config) string { return fmt.Sprint(c.WebhookAllowedNetworks) }, false},
+	// The URL may carry credentials.
+	{"EVENTS_SINK", func(c Config) string { return c.EventsSink }, true},
+	{"EVENTS_POLL_INTERVAL", func(c Config) string { return c.EventsPollInterval.String() }, false},
+	{"EVENTS_RETENTION", func(c Config) string { return c.EventsRetention.String() }, false},
+	{"LEADER_ELECTION", func(c Config) string { return fmt.Sprint(c.LeaderElection) }, false},
+	{"LEADER_LEASE_TTL", func(c Config) string { return c.LeaderLeaseTTL.String() }, false},
+	{"INSTANCE_ID", func(c Config) string { return c.InstanceID }, false},
+	{"RATE_SYNC_INTERVAL", func(c Config) string { return c.RateSyncInterval.String() }, false},
+	{"REQUEST_TIMEOUT", func(c Config) string { return c.RequestTimeout.String() }, false},
+	{"REQUEST_TIMEOUTS", func(c Config) string { return fmt.Sprint(c.RouteTimeouts) }, false},
+	{"LOG_LEVEL", func(c Config) string { return c.LogLevel }, false},
+	{"LOG_FORMAT", func(c Config) string { return c.LogFormat }, false},
+	{"LOG_REQUEST_SAMPLE", func(c Config) string { return fmt.Sprint(c.LogRequestSample) }, false},
+	{"USAGE_BUFFER_SIZE", func(c Config) string { return fmt.Sprint(c.UsageBufferSize) }, false},
+	{"USAGE_FLUSH_INTERVAL", func(c Config) string { return c.UsageFlushInterval.String() }, false},
+	{"USAGE_RETENTION", func(c Config) string { return c.UsageRetention.String() }, false},
+	{"MIGRATE_ON_START", func(c Config) string { return fmt.Sprint(c.MigrateOnStart) }, false},
+	{"CONFIG_FILE", func(c Config) string { return c.ConfigFile }, false},
+	{"CONFIG_WATCH_INTERVAL", func(c Config) string { return c.ConfigWatchInterval.String() }, false},
+	{"SHUTDOWN_TIMEOUT", func(c Config) string { return c.ShutdownTimeout.String() }, false},
+}
+
+// Reloader holds the running configuration and swaps its reloadable settings
+// atomically. Reads through Current never block.
+type Reloader struct {
+	current atomic.Pointer[Config]
+	load    func() (Config, error)
+
+	mu   sync.Mutex
+	subs []Subscriber
+}
+
+func NewReloader(cfg Config, load func() (Config, error)) *Reloader {
+	r := &Reloader{load: load}
+	r.current.Store(&cfg)
+	return r
+}
+
+// Current returns a snapshot of the running configuration.
+func (r *Reloader) Current() Config {
+	return *r.current.Load()
+}
+
+func (r *Reloader) Subscribe(s Subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subs = append(r.subs, s)
+}
+
+// Reload loads and validates the configuration again and applies the
+// reloadable settings that changed. On a load, validation or subscriber error
+// the running configuration is left untouched.
+func (r *Reloader) Reload() ([]Change, error) {
+	next, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.Current()
+	applied := old
+	var changes []Change
+	dirty := false
+	for _, f := range reloadable {
+		if o, n := f.get(old), f.get(next); o != n {
+			f.set(&applied, next)
+			changes = append(changes, Change{Key: f.key, Old: o, New: n, Applied: true})
+			dirty = true
+		}
+	}
+	for _, f := range restartOnly {
+		if o, n := f.get(old), f.get(next); o != n {
+			if f.secret {
+				o, n = "[REDACTED]", "[REDACTED]"
+			}
+			changes = append(changes, Change{Key: f.key, Old: o, New: n})
+		}
+	}
+
+	if !dirty {
+		return changes, nil
+	}
+	if err := applied.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.current.Store(&applied)
+	for i, s := range r.subs {
+		if err := s(old, applied); err != nil {
+			r.current.Store(&old)
+			for _, prev := range r.subs[:i] {
+				_ = prev(applied, old)
+			}
+			return nil, fmt.Errorf("settings rolled back: %w", err)
+		}
+	}
+	return changes, nil
+}
+
+// Watch reloads on SIGHUP and, when path is set and interval is positive,
+// whenever the file's modification time or size changes. onReload receives
+// the outcome of every attempt. Watch blocks until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, path string, interval time.Duration, onReload func([]Change, error)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var poll <-chan time.Time
+	if path != "" && interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	lastMod, lastSize := fileStamp(path)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			onReload(r.Reload())
+		case <-poll:
+			mod, size := fileStamp(path)
+			if mod.Equal(lastMod) && size == lastSize {
+				continue
+			}
+			lastMod, lastSize = mod, size
+			onReload(r.Reload())
+		}
+	}
+}
+
+func fileStamp(path string) (time.Time, int64) {
+	if path == "" {
+		return time.Time{}, 0
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return fi.ModTime(), fi.Size()
+}
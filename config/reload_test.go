@@ -0,0 +1,179 @@
+package config_test
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+)
+
+// reloader starts from the defaults and reloads into whatever next returns.
+func reloader(t *testing.T, next func(*config.Config)) (*config.Reloader, config.Config) {
+	t.Helper()
+	setEnv(t, nil)
+	base, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.NewReloader(base, func() (config.Config, error) {
+		cfg := base
+		next(&cfg)
+		return cfg, nil
+	}), base
+}
+
+func TestReloadRollsBackWhenASubscriberFails(t *testing.T) {
+	r, base := reloader(t, func(c *config.Config) { c.RateLimitRequests = 7 })
+
+	var seen []int
+	r.Subscribe(func(old, new config.Config) error {
+		seen = append(seen, new.RateLimitRequests)
+		return nil
+	})
+	r.Subscribe(func(old, new config.Config) error {
+		if new.RateLimitRequests == 7 {
+			return errors.New("limiter busy")
+		}
+		return nil
+	})
+
+	changes, err := r.Reload()
+	if err == nil || !strings.Contains(err.Error(), "limiter busy") {
+		t.Fatalf("reload = %v, %v, want the subscriber error", changes, err)
+	}
+	if got := r.Current().RateLimitRequests; got != base.RateLimitRequests {
+		t.Errorf("running RATE_LIMIT_REQUESTS = %d, want %d restored", got, base.RateLimitRequests)
+	}
+	// The first subscriber accepted the new value and was given the old one back.
+	want := []int{7, base.RateLimitRequests}
+	if len(seen) != 2 || seen[0] != want[0] || seen[1] != want[1] {
+		t.Errorf("first subscriber saw %v, want %v", seen, want)
+	}
+}
+
+func TestReloadReportsRestartOnlyKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		next    func(*config.Config)
+		want    []config.Change
+		current func(config.Config) bool
+	}{
+		{
+			name: "port and driver",
+			next: func(c *config.Config) {
+				c.Port = 9999
+				c.DBDriver = "postgres"
+				c.RateLimitWindow = 2 * time.Minute
+			},
+			want: []config.Change{
+				{Key: "RATE_LIMIT_WINDOW", Old: "1m0s", New: "2m0s", Applied: true},
+				{Key: "PORT", Old: "8080", New: "9999"},
+				{Key: "DB_DRIVER", Old: "sqlite", New: "postgres"},
+			},
+			current: func(c config.Config) bool {
+				return c.Port == 8080 && c.DBDriver == "sqlite" && c.RateLimitWindow == 2*time.Minute
+			},
+		},
+		{
+			name: "only restart keys",
+			next: func(c *config.Config) { c.Port = 9999 },
+			want: []config.Change{{Key: "PORT", Old: "8080", New: "9999"}},
+			current: func(c config.Config) bool {
+				return c.Port == 8080
+			},
+		},
+		{
+			name: "secrets redacted",
+			next: func(c *config.Config) { c.JWTSecret = "rotated" },
+			want: []config.Change{{Key: "JWT_SECRET", Old: "[REDACTED]", New: "[REDACTED]"}},
+			current: func(c config.Config) bool {
+				return c.JWTSecret == "test-secret"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := reloader(t, tt.next)
+			calls := 0
+			r.Subscribe(func(old, new config.Config) error { calls++; return nil })
+
+			changes, err := r.Reload()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changes) != len(tt.want) {
+				t.Fatalf("changes = %+v, want %+v", changes, tt.want)
+			}
+			for i := range tt.want {
+				if changes[i] != tt.want[i] {
+					t.Errorf("change %d = %+v, want %+v", i, changes[i], tt.want[i])
+				}
+			}
+			if !tt.current(r.Current()) {
+				t.Errorf("running config = %+v", r.Current())
+			}
+			applied := tt.want[0].Applied
+			if applied && calls != 1 || !applied && calls != 0 {
+				t.Errorf("subscriber called %d times", calls)
+			}
+		})
+	}
+}
+
+// TestReloadNoticesEverySetting changes each Config field in turn: a reload
+// must report it, or reject it when the new value is invalid, but never drop
+// it silently.
+func TestReloadNoticesEverySetting(t *testing.T) {
+	fields := reflect.TypeOf(config.Config{})
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		t.Run(field.Name, func(t *testing.T) {
+			r, _ := reloader(t, func(c *config.Config) { change(t, reflect.ValueOf(c).Elem().Field(i)) })
+			changes, err := r.Reload()
+			if err == nil && len(changes) == 0 {
+				t.Errorf("a change to %s was neither reported nor rejected", field.Name)
+			}
+		})
+	}
+}
+
+func change(t *testing.T, v reflect.Value) {
+	t.Helper()
+	switch x := v.Addr().Interface().(type) {
+	case *string:
+		*x += "x"
+	case *int:
+		*x++
+	case *bool:
+		*x = !*x
+	case *float64:
+		*x++
+	case *time.Duration:
+		*x += time.Second
+	case *[]string:
+		*x = append(*x, "X")
+	case *[]netip.Prefix:
+		*x = append(*x, netip.MustParsePrefix("10.0.0.0/8"))
+	case *map[string]time.Duration:
+		*x = map[string]time.Duration{"/api/v1/convert": time.Second}
+	default:
+		t.Fatalf("no way to change a %s", v.Type())
+	}
+}
+
+func TestReloadWithoutChangesReportsNone(t *testing.T) {
+	setEnv(t, nil)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Defaults that are generated, like INSTANCE_ID, stay the same.
+	changes, err := config.NewReloader(cfg, config.Load).Reload()
+	if err != nil || len(changes) != 0 {
+		t.Errorf("reload = %+v, %v, want no changes", changes, err)
+	}
+}
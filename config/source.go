@@ -0,0 +1,249 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.yaml.in/yaml/v3"
+)
+
+// loader resolves settings from, in order of precedence: the environment,
+// a KEY_FILE pointing at a file holding the value (Docker/Kubernetes
+// secrets), the optional config file, and finally the built-in default.
+// Malformed values are collected instead of silently replaced by defaults.
+type loader struct {
+	file map[string]string
+	errs []string
+}
+
+func newLoader(path string) (*loader, error) {
+	l := &loader{file: map[string]string{}}
+	if path == "" {
+		return l, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var doc map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &doc)
+	case ".toml":
+		err = toml.Unmarshal(raw, &doc)
+	case ".json":
+		err = json.Unmarshal(raw, &doc)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format (use .yaml, .toml or .json)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	flatten("", doc, l.file)
+	return l, nil
+}
+
+// flatten turns nested keys into their environment variable names, so
+// `db: {host: x}` and `DB_HOST=x` configure the same setting.
+func flatten(prefix string, in map[string]interface{}, out map[string]string) {
+	for k, v := range in {
+		key := strings.ToUpper(strings.ReplaceAll(k, "-", "_"))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			flatten(key, vv, out)
+		case []interface{}:
+			items := make([]string, 0, len(vv))
+			for _, item := range vv {
+				items = append(items, fmt.Sprint(item))
+			}
+			out[key] = strings.Join(items, ",")
+		case nil:
+		default:
+			out[key] = fmt.Sprint(vv)
+		}
+	}
+}
+
+func (l *loader) lookup(key string) (string, bool) {
+	if v := os.Getenv(key); v != "" {
+		return v, true
+	}
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			l.errorf("%s_FILE: %v", key, err)
+			return "", false
+		}
+		return strings.TrimRight(string(b), "\r\n"), true
+	}
+	v, ok := l.file[key]
+	return v, ok && v != ""
+}
+
+func (l *loader) errorf(format string, args ...interface{}) {
+	l.errs = append(l.errs, fmt.Sprintf(format, args...))
+}
+
+func (l *loader) getEnv(key, def string) string {
+	if v, ok := l.lookup(key); ok {
+		return v
+	}
+	return def
+}
+
+func (l *loader) mustEnv(key string) string {
+	v, ok := l.lookup(key)
+	if !ok {
+		l.errorf("%s is required (set %s or %s_FILE)", key, key, key)
+	}
+	return v
+}
+
+func (l *loader) getDuration(key string, def time.Duration) time.Duration {
+	v, ok := l.lookup(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		l.errorf("%s: %q is not a valid duration (e.g. 90s, 15m, 6h)", key, v)
+		return def
+	}
+	return d
+}
+
+func (l *loader) getInt(key string, def int) int {
+	v, ok := l.lookup(key)
+	if !ok {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		l.errorf("%s: %q is not a valid integer", key, v)
+		return def
+	}
+	return i
+}
+
+func (l *loader) getFloat(key string, def float64) float64 {
+	v, ok := l.lookup(key)
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		l.errorf("%s: %q is not a valid number", key, v)
+		return def
+	}
+	return f
+}
+
+func (l *loader) getBool(key string, def bool) bool {
+	v, ok := l.lookup(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		l.errorf("%s: %q is not a valid boolean", key, v)
+		return def
+	}
+	return b
+}
+
+func (l *loader) getList(key string) []string {
+	v, ok := l.lookup(key)
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// getCurrencies parses a comma separated list of currency codes, upper-cased;
+// def is used when key is unset.
+func (l *loader) getCurrencies(key, def string) []string {
+	v, ok := l.lookup(key)
+	if !ok {
+		v = def
+	}
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// getNetworks parses a comma separated list of CIDR prefixes; a bare address
+// stands for itself.
+func (l *loader) getNetworks(key string) []netip.Prefix {
+	var out []netip.Prefix
+	for _, item := range l.getList(key) {
+		if p, err := netip.ParsePrefix(item); err == nil {
+			out = append(out, p.Masked())
+		} else if a, err := netip.ParseAddr(item); err == nil {
+			out = append(out, netip.PrefixFrom(a, a.BitLen()))
+		} else {
+			l.errorf("%s: %q is not an IP address or CIDR prefix", key, item)
+		}
+	}
+	return out
+}
+
+// getDurationMap parses a comma separated list of key=duration pairs, such as
+// "/api/v1/convert=2s,/api/v1/admin/usage=30s".
+func (l *loader) getDurationMap(key string) map[string]time.Duration {
+	v, ok := l.lookup(key)
+	if !ok {
+		return nil
+	}
+	out := map[string]time.Duration{}
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		k, ds, found := strings.Cut(item, "=")
+		k = strings.TrimSpace(k)
+		if !found || k == "" {
+			l.errorf("%s: %q is not a key=duration pair", key, item)
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(ds))
+		if err != nil {
+			l.errorf("%s: %q is not a valid duration for %s", key, ds, k)
+			continue
+		}
+		out[k] = d
+	}
+	return out
+}
+
+// ValidationError lists every invalid setting found while loading.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
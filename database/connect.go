@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	connectInitialBackoff = 500 * time.Millisecond
+	connectMaxBackoff     = 15 * time.Second
+	connectPingTimeout    = 5 * time.Second
+)
+
+func configurePool(sqlDB *sql.DB, cfg config.Config) {
+	sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+}
+
+// pingWithRetry pings sqlDB until it answers, doubling the wait between
+// attempts up to connectMaxBackoff. It gives up when ctx is done and returns
+// the last connection error.
+func pingWithRetry(ctx context.Context, sqlDB *sql.DB, log *logger.Logger) error {
+	backoff := connectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, connectPingTimeout)
+		err := sqlDB.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		wait := backoff
+		if dl, ok := ctx.Deadline(); ok && time.Until(dl) < wait {
+			wait = time.Until(dl)
+		}
+		if wait <= 0 {
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+		log.Warn("database not reachable, retrying", logger.Fields{
+			"attempt":  attempt,
+			"retry_in": wait.String(),
+			"error":    err.Error(),
+		})
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, connectMaxBackoff)
+	}
+}
+
+func closePool(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -1,8 +1,41 @@
-package database
-
-import "gorm.io/gorm"
-
-type Database interface {
-	ConnectDB() *gorm.DB
-	MigrateDB() error
-}
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+type Database interface {
+	ConnectDB() *gorm.DB
+	// Reader returns the connection for read-only queries that tolerate
+	// replication lag: the read replica when configured, else ConnectDB().
+	Reader() *gorm.DB
+	MigrateDB() error
+	Migrator() (*Migrator, error)
+	Ping(ctx context.Context) error
+	// Notifier returns nil when the backend cannot reach other instances.
+	Notifier() Notifier
+	// Close closes the connection pools once nothing uses them any more.
+	Close() error
+}
+
+// New opens the database selected by cfg.DBDriver.
+func New(cfg config.Config, log *logger.Logger) (Database, error) {
+	switch cfg.DBDriver {
+	case DriverPostgres:
+		return NewPostgresDatabase(cfg, log)
+	case DriverSQLite:
+		return NewSQLiteDatabase(cfg, log)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.DBDriver)
+	}
+}
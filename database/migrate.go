@@ -0,0 +1,310 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationLockKey is the Postgres advisory lock held while migrating, so only
+// one replica applies migrations when several start at once.
+const migrationLockKey = 7_340_217_001
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// dialect holds the driver specific parts of the migrator.
+type dialect struct {
+	dir        string
+	createSQL  string
+	lock       func(ctx context.Context, conn *sql.Conn) error
+	unlock     func(ctx context.Context, conn *sql.Conn) error
+	insertSQL  string
+	deleteSQL  string
+	appliedSQL string
+}
+
+var postgresDialect = dialect{
+	dir: "migrations/postgres",
+	createSQL: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`,
+	lock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey)
+		return err
+	},
+	unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey)
+		return err
+	},
+	insertSQL:  "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+	deleteSQL:  "DELETE FROM schema_migrations WHERE version = $1",
+	appliedSQL: "SELECT version, applied_at FROM schema_migrations ORDER BY version",
+}
+
+// Migrator applies the embedded, numbered SQL migrations. Files are named
+// NNNN_description.up.sql / NNNN_description.down.sql and each one runs in its
+// own transaction together with its schema_migrations bookkeeping.
+type Migrator struct {
+	db         *sql.DB
+	d          dialect
+	migrations []Migration
+}
+
+func newMigrator(db *sql.DB, d dialect) (*Migrator, error) {
+	ms, err := loadMigrations(migrationFiles, d.dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, d: d, migrations: ms}, nil
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		num, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name.%s.sql", name, direction)
+		}
+		version, err := strconv.Atoi(num)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", name, num)
+		}
+		body, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Latest returns the highest known migration version.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the given number of applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return errors.New("steps must be positive")
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+			if err := m.runDown(ctx, conn, mg); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// To migrates up or down until version is the newest applied migration.
+// Version 0 rolls back everything.
+func (m *Migrator) To(ctx context.Context, version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("unknown migration version %d (latest is %d)", version, m.Latest())
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; ok && mg.Version > version {
+				if err := m.runDown(ctx, conn, mg); err != nil {
+					return err
+				}
+			}
+		}
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; !ok && mg.Version <= version {
+				if err := m.runUp(ctx, conn, mg); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var out []MigrationStatus
+	err := m.withConn(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			at, ok := applied[mg.Version]
+			out = append(out, MigrationStatus{Version: mg.Version, Name: mg.Name, Applied: ok, AppliedAt: at})
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (m *Migrator) withConn(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, m.d.createSQL); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+// withLock runs fn while holding the migration lock on a dedicated
+// connection; advisory locks are tied to the session that took them.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := m.d.lock(ctx, conn); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		_ = m.d.unlock(context.Background(), conn)
+	}()
+
+	if _, err := conn.ExecContext(ctx, m.d.createSQL); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, m.d.appliedSQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int]time.Time{}
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		out[v] = at
+	}
+	return out, rows.Err()
+}
+
+func (m *Migrator) runUp(ctx context.Context, conn *sql.Conn, mg Migration) error {
+	return m.inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, mg.Up); err != nil {
+			return fmt.Errorf("migration %d_%s up: %w", mg.Version, mg.Name, err)
+		}
+		_, err := tx.ExecContext(ctx, m.d.insertSQL, mg.Version, mg.Name, time.Now().UTC())
+		return err
+	})
+}
+
+func (m *Migrator) runDown(ctx context.Context, conn *sql.Conn, mg Migration) error {
+	if mg.Down == "" {
+		return fmt.Errorf("migration %d_%s has no down script", mg.Version, mg.Name)
+	}
+	return m.inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, mg.Down); err != nil {
+			return fmt.Errorf("migration %d_%s down: %w", mg.Version, mg.Name, err)
+		}
+		_, err := tx.ExecContext(ctx, m.d.deleteSQL, mg.Version)
+		return err
+	})
+}
+
+func (m *Migrator) inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	cfg := config.Config{DBDriver: DriverSQLite, DBPath: filepath.Join(t.TempDir(), "test.db")}
+	db, err := NewSQLiteDatabase(cfg, logger.New(logger.Options{Level: "error"}))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	sqlDB, err := db.ConnectDB().DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sqlDB
+}
+
+// appliedVersions returns the versions Status reports as applied.
+func appliedVersions(t *testing.T, m *Migrator) []int {
+	t.Helper()
+	st, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	var out []int
+	for _, s := range st {
+		if s.Applied {
+			out = append(out, s.Version)
+		}
+	}
+	return out
+}
+
+func hasTable(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n == 1
+}
+
+func versions(from, to int) []int {
+	var out []int
+	for v := from; v <= to; v++ {
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestMigratorSteps(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	m, err := newMigrator(db, sqliteDialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := m.Latest()
+
+	steps := []struct {
+		name   string
+		run    func() error
+		want   []int
+		tables map[string]bool
+	}{
+		{"up", func() error { return m.Up(ctx) }, versions(1, latest), map[string]bool{"users": true, "leases": true}},
+		{"up again is a no-op", func() error { return m.Up(ctx) }, versions(1, latest), nil},
+		{"down two", func() error { return m.Down(ctx, 2) }, versions(1, latest-2), map[string]bool{"leases": false}},
+		{"to 2 goes down", func() error { return m.To(ctx, 2) }, versions(1, 2), map[string]bool{"users": true, "audit_events": false}},
+		{"to 4 goes up", func() error { return m.To(ctx, 4) }, versions(1, 4), map[string]bool{"audit_events": true, "usage_records": true}},
+		{"down more than applied", func() error { return m.Down(ctx, 10) }, nil, map[string]bool{"users": false}},
+		{"to latest", func() error { return m.To(ctx, latest) }, versions(1, latest), map[string]bool{"users": true, "leases": true}},
+		{"to 0", func() error { return m.To(ctx, 0) }, nil, map[string]bool{"users": false}},
+	}
+	for _, s := range steps {
+		if err := s.run(); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if got := appliedVersions(t, m); !reflect.DeepEqual(got, s.want) {
+			t.Fatalf("%s: applied %v, want %v", s.name, got, s.want)
+		}
+		for table, want := range s.tables {
+			if got := hasTable(t, db, table); got != want {
+				t.Errorf("%s: table %s exists = %v, want %v", s.name, table, got, want)
+			}
+		}
+	}
+
+	for _, v := range []int{-1, latest + 1} {
+		if err := m.To(ctx, v); err == nil || !strings.Contains(err.Error(), "unknown migration version") {
+			t.Errorf("To(%d) = %v, want an unknown version error", v, err)
+		}
+	}
+	if err := m.Down(ctx, 0); err == nil {
+		t.Error("Down(0) succeeded")
+	}
+}
+
+func TestMigratorStatus(t *testing.T) {
+	ctx := context.Background()
+	m, err := newMigrator(openSQLite(t), sqliteDialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.To(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	st, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st) != m.Latest() {
+		t.Fatalf("status lists %d migrations, want %d", len(st), m.Latest())
+	}
+	for i, s := range st {
+		if s.Version != i+1 || s.Name == "" {
+			t.Errorf("status[%d] = %+v", i, s)
+		}
+		if applied := s.Version <= 2; s.Applied != applied || s.AppliedAt.IsZero() == applied {
+			t.Errorf("status[%d] = %+v, want applied %v with a time only when applied", i, s, applied)
+		}
+	}
+	if st[0].Name != "init" || st[1].Name != "user_roles" {
+		t.Errorf("names = %q, %q", st[0].Name, st[1].Name)
+	}
+}
+
+func TestMigratorMissingDownScript(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"m/0001_things.up.sql":      {Data: []byte("CREATE TABLE things (id INTEGER)")},
+		"m/0001_things.down.sql":    {Data: []byte("DROP TABLE things")},
+		"m/0002_more_things.up.sql": {Data: []byte("CREATE TABLE more_things (id INTEGER)")},
+		"m/0003_even_more.up.sql":   {Data: []byte("CREATE TABLE even_more (id INTEGER)")},
+		"m/0003_even_more.down.sql": {Data: []byte("DROP TABLE even_more")},
+		"m/README.md":               {Data: []byte("ignored")},
+	}
+	ms, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := openSQLite(t)
+	m := &Migrator{db: db, d: sqliteDialect, migrations: ms}
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 0003 rolls back, then 0002 stops the run and stays applied.
+	err = m.To(ctx, 0)
+	if err == nil || !strings.Contains(err.Error(), "migration 2_more_things has no down script") {
+		t.Fatalf("To(0) = %v, want a missing down script error", err)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("applied %v, want [1 2]", got)
+	}
+	if !hasTable(t, db, "more_things") || hasTable(t, db, "even_more") {
+		t.Error("schema does not match the applied versions")
+	}
+}
+
+func TestLoadMigrationsRejectsBadFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"no up script", fstest.MapFS{"m/0001_x.down.sql": {}}, "has no up script"},
+		{"no version", fstest.MapFS{"m/init.up.sql": {}}, "expected NNNN_name.up.sql"},
+		{"bad version", fstest.MapFS{"m/00a1_x.up.sql": {}}, "invalid version"},
+		{"conflicting names", fstest.MapFS{"m/0001_x.up.sql": {}, "m/0001_y.down.sql": {}}, "conflicting names"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadMigrations(tt.fsys, "m"); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rates;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,22 @@
+-- Baseline schema. IF NOT EXISTS lets databases previously managed by
+-- GORM AutoMigrate adopt the migration history without changes.
+CREATE TABLE IF NOT EXISTS users (
+    id            BIGSERIAL PRIMARY KEY,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ,
+    username      VARCHAR(100),
+    email         VARCHAR(100) NOT NULL,
+    password      TEXT NOT NULL,
+    token_version BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT uni_users_username UNIQUE (username)
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS rates (
+    currency   VARCHAR(3) PRIMARY KEY,
+    rate       DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_rates_updated_at ON rates (updated_at);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS usage_records (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency   VARCHAR(3) NOT NULL,
+    amount        DOUBLE PRECISION NOT NULL,
+    rate          DOUBLE PRECISION NOT NULL,
+    snapshot_at   TIMESTAMPTZ NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_usage_user_created ON usage_records (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_usage_records_created_at ON usage_records (created_at);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS rate_overrides (
+    id               BIGSERIAL PRIMARY KEY,
+    currency         VARCHAR(3) NOT NULL,
+    base             VARCHAR(3) NOT NULL,
+    rate             DOUBLE PRECISION NOT NULL,
+    reason           VARCHAR(255) NOT NULL,
+    expires_at       TIMESTAMPTZ NOT NULL,
+    created_by_id    BIGINT,
+    created_by_email VARCHAR(100),
+    created_at       TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rate_overrides_currency ON rate_overrides (currency);
+CREATE INDEX IF NOT EXISTS idx_rate_overrides_expires_at ON rate_overrides (expires_at);
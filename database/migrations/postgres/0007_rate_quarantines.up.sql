@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS rate_quarantines (
+    id               BIGSERIAL PRIMARY KEY,
+    base             VARCHAR(3) NOT NULL,
+    provider         VARCHAR(100) NOT NULL,
+    rates            TEXT NOT NULL,
+    anomalies        TEXT NOT NULL,
+    fetched_at       TIMESTAMPTZ NOT NULL,
+    status           VARCHAR(16) NOT NULL,
+    decided_by_id    BIGINT,
+    decided_by_email VARCHAR(100),
+    decided_at       TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rate_quarantines_status ON rate_quarantines (status);
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id                BIGSERIAL PRIMARY KEY,
+    user_id           BIGINT NOT NULL,
+    from_currency     VARCHAR(3) NOT NULL,
+    to_currency       VARCHAR(3) NOT NULL,
+    condition         VARCHAR(16) NOT NULL,
+    threshold         DOUBLE PRECISION NOT NULL,
+    change_window     BIGINT NOT NULL,
+    cooldown          BIGINT NOT NULL,
+    webhook_url       VARCHAR(2048) NOT NULL,
+    webhook_secret    VARCHAR(100) NOT NULL,
+    last_rate         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    baseline_rate     DOUBLE PRECISION NOT NULL DEFAULT 0,
+    baseline_at       TIMESTAMPTZ,
+    last_triggered_at TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_alert_rules_user_id ON alert_rules (user_id);
+
+CREATE TABLE IF NOT EXISTS alert_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    rule_id         BIGINT NOT NULL,
+    user_id         BIGINT NOT NULL,
+    event           VARCHAR(32) NOT NULL,
+    payload         TEXT NOT NULL,
+    status          VARCHAR(16) NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    response_status INTEGER,
+    last_error      VARCHAR(255),
+    delivered_at    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_rule_id ON alert_deliveries (rule_id);
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_user_id ON alert_deliveries (user_id);
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_status ON alert_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_next_attempt_at ON alert_deliveries (next_attempt_at);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS users (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at    DATETIME,
+    updated_at    DATETIME,
+    deleted_at    DATETIME,
+    username      VARCHAR(100) UNIQUE,
+    email         VARCHAR(100) NOT NULL,
+    password      TEXT NOT NULL,
+    token_version INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS rates (
+    currency   VARCHAR(3) PRIMARY KEY,
+    rate       REAL NOT NULL,
+    updated_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_rates_updated_at ON rates (updated_at);
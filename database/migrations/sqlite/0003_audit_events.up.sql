@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at  DATETIME NOT NULL,
+    actor_id    INTEGER,
+    actor_email VARCHAR(100),
+    action      VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32),
+    target_id   VARCHAR(64),
+    outcome     VARCHAR(16) NOT NULL,
+    reason      VARCHAR(255),
+    ip          VARCHAR(64),
+    user_agent  VARCHAR(255),
+    trace_id    VARCHAR(64)
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_outcome ON audit_events (outcome);
+CREATE INDEX IF NOT EXISTS idx_audit_events_trace_id ON audit_events (trace_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS usage_records (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id       INTEGER NOT NULL,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency   VARCHAR(3) NOT NULL,
+    amount        REAL NOT NULL,
+    rate          REAL NOT NULL,
+    snapshot_at   DATETIME NOT NULL,
+    created_at    DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_usage_user_created ON usage_records (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_usage_records_created_at ON usage_records (created_at);
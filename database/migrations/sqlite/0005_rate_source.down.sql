@@ -0,0 +1,2 @@
+ALTER TABLE rates DROP COLUMN provider;
+ALTER TABLE rates DROP COLUMN base;
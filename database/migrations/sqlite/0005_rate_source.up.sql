@@ -0,0 +1,4 @@
+-- Record which base and provider each stored rate was quoted in, so the
+-- database fallback never mixes rates fetched under different bases.
+ALTER TABLE rates ADD COLUMN base VARCHAR(3) NOT NULL DEFAULT '';
+ALTER TABLE rates ADD COLUMN provider VARCHAR(100) NOT NULL DEFAULT '';
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS rate_overrides (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    currency         VARCHAR(3) NOT NULL,
+    base             VARCHAR(3) NOT NULL,
+    rate             REAL NOT NULL,
+    reason           VARCHAR(255) NOT NULL,
+    expires_at       DATETIME NOT NULL,
+    created_by_id    INTEGER,
+    created_by_email VARCHAR(100),
+    created_at       DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rate_overrides_currency ON rate_overrides (currency);
+CREATE INDEX IF NOT EXISTS idx_rate_overrides_expires_at ON rate_overrides (expires_at);
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id                INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id           INTEGER NOT NULL,
+    from_currency     VARCHAR(3) NOT NULL,
+    to_currency       VARCHAR(3) NOT NULL,
+    condition         VARCHAR(16) NOT NULL,
+    threshold         REAL NOT NULL,
+    change_window     INTEGER NOT NULL,
+    cooldown          INTEGER NOT NULL,
+    webhook_url       VARCHAR(2048) NOT NULL,
+    webhook_secret    VARCHAR(100) NOT NULL,
+    last_rate         REAL NOT NULL DEFAULT 0,
+    baseline_rate     REAL NOT NULL DEFAULT 0,
+    baseline_at       DATETIME,
+    last_triggered_at DATETIME,
+    created_at        DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_alert_rules_user_id ON alert_rules (user_id);
+
+CREATE TABLE IF NOT EXISTS alert_deliveries (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    rule_id         INTEGER NOT NULL,
+    user_id         INTEGER NOT NULL,
+    event           VARCHAR(32) NOT NULL,
+    payload         TEXT NOT NULL,
+    status          VARCHAR(16) NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    response_status INTEGER,
+    last_error      VARCHAR(255),
+    delivered_at    DATETIME,
+    created_at      DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_rule_id ON alert_deliveries (rule_id);
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_user_id ON alert_deliveries (user_id);
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_status ON alert_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_next_attempt_at ON alert_deliveries (next_attempt_at);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id     VARCHAR(36) NOT NULL,
+    type         VARCHAR(64) NOT NULL,
+    key          VARCHAR(64) NOT NULL,
+    payload      TEXT NOT NULL,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    last_error   VARCHAR(255),
+    published_at DATETIME,
+    created_at   DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_event_id ON outbox_events (event_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events (published_at);
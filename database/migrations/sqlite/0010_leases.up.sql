@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS leases (
+    name       VARCHAR(64) PRIMARY KEY,
+    holder     VARCHAR(128) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    renewed_at DATETIME NOT NULL
+);
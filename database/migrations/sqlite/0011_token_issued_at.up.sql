@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN token_issued_at DATETIME;
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// Notifier broadcasts small messages to every instance sharing the database.
+type Notifier interface {
+	Notify(ctx context.Context, channel, payload string) error
+	// Listen calls fn for every message on channel until ctx is done,
+	// reconnecting after connection errors. onListen, when not nil, is called
+	// each time the listener (re)connects: messages sent while it was
+	// disconnected are lost, so callers resynchronise there.
+	Listen(ctx context.Context, channel string, onListen func(), fn func(payload string)) error
+}
+
+const listenRetryDelay = 2 * time.Second
+
+func (p *postgresDatabase) Notifier() Notifier {
+	return p
+}
+
+func (p *postgresDatabase) Notify(ctx context.Context, channel, payload string) error {
+	return p.Db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", channel, payload).Error
+}
+
+func (p *postgresDatabase) Listen(ctx context.Context, channel string, onListen func(), fn func(payload string)) error {
+	for {
+		err := p.listenOnce(ctx, channel, onListen, fn)
+		if ctx.Err() != nil {
+			return nil
+		}
+		p.log.Warn("notification listener disconnected, reconnecting", logger.Fields{
+			"channel": channel,
+			"error":   fmt.Sprint(err),
+		})
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+// listenOnce holds one pooled connection for as long as it listens. The
+// connection is always reported bad afterwards so database/sql discards it
+// instead of handing a LISTENing session to other queries.
+func (p *postgresDatabase) listenOnce(ctx context.Context, channel string, onListen func(), fn func(payload string)) error {
+	sqlDB, err := p.Db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var listenErr error
+	_ = conn.Raw(func(dc any) error {
+		sc, ok := dc.(*stdlib.Conn)
+		if !ok {
+			listenErr = errors.New("postgres connection is not a pgx connection")
+			return driver.ErrBadConn
+		}
+		pc := sc.Conn()
+		if _, err := pc.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			listenErr = err
+			return driver.ErrBadConn
+		}
+		if onListen != nil {
+			onListen()
+		}
+		for {
+			n, err := pc.WaitForNotification(ctx)
+			if err != nil {
+				listenErr = err
+				return driver.ErrBadConn
+			}
+			fn(n.Payload)
+		}
+	})
+	return listenErr
+}
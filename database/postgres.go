@@ -1,11 +1,11 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"sync"
 
 	"github.com/spksupakorn/Currency-Converter/config"
-	"github.com/spksupakorn/Currency-Converter/internal/models"
 	"github.com/spksupakorn/Currency-Converter/pkg/logger"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -14,42 +14,104 @@ import (
 
 type postgresDatabase struct {
 	Db *gorm.DB
+	// Replica serves the read-heavy queries when DB_REPLICA_HOST is set;
+	// otherwise it is the primary.
+	Replica *gorm.DB
+
+	log *logger.Logger
 }
 
-var (
-	once       sync.Once
-	dbInstance *postgresDatabase
-)
+// NewPostgresDatabase connects to the primary, and the read replica when one
+// is configured, retrying until DB_CONNECT_TIMEOUT so the app can start
+// before Postgres is accepting connections.
+func NewPostgresDatabase(cfg config.Config, log *logger.Logger) (Database, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DBConnectTimeout)
+	defer cancel()
 
-func NewPostgresDatabase(cfg config.Config, log *logger.Logger) Database {
-	once.Do(func() {
-		dsn := fmt.Sprintf(
-			"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
-			cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort, cfg.DBSSLMODE, cfg.DBTimeZone,
-		)
+	db, err := openPostgres(ctx, cfg, cfg.DBHost, cfg.DBPort, log)
+	if err != nil {
+		return nil, fmt.Errorf("connect to postgres at %s:%d: %w", cfg.DBHost, cfg.DBPort, err)
+	}
+	log.Info("connected to postgres database", logger.Fields{"host": cfg.DBHost, "port": cfg.DBPort, "db": cfg.DBName})
 
-		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-			Logger: gormlogger.Default.LogMode(gormlogger.Silent),
-		})
+	replica := db
+	if cfg.DBReplicaHost != "" {
+		replica, err = openPostgres(ctx, cfg, cfg.DBReplicaHost, cfg.DBReplicaPort, log)
 		if err != nil {
-			panic("failed to connect database")
+			_ = closePool(db)
+			return nil, fmt.Errorf("connect to postgres replica at %s:%d: %w", cfg.DBReplicaHost, cfg.DBReplicaPort, err)
 		}
+		log.Info("connected to postgres read replica", logger.Fields{"host": cfg.DBReplicaHost, "port": cfg.DBReplicaPort})
+	}
 
-		fmt.Println("💰 Successfully connected to the database")
+	return &postgresDatabase{Db: db, Replica: replica, log: log}, nil
+}
 
-		dbInstance = &postgresDatabase{Db: db}
+func openPostgres(ctx context.Context, cfg config.Config, host string, port int, log *logger.Logger) (*gorm.DB, error) {
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
+		host, cfg.DBUser, cfg.DBPassword, cfg.DBName, port, cfg.DBSSLMODE, cfg.DBTimeZone,
+	)
+	// The automatic ping is skipped so the first connection attempt goes
+	// through the retry loop below instead of failing Open.
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:               gormlogger.Default.LogMode(gormlogger.Silent),
+		DisableAutomaticPing: true,
 	})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sqlDB, cfg)
 
-	return dbInstance
+	if err := pingWithRetry(ctx, sqlDB, log.WithFields(logger.Fields{"host": host, "port": port})); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
 func (p *postgresDatabase) ConnectDB() *gorm.DB {
 	return p.Db
 }
 
+func (p *postgresDatabase) Reader() *gorm.DB {
+	return p.Replica
+}
+
+// MigrateDB applies all pending versioned migrations.
 func (p *postgresDatabase) MigrateDB() error {
-	return p.Db.AutoMigrate(
-		&models.User{},
-		&models.Rate{},
-	)
+	m, err := p.Migrator()
+	if err != nil {
+		return err
+	}
+	return m.Up(context.Background())
+}
+
+func (p *postgresDatabase) Migrator() (*Migrator, error) {
+	sqlDB, err := p.Db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(sqlDB, postgresDialect)
+}
+
+func (p *postgresDatabase) Ping(ctx context.Context) error {
+	sqlDB, err := p.Db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (p *postgresDatabase) Close() error {
+	var errs []error
+	if p.Replica != p.Db {
+		errs = append(errs, closePool(p.Replica))
+	}
+	errs = append(errs, closePool(p.Db))
+	return errors.Join(errs...)
 }
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/glebarez/sqlite"
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+type sqliteDatabase struct {
+	Db *gorm.DB
+}
+
+// sqliteDialect has no cross-process lock: SQLite is meant for a single local
+// or embedded instance, and each migration still runs in its own transaction.
+var sqliteDialect = dialect{
+	dir: "migrations/sqlite",
+	createSQL: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`,
+	lock:       func(ctx context.Context, conn *sql.Conn) error { return nil },
+	unlock:     func(ctx context.Context, conn *sql.Conn) error { return nil },
+	insertSQL:  "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+	deleteSQL:  "DELETE FROM schema_migrations WHERE version = ?",
+	appliedSQL: "SELECT version, applied_at FROM schema_migrations ORDER BY version",
+}
+
+// NewSQLiteDatabase opens (creating if needed) the SQLite file at
+// cfg.DBPath.
+func NewSQLiteDatabase(cfg config.Config, log *logger.Logger) (Database, error) {
+	if dir := filepath.Dir(cfg.DBPath); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create sqlite directory: %w", err)
+		}
+	}
+	dsn := cfg.DBPath + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sqlDB, cfg)
+	log.Info("connected to sqlite database", logger.Fields{"path": cfg.DBPath})
+	return &sqliteDatabase{Db: db}, nil
+}
+
+func (s *sqliteDatabase) ConnectDB() *gorm.DB {
+	return s.Db
+}
+
+func (s *sqliteDatabase) Reader() *gorm.DB {
+	return s.Db
+}
+
+// Notifier returns nil: a SQLite file is served by a single instance.
+func (s *sqliteDatabase) Notifier() Notifier {
+	return nil
+}
+
+func (s *sqliteDatabase) MigrateDB() error {
+	m, err := s.Migrator()
+	if err != nil {
+		return err
+	}
+	return m.Up(context.Background())
+}
+
+func (s *sqliteDatabase) Migrator() (*Migrator, error) {
+	sqlDB, err := s.Db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(sqlDB, sqliteDialect)
+}
+
+func (s *sqliteDatabase) Ping(ctx context.Context) error {
+	sqlDB, err := s.Db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (s *sqliteDatabase) Close() error {
+	return closePool(s.Db)
+}
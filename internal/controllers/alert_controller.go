@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"github.com/spksupakorn/Currency-Converter/pkg/response"
+)
+
+type AlertController struct {
+	alerts services.AlertService
+	log    *logger.Logger
+}
+
+func NewAlertController(alerts services.AlertService, log *logger.Logger) *AlertController {
+	return &AlertController{alerts: alerts, log: log}
+}
+
+type alertReq struct {
+	From      string  `json:"from" binding:"required" example:"USD"`
+	To        string  `json:"to" binding:"required" example:"THB"`
+	Condition string  `json:"condition" binding:"required" enums:"above,below,change"`
+	Threshold float64 `json:"threshold" binding:"required" example:"36"`
+	// Window applies to change alerts only; default 24h.
+	Window     string `json:"window" example:"24h"`
+	Cooldown   string `json:"cooldown" example:"1h"`
+	WebhookURL string `json:"webhook_url" binding:"required" example:"https://example.com/hooks/rates"`
+}
+
+type alertResponse struct {
+	models.AlertRule
+	Window   string `json:"window,omitempty"`
+	Cooldown string `json:"cooldown"`
+	// WebhookSecret signs every call; it is only shown when the alert is
+	// created.
+	WebhookSecret string `json:"webhook_secret,omitempty"`
+}
+
+func newAlertResponse(r models.AlertRule) alertResponse {
+	resp := alertResponse{AlertRule: r, Cooldown: r.Cooldown.String()}
+	if r.Condition == models.AlertChange {
+		resp.Window = r.Window.String()
+	}
+	return resp
+}
+
+type alertListResponse struct {
+	Alerts []alertResponse `json:"alerts"`
+}
+
+type deliveryListResponse struct {
+	Deliveries []models.AlertDelivery `json:"deliveries"`
+}
+
+// CreateAlert godoc
+// @Summary      Create a rate alert
+// @Description  Call a webhook when the rate of from in to rises above or falls below threshold, or moves by more than threshold percent within window. Alerts are checked after every rate refresh and stay quiet for cooldown after firing. Calls are signed with the returned webhook_secret: X-Webhook-Signature is sha256= and the hex HMAC-SHA256 of X-Webhook-Timestamp, a dot and the body.
+// @Tags         Alerts
+// @Accept       json
+// @Produce      json
+// @Param        alertReq  body      alertReq  true  "Alert"
+// @Success      201       {object}  alertResponse
+// @Failure      400       {object}  response.ErrorResponse
+// @Failure      401       {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /alerts [post]
+func (h *AlertController) CreateAlert(c *gin.Context) {
+	var req alertReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, "invalid_request", err)
+		return
+	}
+	rule := models.AlertRule{
+		UserID:       c.GetUint("user_id"),
+		FromCurrency: req.From,
+		ToCurrency:   req.To,
+		Condition:    strings.ToLower(strings.TrimSpace(req.Condition)),
+		Threshold:    req.Threshold,
+		WebhookURL:   strings.TrimSpace(req.WebhookURL),
+	}
+	var err error
+	if rule.Window, err = optionalDuration(req.Window); err != nil {
+		response.BadRequest(c, "validation_error", "window must be a duration such as 24h")
+		return
+	}
+	if rule.Cooldown, err = optionalDuration(req.Cooldown); err != nil {
+		response.BadRequest(c, "validation_error", "cooldown must be a duration such as 1h")
+		return
+	}
+
+	saved, err := h.alerts.CreateRule(c.Request.Context(), rule)
+	if err != nil {
+		if timedOut(c, err) {
+			return
+		}
+		response.BadRequest(c, "alert_rejected", err.Error())
+		return
+	}
+	resp := newAlertResponse(saved)
+	resp.WebhookSecret = saved.WebhookSecret
+	c.JSON(http.StatusCreated, resp)
+}
+
+func optionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// ListAlerts godoc
+// @Summary      List my rate alerts
+// @Tags         Alerts
+// @Produce      json
+// @Success      200  {object}  alertListResponse
+// @Failure      401  {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /alerts [get]
+func (h *AlertController) ListAlerts(c *gin.Context) {
+	rules, err := h.alerts.ListRules(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		if timedOut(c, err) {
+			return
+		}
+		logger.FromContext(c.Request.Context(), h.log).Error("failed to list alerts", logger.Fields{"error": err.Error()})
+		response.InternalError(c, "alerts_unavailable", "could not list alerts")
+		return
+	}
+	out := make([]alertResponse, len(rules))
+	for i, r := range rules {
+		out[i] = newAlertResponse(r)
+	}
+	c.JSON(http.StatusOK, alertListResponse{Alerts: out})
+}
+
+// DeleteAlert godoc
+// @Summary      Delete a rate alert
+// @Description  Delete an alert together with its delivery log.
+// @Tags         Alerts
+// @Param        id  path  int  true  "Alert ID"
+// @Success      204
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /alerts/{id} [delete]
+func (h *AlertController) DeleteAlert(c *gin.Context) {
+	id, ok := pathID(c, "alert_not_found")
+	if !ok {
+		return
+	}
+	if err := h.alerts.DeleteRule(c.Request.Context(), c.GetUint("user_id"), id); err != nil {
+		if errors.Is(err, services.ErrAlertNotFound) {
+			response.NotFound(c, "alert_not_found", "no such alert")
+			return
+		}
+		if timedOut(c, err) {
+			return
+		}
+		logger.FromContext(c.Request.Context(), h.log).Error("failed to delete alert", logger.Fields{"id": id, "error": err.Error()})
+		response.InternalError(c, "alert_delete_failed", "could not delete the alert")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TestAlert godoc
+// @Summary      Test an alert webhook
+// @Description  Send a signed rate.alert.test event to the alert's webhook once, right away, and return the logged delivery.
+// @Tags         Alerts
+// @Produce      json
+// @Param        id   path      int  true  "Alert ID"
+// @Success      200  {object}  models.AlertDelivery
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /alerts/{id}/test [post]
+func (h *AlertController) TestAlert(c *gin.Context) {
+	id, ok := pathID(c, "alert_not_found")
+	if !ok {
+		return
+	}
+	d, err := h.alerts.TestRule(c.Request.Context(), c.GetUint("user_id"), id)
+	if err != nil {
+		if errors.Is(err, services.ErrAlertNotFound) {
+			response.NotFound(c, "alert_not_found", "no such alert")
+			return
+		}
+		if timedOut(c, err) {
+			return
+		}
+		logger.FromContext(c.Request.Context(), h.log).Error("failed to test alert webhook", logger.Fields{"id": id, "error": err.Error()})
+		response.InternalError(c, "alert_test_failed", "could not send the test event")
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// ListDeliveries godoc
+// @Summary      Webhook delivery log
+// @Description  List the webhook calls of my alerts, newest first (at most 100). Dead deliveries ran out of attempts and can be retried.
+// @Tags         Alerts
+// @Produce      json
+// @Param        alert_id  query     int     false  "Only deliveries of this alert"
+// @Param        status    query     string  false  "pending, delivered or dead"
+// @Param        limit     query     int     false  "Maximum number of deliveries (default 100)"
+// @Success      200       {object}  deliveryListResponse
+// @Failure      400       {object}  response.ErrorResponse
+// @Failure      401       {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /alerts/deliveries [get]
+func (h *AlertController) ListDeliveries(c *gin.Context) {
+	f := repositories.DeliveryFilter{UserID: c.GetUint("user_id"), Status: c.Query("status")}
+	if s := c.Query("alert_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			response.BadRequest(c, "validation_error", "alert_id must be a positive integer")
+			return
+		}
+		f.RuleID = uint(id)
+	}
+	limit, err := queryInt(c, "limit")
+	if err != nil || limit < 0 {
+		response.BadRequest(c, "validation_error", "limit must be a positive integer")
+		return
+	}
+	f.Limit = limit
+
+	list, err := h.alerts.ListDeliveries(c.Request.Context(), f)
+	if err != nil {
+		if timedOut(c, err) {
+			return
+		}
+		response.BadRequest(c, "invalid_query", err.Error())
+		return
+	}
+	if list == nil {
+		list = []models.AlertDelivery{}
+	}
+	c.JSON(http.StatusOK, deliveryListResponse{Deliveries: list})
+}
+
+// RetryDelivery godoc
+// @Summary      Retry a dead webhook delivery
+// @Description  Queue a dead delivery again with a fresh set of attempts.
+// @Tags         Alerts
+// @Produce      json
+// @Param        id   path      int  true  "Delivery ID"
+// @Success      200  {object}  models.AlertDelivery
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse  "The delivery is not dead"
+// @Security     BearerAuth
+// @Router       /alerts/deliveries/{id}/retry [post]
+func (h *AlertController) RetryDelivery(c *gin.Context) {
+	id, ok := pathID(c, "delivery_not_found")
+	if !ok {
+		return
+	}
+	d, err := h.alerts.RetryDelivery(c.Request.Context(), c.GetUint("user_id"), id)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, d)
+	case errors.Is(err, services.ErrDeliveryNotFound):
+		response.NotFound(c, "delivery_not_found", "no such delivery")
+	case errors.Is(err, services.ErrDeliveryNotDead):
+		response.Conflict(c, "delivery_not_dead", err.Error())
+	case timedOut(c, err):
+	default:
+		logger.FromContext(c.Request.Context(), h.log).Error("failed to retry webhook delivery", logger.Fields{"id": id, "error": err.Error()})
+		response.InternalError(c, "delivery_retry_failed", "could not retry the delivery")
+	}
+}
+
+// pathID parses the :id parameter; anything but a positive integer cannot
+// name a record, so it answers 404 with code.
+func pathID(c *gin.Context, code string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		response.NotFound(c, code, "not found")
+		return 0, false
+	}
+	return uint(id), true
+}
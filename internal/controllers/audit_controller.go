@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"github.com/spksupakorn/Currency-Converter/pkg/response"
+)
+
+type AuditController struct {
+	audit services.AuditService
+	log   *logger.Logger
+}
+
+func NewAuditController(audit services.AuditService, log *logger.Logger) *AuditController {
+	return &AuditController{audit: audit, log: log}
+}
+
+type auditListResponse struct {
+	Events []models.AuditEvent `json:"events"`
+	Total  int64               `json:"total"`
+	Offset int                 `json:"offset"`
+}
+
+// MyActivity godoc
+// @Summary      List my account activity
+// @Description  List security events (logins, failed logins, logouts, token revocations) for the current user, newest first.
+// @Tags         Account
+// @Produce      json
+// @Param        action  query     string  false  "Filter by action (e.g. auth.login)"
+// @Param        limit   query     int     false  "Page size (default 50, max 200)"
+// @Param        offset  query     int     false  "Page offset"
+// @Success      200     {object}  auditListResponse
+// @Failure      400     {object}  response.ErrorResponse
+// @Failure      401     {object}  response.ErrorResponse
+// @Failure      500     {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /me/activity [get]
+func (h *AuditController) MyActivity(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	f, ok := bindAuditFilter(c)
+	if !ok {
+		return
+	}
+	f.ActorID = &userID
+	f.ActorEmail = c.GetString("user_email")
+	h.list(c, f)
+}
+
+// ListEvents godoc
+// @Summary      Query the audit log
+// @Description  Query audit events across all users. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Param        actor_id  query     int     false  "Filter by actor user ID"
+// @Param        email     query     string  false  "Filter by actor email"
+// @Param        action    query     string  false  "Filter by action (e.g. auth.login)"
+// @Param        outcome   query     string  false  "Filter by outcome (success or failure)"
+// @Param        from      query     string  false  "Only events at or after this RFC3339 time"
+// @Param        to        query     string  false  "Only events before this RFC3339 time"
+// @Param        limit     query     int     false  "Page size (default 50, max 200)"
+// @Param        offset    query     int     false  "Page offset"
+// @Success      200       {object}  auditListResponse
+// @Failure      400       {object}  response.ErrorResponse
+// @Failure      401       {object}  response.ErrorResponse
+// @Failure      403       {object}  response.ErrorResponse
+// @Failure      500       {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/audit-events [get]
+func (h *AuditController) ListEvents(c *gin.Context) {
+	f, ok := bindAuditFilter(c)
+	if !ok {
+		return
+	}
+	if s := c.Query("actor_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			response.BadRequest(c, "validation_error", "actor_id must be a positive integer")
+			return
+		}
+		actorID := uint(id)
+		f.ActorID = &actorID
+	}
+	f.ActorEmail = normalizeEmail(c.Query("email"))
+	f.Outcome = c.Query("outcome")
+	if f.Outcome != "" && f.Outcome != models.AuditOutcomeSuccess && f.Outcome != models.AuditOutcomeFailure {
+		response.BadRequest(c, "validation_error", "outcome must be success or failure")
+		return
+	}
+	h.list(c, f)
+}
+
+func (h *AuditController) list(c *gin.Context, f repositories.AuditFilter) {
+	events, total, err := h.audit.List(c.Request.Context(), f)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidQuery) {
+			response.BadRequest(c, "invalid_query", err.Error())
+			return
+		}
+		if timedOut(c, err) {
+			return
+		}
+		logger.FromContext(c.Request.Context(), h.log).Error("failed to list audit events", logger.Fields{"error": err.Error()})
+		response.InternalError(c, "audit_unavailable", "could not list audit events")
+		return
+	}
+	if events == nil {
+		events = []models.AuditEvent{}
+	}
+	c.JSON(http.StatusOK, auditListResponse{
+		Events: events,
+		Total:  total,
+		Offset: f.Offset,
+	})
+}
+
+func bindAuditFilter(c *gin.Context) (repositories.AuditFilter, bool) {
+	var f repositories.AuditFilter
+	f.Action = c.Query("action")
+
+	var err error
+	if f.Limit, err = queryInt(c, "limit"); err != nil {
+		response.BadRequest(c, "validation_error", "limit must be an integer")
+		return f, false
+	}
+	if f.Offset, err = queryInt(c, "offset"); err != nil {
+		response.BadRequest(c, "validation_error", "offset must be an integer")
+		return f, false
+	}
+	if f.From, err = queryTime(c, "from"); err != nil {
+		response.BadRequest(c, "validation_error", "from must be an RFC3339 timestamp")
+		return f, false
+	}
+	if f.To, err = queryTime(c, "to"); err != nil {
+		response.BadRequest(c, "validation_error", "to must be an RFC3339 timestamp")
+		return f, false
+	}
+	return f, true
+}
+
+func queryInt(c *gin.Context, key string) (int, error) {
+	s := c.Query(key)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func queryTime(c *gin.Context, key string) (time.Time, error) {
+	s := c.Query(key)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// newAuditEvent pre-fills the request metadata every audit event carries.
+func newAuditEvent(c *gin.Context, action string) models.AuditEvent {
+	evt := models.AuditEvent{
+		Action:    action,
+		Outcome:   models.AuditOutcomeSuccess,
+		IP:        c.ClientIP(),
+		UserAgent: truncate(c.Request.UserAgent(), 255),
+		TraceID:   c.GetString("trace_id"),
+	}
+	if id := c.GetUint("user_id"); id != 0 {
+		evt.ActorID = &id
+		evt.ActorEmail = c.GetString("user_email")
+	}
+	return evt
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
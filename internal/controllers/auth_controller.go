@@ -1,109 +1,161 @@
-package controllers
-
-import (
-	"net/http"
-
-	"github.com/gin-gonic/gin"
-
-	"github.com/spksupakorn/Currency-Converter/internal/services"
-	"github.com/spksupakorn/Currency-Converter/pkg/logger"
-	"github.com/spksupakorn/Currency-Converter/pkg/response"
-)
-
-type AuthController struct {
-	auth services.AuthService
-	log  *logger.Logger
-}
-
-func NewAuthController(auth services.AuthService, log *logger.Logger) *AuthController {
-	return &AuthController{auth: auth, log: log}
-}
-
-type registerReq struct {
-	Username string `json:"username"`
-	Email    string `json:"email" binding:"required"`
-	Password string `json:"password" binding:"required,min=8"`
-}
-
-// Register godoc
-// @Summary      Register a new user
-// @Description  Register a new user with email and password
-// @Tags         Authentication
-// @Accept       json
-// @Produce      json
-// @Param        registerReq  body      registerReq  true  "Register Request"
-// @Success      201          {object}  map[string]string
-// @Failure      400          {object}  response.ErrorResponse
-// @Failure      500          {object}  response.ErrorResponse
-// @Router       /auth/register [post]
-func (h *AuthController) Register(c *gin.Context) {
-	var req registerReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.ValidationError(c, "invalid_request", err)
-		return
-	}
-	if err := h.auth.Register(req.Username, req.Email, req.Password); err != nil {
-		response.BadRequest(c, "registration_failed", err.Error())
-		return
-	}
-	c.JSON(http.StatusCreated, gin.H{"message": "registered"})
-}
-
-type loginReq struct {
-	Email    string `json:"email" binding:"required"`
-	Password string `json:"password" binding:"required"`
-}
-
-// Login godoc
-// @Summary      Login a user
-// @Description  Login a user with email and password
-// @Tags         Authentication
-// @Accept       json
-// @Produce      json
-// @Param        loginReq  body      loginReq  true  "Login Request"
-// @Success      200       {object}  map[string]string
-// @Failure      400       {object}  response.ErrorResponse
-// @Failure	  401       {object}  response.ErrorResponse
-// @Failure	  500       {object}  response.ErrorResponse
-// @Router       /auth/login [post]
-func (h *AuthController) Login(c *gin.Context) {
-	var req loginReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.ValidationError(c, "invalid_request", err)
-		return
-	}
-	token, _, err := h.auth.Login(req.Email, req.Password)
-	if err != nil {
-		response.Unauthorized(c, "login_failed", err.Error())
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{
-		"access_token": token,
-		"token_type":   "bearer",
-	})
-}
-
-// Logout godoc
-// @Summary      Logout a user
-// @Description  Logout a user by invalidating their token
-// @Tags         Authentication
-// @Accept       json
-// @Produce      json
-// @Success      200  {object}  map[string]string
-// @Failure      401  {object}  response.ErrorResponse
-// @Failure      500  {object}  response.ErrorResponse
-// @Router       /auth/logout [post]
-// @Security     BearerAuth
-func (h *AuthController) Logout(c *gin.Context) {
-	userIDv, exists := c.Get("user_id")
-	if !exists {
-		response.Unauthorized(c, "unauthorized", "missing user")
-		return
-	}
-	userID := userIDv.(uint)
-	if err := h.auth.Logout(userID); err != nil {
-		response.InternalError(c, "logout_failed", err.Error())
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
-}
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"github.com/spksupakorn/Currency-Converter/pkg/response"
+)
+
+type AuthController struct {
+	auth  services.AuthService
+	audit services.AuditService
+	log   *logger.Logger
+}
+
+func NewAuthController(auth services.AuthService, audit services.AuditService, log *logger.Logger) *AuthController {
+	return &AuthController{auth: auth, audit: audit, log: log}
+}
+
+type registerReq struct {
+	Username string `json:"username"`
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// Register godoc
+// @Summary      Register a new user
+// @Description  Register a new user with email and password
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        registerReq  body      registerReq  true  "Register Request"
+// @Success      201          {object}  map[string]string
+// @Failure      400          {object}  response.ErrorResponse
+// @Failure      500          {object}  response.ErrorResponse
+// @Router       /auth/register [post]
+func (h *AuthController) Register(c *gin.Context) {
+	var req registerReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, "invalid_request", err)
+		return
+	}
+	evt := newAuditEvent(c, models.AuditActionRegister)
+	evt.ActorEmail = normalizeEmail(req.Email)
+	if err := h.auth.Register(c.Request.Context(), req.Username, req.Email, req.Password); err != nil {
+		evt.Outcome = models.AuditOutcomeFailure
+		evt.Reason = err.Error()
+		h.audit.Record(c.Request.Context(), evt)
+		if timedOut(c, err) {
+			return
+		}
+		response.BadRequest(c, "registration_failed", err.Error())
+		return
+	}
+	h.audit.Record(c.Request.Context(), evt)
+	c.JSON(http.StatusCreated, gin.H{"message": "registered"})
+}
+
+type loginReq struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Login godoc
+// @Summary      Login a user
+// @Description  Login a user with email and password
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        loginReq  body      loginReq  true  "Login Request"
+// @Success      200       {object}  map[string]string
+// @Failure      400       {object}  response.ErrorResponse
+// @Failure	  401       {object}  response.ErrorResponse
+// @Failure	  500       {object}  response.ErrorResponse
+// @Router       /auth/login [post]
+func (h *AuthController) Login(c *gin.Context) {
+	var req loginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, "invalid_request", err)
+		return
+	}
+	evt := newAuditEvent(c, models.AuditActionLogin)
+	evt.ActorEmail = normalizeEmail(req.Email)
+	token, u, revoked, err := h.auth.Login(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		logger.FromContext(c.Request.Context(), h.log).Warn("login failed", logger.Fields{"error": err.Error()})
+		evt.Outcome = models.AuditOutcomeFailure
+		evt.Reason = err.Error()
+		h.audit.Record(c.Request.Context(), evt)
+		if timedOut(c, err) {
+			return
+		}
+		response.Unauthorized(c, "login_failed", err.Error())
+		return
+	}
+	evt.ActorID = &u.ID
+	h.audit.Record(c.Request.Context(), evt)
+	// A successful login revokes every previously issued token.
+	if revoked {
+		h.recordTokenRevoked(c, u.ID, u.Email, "new login")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"token_type":   "bearer",
+	})
+}
+
+// Logout godoc
+// @Summary      Logout a user
+// @Description  Logout a user by invalidating their token
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /auth/logout [post]
+// @Security     BearerAuth
+func (h *AuthController) Logout(c *gin.Context) {
+	userIDv, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "unauthorized", "missing user")
+		return
+	}
+	userID := userIDv.(uint)
+	evt := newAuditEvent(c, models.AuditActionLogout)
+	if err := h.auth.Logout(c.Request.Context(), userID); err != nil {
+		evt.Outcome = models.AuditOutcomeFailure
+		evt.Reason = err.Error()
+		h.audit.Record(c.Request.Context(), evt)
+		if timedOut(c, err) {
+			return
+		}
+		response.InternalError(c, "logout_failed", err.Error())
+		return
+	}
+	h.audit.Record(c.Request.Context(), evt)
+	h.recordTokenRevoked(c, userID, c.GetString("user_email"), "logout")
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+func (h *AuthController) recordTokenRevoked(c *gin.Context, userID uint, email, reason string) {
+	evt := newAuditEvent(c, models.AuditActionTokenRevoked)
+	evt.ActorID = &userID
+	evt.ActorEmail = email
+	evt.TargetType = "user"
+	evt.TargetID = strconv.FormatUint(uint64(userID), 10)
+	evt.Reason = reason
+	h.audit.Record(c.Request.Context(), evt)
+}
+
+func normalizeEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spksupakorn/Currency-Converter/pkg/response"
+)
+
+// timedOut writes a 504 and reports true when err, or the request context,
+// shows that the per-route deadline has passed.
+func timedOut(c *gin.Context, err error) bool {
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		return false
+	}
+	response.GatewayTimeout(c, "timeout", "request deadline exceeded")
+	return true
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spksupakorn/Currency-Converter/database"
+	"github.com/spksupakorn/Currency-Converter/internal/leader"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+
+	dbPingTimeout = 2 * time.Second
+)
+
+type HealthController struct {
+	db      database.Database
+	rates   services.RateService
+	elector *leader.Elector
+	log     *logger.Logger
+}
+
+// NewHealthController reports the leader election through elector, which is
+// nil when every instance leads.
+func NewHealthController(db database.Database, rates services.RateService, elector *leader.Elector, log *logger.Logger) *HealthController {
+	return &HealthController{db: db, rates: rates, elector: elector, log: log}
+}
+
+type checkResult struct {
+	Status    string                 `json:"status"`
+	LatencyMS int64                  `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+type healthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// Livez reports whether the process is alive. It never touches dependencies
+// so a slow database cannot get the pod restarted.
+func (h *HealthController) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, healthReport{Status: checkOK})
+}
+
+// Readyz reports whether the instance can serve traffic: the database must be
+// reachable and the rate cache populated. Stale rates are reported as a
+// warning but do not fail readiness, otherwise an upstream outage would take
+// every replica out of rotation at once.
+func (h *HealthController) Readyz(c *gin.Context) {
+	report := healthReport{
+		Status: checkOK,
+		Checks: map[string]checkResult{
+			"database": h.checkDatabase(c.Request.Context()),
+			"rates":    h.checkRates(),
+		},
+	}
+	if h.elector != nil {
+		report.Checks["leadership"] = h.checkLeadership(c.Request.Context())
+	}
+
+	status := http.StatusOK
+	for _, chk := range report.Checks {
+		if chk.Status == checkFail {
+			report.Status = checkFail
+			status = http.StatusServiceUnavailable
+			break
+		}
+	}
+	if status != http.StatusOK {
+		h.log.Error("readiness check failed", logger.Fields{"checks": report.Checks})
+	}
+	c.JSON(status, report)
+}
+
+func (h *HealthController) checkDatabase(ctx context.Context) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, dbPingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := h.db.Ping(ctx)
+	res := checkResult{Status: checkOK, LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		// The driver error names the host, and /readyz is not authenticated.
+		h.log.Error("database ping failed", logger.Fields{"error": err.Error()})
+		res.Status = checkFail
+		res.Error = "database unreachable"
+	}
+	return res
+}
+
+func (h *HealthController) checkRates() checkResult {
+	st := h.rates.Status()
+	res := checkResult{
+		Status: checkOK,
+		Details: map[string]interface{}{
+			"base":                     st.Base,
+			"provider":                 st.Provider,
+			"count":                    st.Count,
+			"refresh_interval_seconds": int64(st.RefreshInterval.Seconds()),
+			"schedule":                 st.Schedule,
+			"market_open":              st.MarketOpen,
+			"max_age_seconds":          int64(st.MaxAge.Seconds()),
+			"breaker":                  st.Breaker,
+			"consecutive_failures":     st.ConsecutiveFailures,
+			"overrides":                st.Overrides,
+		},
+	}
+	if !st.NextRefreshAt.IsZero() {
+		res.Details["next_refresh_at"] = st.NextRefreshAt
+	}
+	// The error itself is only logged: /readyz is not authenticated.
+	if st.ConsecutiveFailures > 0 {
+		res.Details["last_failure_at"] = st.LastFailureAt
+	}
+	if st.PendingQuarantine != 0 {
+		res.Details["pending_quarantine"] = st.PendingQuarantine
+	}
+	if st.Breaker == services.BreakerOpen {
+		res.Details["breaker_open_until"] = st.BreakerOpenUntil
+	}
+	if st.Count == 0 {
+		res.Status = checkFail
+		res.Error = "rates have not been loaded yet"
+		return res
+	}
+
+	age := time.Since(st.UpdatedAt)
+	res.Details["updated_at"] = st.UpdatedAt
+	res.Details["age_seconds"] = int64(age.Seconds())
+	// Stale rates do not fail readiness: every replica shares the upstream,
+	// so pulling them all out of rotation would not help.
+	if st.Stale {
+		res.Status = checkWarn
+		res.Error = "rates are stale"
+	}
+	if st.PendingQuarantine != 0 && res.Status == checkOK {
+		res.Status = checkWarn
+		res.Error = "fetched rates are quarantined pending approval"
+	}
+	return res
+}
+
+// checkLeadership never fails readiness: a follower serves the rates the
+// leader stores, and without a leader they only go stale.
+func (h *HealthController) checkLeadership(ctx context.Context) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, dbPingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	st := h.elector.Status(ctx)
+	res := checkResult{
+		Status:    checkOK,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Details: map[string]interface{}{
+			"instance_id": st.ID,
+			"leader":      st.Leader,
+			"holder":      st.Holder,
+		},
+	}
+	if !st.ExpiresAt.IsZero() {
+		res.Details["lease_expires_at"] = st.ExpiresAt
+	}
+	switch {
+	case st.LastError != "":
+		// Logged by the elector; database errors are not for /readyz.
+		res.Status = checkWarn
+		res.Error = "leader lease could not be acquired or renewed"
+	case st.Holder == "":
+		res.Status = checkWarn
+		res.Error = "no instance holds the leader lease"
+	}
+	return res
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+)
+
+// setCacheHeaders marks a response computed from the rate snapshot described
+// by info as cacheable until the next refresh, or until an override expires;
+// stale rates are not cacheable. The snapshot's fetch time is the version: it
+// is the same on every instance that loaded the same snapshot from the
+// database. A later change of overrides moves it forward. key distinguishes
+// representations of one snapshot, e.g. the base currency and filters. It
+// writes a 304 and reports true when the client's copy is still current,
+// which a copy is never once the rates went stale: the body says whether they
+// are.
+func setCacheHeaders(c *gin.Context, info services.RateInfo, key string) bool {
+	updatedAt := info.FetchedAt
+	if info.OverriddenAt.After(updatedAt) {
+		updatedAt = info.OverriddenAt
+	}
+	hk := fnv.New64a()
+	_, _ = hk.Write([]byte(key))
+	if info.Stale {
+		_, _ = hk.Write([]byte("|stale"))
+	}
+	etag := fmt.Sprintf(`W/"%x-%x"`, updatedAt.UnixNano(), hk.Sum64())
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+
+	maxAge := 0
+	if !info.Stale && !info.NextRefreshAt.IsZero() {
+		until := info.NextRefreshAt
+		if !info.OverridesExpireAt.IsZero() && info.OverridesExpireAt.Before(until) {
+			until = info.OverridesExpireAt
+		}
+		maxAge = max(int(time.Until(until).Seconds()), 0)
+	}
+	// Rates are only served to authenticated users, so shared caches must not
+	// store them.
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+
+	if info.Stale {
+		return false
+	}
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if !etagMatches(inm, etag) {
+			return false
+		}
+	} else if ims := c.GetHeader("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		if err != nil || updatedAt.Truncate(time.Second).After(t) {
+			return false
+		}
+	} else {
+		return false
+	}
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
+
+// etagMatches applies the weak comparison If-None-Match requires.
+func etagMatches(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
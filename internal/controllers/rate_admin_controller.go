@@ -0,0 +1,355 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"github.com/spksupakorn/Currency-Converter/pkg/response"
+)
+
+type RateAdminController struct {
+	rates services.RateService
+	audit services.AuditService
+	log   *logger.Logger
+}
+
+func NewRateAdminController(rates services.RateService, audit services.AuditService, log *logger.Logger) *RateAdminController {
+	return &RateAdminController{rates: rates, audit: audit, log: log}
+}
+
+type refreshResponse struct {
+	Base       string    `json:"base"`
+	Provider   string    `json:"provider"`
+	Count      int       `json:"count"`
+	FetchedAt  time.Time `json:"fetched_at"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// Refresh godoc
+// @Summary      Refresh rates now
+// @Description  Fetch rates from the provider immediately, bypassing the circuit breaker, and wait for the result. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Success      200  {object}  refreshResponse
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      403  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse  "The rates moved too far and were quarantined"
+// @Failure      502  {object}  response.ErrorResponse  "The provider call failed or returned invalid rates"
+// @Failure      504  {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/rates/refresh [post]
+func (h *RateAdminController) Refresh(c *gin.Context) {
+	evt := newAuditEvent(c, models.AuditActionRatesRefresh)
+	evt.TargetType = "rates"
+	res, err := h.rates.Refresh(c.Request.Context())
+	if err != nil {
+		evt.Outcome = models.AuditOutcomeFailure
+		evt.Reason = truncate(err.Error(), 255)
+		h.audit.Record(c.Request.Context(), evt)
+		if timedOut(c, err) {
+			return
+		}
+		logger.FromContext(c.Request.Context(), h.log).Warn("manual rate refresh failed", logger.Fields{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrRatesQuarantined):
+			response.Conflict(c, "rates_quarantined", err.Error())
+		case errors.Is(err, services.ErrRatesRejected):
+			response.BadGateway(c, "rates_rejected", err.Error())
+		default:
+			response.BadGateway(c, "refresh_failed", err.Error())
+		}
+		return
+	}
+	evt.TargetID = res.Base
+	h.audit.Record(c.Request.Context(), evt)
+	c.JSON(http.StatusOK, refreshResponse{
+		Base:       res.Base,
+		Provider:   res.Provider,
+		Count:      res.Count,
+		FetchedAt:  res.FetchedAt,
+		DurationMS: res.Duration.Milliseconds(),
+	})
+}
+
+type overrideReq struct {
+	Rate   float64 `json:"rate" binding:"required"`
+	Base   string  `json:"base"`
+	Reason string  `json:"reason" binding:"required"`
+	// Exactly one of ExpiresAt and TTL is required.
+	ExpiresAt *time.Time `json:"expires_at"`
+	TTL       string     `json:"ttl" example:"2h"`
+}
+
+type overrideListResponse struct {
+	Overrides []models.RateOverride `json:"overrides"`
+}
+
+// SetOverride godoc
+// @Summary      Pin a rate
+// @Description  Override the provider rate of a currency until it expires. The rate is the price of the currency in base (default the provider base) and replaces any override for the same currency. Give either expires_at or ttl. Admin only.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        currency     path      string       true  "Currency to override (3-letter code)"
+// @Param        overrideReq  body      overrideReq  true  "Override"
+// @Success      200          {object}  models.RateOverride
+// @Failure      400          {object}  response.ErrorResponse
+// @Failure      401          {object}  response.ErrorResponse
+// @Failure      403          {object}  response.ErrorResponse
+// @Failure      500          {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/rates/overrides/{currency} [put]
+func (h *RateAdminController) SetOverride(c *gin.Context) {
+	currency := strings.ToUpper(strings.TrimSpace(c.Param("currency")))
+	if !isCurrency(currency) {
+		response.BadRequest(c, "validation_error", "currency must be a 3-letter currency code")
+		return
+	}
+	var req overrideReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, "invalid_request", err)
+		return
+	}
+	if base := strings.ToUpper(strings.TrimSpace(req.Base)); base != "" && !isCurrency(base) {
+		response.BadRequest(c, "validation_error", "base must be a 3-letter currency code")
+		return
+	}
+	var expiresAt time.Time
+	switch {
+	case req.ExpiresAt != nil && req.TTL != "":
+		response.BadRequest(c, "validation_error", "give either expires_at or ttl, not both")
+		return
+	case req.ExpiresAt != nil:
+		expiresAt = *req.ExpiresAt
+	case req.TTL != "":
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil {
+			response.BadRequest(c, "validation_error", "ttl must be a duration such as 90m or 2h")
+			return
+		}
+		expiresAt = time.Now().Add(ttl)
+	default:
+		response.BadRequest(c, "validation_error", "expires_at or ttl is required")
+		return
+	}
+
+	o := models.RateOverride{
+		Currency:  currency,
+		Base:      req.Base,
+		Rate:      req.Rate,
+		Reason:    req.Reason,
+		ExpiresAt: expiresAt,
+	}
+	if id := c.GetUint("user_id"); id != 0 {
+		o.CreatedByID = &id
+		o.CreatedByEmail = c.GetString("user_email")
+	}
+
+	evt := newAuditEvent(c, models.AuditActionOverrideSet)
+	evt.TargetType = "currency"
+	evt.TargetID = currency
+	saved, err := h.rates.SetOverride(c.Request.Context(), o)
+	if err != nil {
+		evt.Outcome = models.AuditOutcomeFailure
+		evt.Reason = truncate(err.Error(), 255)
+		h.audit.Record(c.Request.Context(), evt)
+		if errors.Is(err, services.ErrInvalidOverride) {
+			response.BadRequest(c, "override_rejected", err.Error())
+			return
+		}
+		if timedOut(c, err) {
+			return
+		}
+		logger.FromContext(c.Request.Context(), h.log).Error("failed to set rate override", logger.Fields{"currency": currency, "error": err.Error()})
+		response.InternalError(c, "override_failed", "could not set the override")
+		return
+	}
+	evt.Reason = truncate(fmt.Sprintf("%g %s per %s until %s: %s",
+		saved.Rate, saved.Currency, saved.Base, saved.ExpiresAt.Format(time.RFC3339), saved.Reason), 255)
+	h.audit.Record(c.Request.Context(), evt)
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteOverride godoc
+// @Summary      Remove a pinned rate
+// @Description  Remove the override of a currency so the provider rate applies again. Admin only.
+// @Tags         Admin
+// @Param        currency  path  string  true  "Currency (3-letter code)"
+// @Success      204
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      403  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/rates/overrides/{currency} [delete]
+func (h *RateAdminController) DeleteOverride(c *gin.Context) {
+	currency := strings.ToUpper(strings.TrimSpace(c.Param("currency")))
+	evt := newAuditEvent(c, models.AuditActionOverrideDeleted)
+	evt.TargetType = "currency"
+	evt.TargetID = currency
+	if err := h.rates.DeleteOverride(c.Request.Context(), currency); err != nil {
+		if errors.Is(err, services.ErrOverrideNotFound) {
+			response.NotFound(c, "override_not_found", "no active override for "+currency)
+			return
+		}
+		evt.Outcome = models.AuditOutcomeFailure
+		evt.Reason = truncate(err.Error(), 255)
+		h.audit.Record(c.Request.Context(), evt)
+		if timedOut(c, err) {
+			return
+		}
+		logger.FromContext(c.Request.Context(), h.log).Error("failed to delete rate override", logger.Fields{"currency": currency, "error": err.Error()})
+		response.InternalError(c, "override_delete_failed", "could not delete the override")
+		return
+	}
+	h.audit.Record(c.Request.Context(), evt)
+	c.Status(http.StatusNoContent)
+}
+
+// ListOverrides godoc
+// @Summary      List pinned rates
+// @Description  List the overrides that have not expired yet, by currency. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Success      200  {object}  overrideListResponse
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      403  {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/rates/overrides [get]
+func (h *RateAdminController) ListOverrides(c *gin.Context) {
+	list, err := h.rates.ListOverrides(c.Request.Context())
+	if err != nil {
+		if timedOut(c, err) {
+			return
+		}
+		logger.FromContext(c.Request.Context(), h.log).Error("failed to list rate overrides", logger.Fields{"error": err.Error()})
+		response.InternalError(c, "overrides_unavailable", "could not list overrides")
+		return
+	}
+	if list == nil {
+		list = []models.RateOverride{}
+	}
+	c.JSON(http.StatusOK, overrideListResponse{Overrides: list})
+}
+
+type quarantineListResponse struct {
+	Quarantines []models.RateQuarantine `json:"quarantines"`
+}
+
+// ListQuarantines godoc
+// @Summary      List quarantined rates
+// @Description  List fetched rate tables held back because some rates moved more than RATE_MAX_CHANGE, newest first. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Param        status  query     string  false  "pending, approved, rejected or superseded; all when empty"
+// @Success      200     {object}  quarantineListResponse
+// @Failure      400     {object}  response.ErrorResponse
+// @Failure      401     {object}  response.ErrorResponse
+// @Failure      403     {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/rates/quarantine [get]
+func (h *RateAdminController) ListQuarantines(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.QuarantinePending, models.QuarantineApproved, models.QuarantineRejected, models.QuarantineSuperseded:
+	default:
+		response.BadRequest(c, "validation_error", "status must be pending, approved, rejected or superseded")
+		return
+	}
+	list, err := h.rates.ListQuarantines(c.Request.Context(), status)
+	if err != nil {
+		if timedOut(c, err) {
+			return
+		}
+		logger.FromContext(c.Request.Context(), h.log).Error("failed to list rate quarantines", logger.Fields{"error": err.Error()})
+		response.InternalError(c, "quarantines_unavailable", "could not list quarantined rates")
+		return
+	}
+	if list == nil {
+		list = []models.RateQuarantine{}
+	}
+	c.JSON(http.StatusOK, quarantineListResponse{Quarantines: list})
+}
+
+// ApproveQuarantine godoc
+// @Summary      Approve quarantined rates
+// @Description  Publish a pending quarantined rate table as if it had just been fetched. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Param        id   path      int  true  "Quarantine ID"
+// @Success      200  {object}  models.RateQuarantine
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      403  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse  "The quarantine is no longer pending"
+// @Security     BearerAuth
+// @Router       /admin/rates/quarantine/{id}/approve [post]
+func (h *RateAdminController) ApproveQuarantine(c *gin.Context) {
+	h.decideQuarantine(c, models.AuditActionQuarantineApproved, h.rates.ApproveQuarantine)
+}
+
+// RejectQuarantine godoc
+// @Summary      Reject quarantined rates
+// @Description  Discard a pending quarantined rate table; the current rates stay published. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Param        id   path      int  true  "Quarantine ID"
+// @Success      200  {object}  models.RateQuarantine
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      403  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse  "The quarantine is no longer pending"
+// @Security     BearerAuth
+// @Router       /admin/rates/quarantine/{id}/reject [post]
+func (h *RateAdminController) RejectQuarantine(c *gin.Context) {
+	h.decideQuarantine(c, models.AuditActionQuarantineRejected, h.rates.RejectQuarantine)
+}
+
+func (h *RateAdminController) decideQuarantine(c *gin.Context, action string,
+	decide func(ctx context.Context, id uint, byID *uint, byEmail string) (models.RateQuarantine, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		response.NotFound(c, "quarantine_not_found", "no such quarantine")
+		return
+	}
+	var byID *uint
+	if uid := c.GetUint("user_id"); uid != 0 {
+		byID = &uid
+	}
+	evt := newAuditEvent(c, action)
+	evt.TargetType = "rate_quarantine"
+	evt.TargetID = strconv.FormatUint(id, 10)
+	q, err := decide(c.Request.Context(), uint(id), byID, c.GetString("user_email"))
+	if err != nil {
+		if errors.Is(err, services.ErrQuarantineNotFound) {
+			response.NotFound(c, "quarantine_not_found", "no such quarantine")
+			return
+		}
+		evt.Outcome = models.AuditOutcomeFailure
+		evt.Reason = truncate(err.Error(), 255)
+		h.audit.Record(c.Request.Context(), evt)
+		if timedOut(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrQuarantineDecided) {
+			response.Conflict(c, "quarantine_decided", err.Error())
+			return
+		}
+		logger.FromContext(c.Request.Context(), h.log).Error("failed to decide rate quarantine", logger.Fields{"id": id, "error": err.Error()})
+		response.InternalError(c, "quarantine_failed", "could not apply the decision")
+		return
+	}
+	evt.Reason = truncate(fmt.Sprintf("%d anomalies in %s rates fetched at %s",
+		len(q.Anomalies), q.Base, q.FetchedAt.Format(time.RFC3339)), 255)
+	h.audit.Record(c.Request.Context(), evt)
+	c.JSON(http.StatusOK, q)
+}
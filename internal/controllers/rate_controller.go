@@ -1,11 +1,17 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spksupakorn/Currency-Converter/internal/currency"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
 	"github.com/spksupakorn/Currency-Converter/internal/services"
 	"github.com/spksupakorn/Currency-Converter/pkg/logger"
 	"github.com/spksupakorn/Currency-Converter/pkg/response"
@@ -13,22 +19,31 @@ import (
 
 type RateController struct {
 	rates services.RateService
+	usage services.UsageService
 	log   *logger.Logger
 }
 
-func NewRateController(rates services.RateService, log *logger.Logger) *RateController {
-	return &RateController{rates: rates, log: log}
+func NewRateController(rates services.RateService, usage services.UsageService, log *logger.Logger) *RateController {
+	return &RateController{rates: rates, usage: usage, log: log}
 }
 
 // GetRates godoc
 // @Summary      Get Exchange Rates
-// @Description  Get all exchange rates relative to a specified base currency. If no base is provided, defaults to USD.
+// @Description  Get exchange rates relative to a specified base currency. If no base is provided, defaults to USD. Narrow the result with symbols, exclude, region and type; requested symbols without a rate are listed in missing.
 // @Tags         Rates
 // @Accept       json
 // @Produce      json
-// @Param        base   query     string  false  "Base currency (3-letter code, e.g., USD)"
-// @Success      200    {object}  map[string]interface{}
-// @Failure      400    {object}  response.ErrorResponse
+// @Param        base           query     string  false  "Base currency (3-letter code, e.g., USD)"
+// @Param        symbols        query     string  false  "Only these currencies, comma separated (e.g., EUR,JPY,THB)"
+// @Param        exclude        query     string  false  "Currencies to leave out, comma separated"
+// @Param        region         query     string  false  "Only currencies of this region"  Enums(africa, americas, asia, europe, oceania)
+// @Param        type           query     string  false  "Only currencies of this type"    Enums(fiat, crypto, metal)
+// @Param        If-None-Match  header    string  false  "ETag of a previous response"
+// @Success      200            {object}  map[string]interface{}
+// @Success      304            "Rates unchanged since the ETag or If-Modified-Since"
+// @Failure      400            {object}  response.ErrorResponse
+// @Failure      503            {object}  response.ErrorResponse  "Rates are older than RATE_MAX_AGE and RATE_STALE_POLICY is refuse"
+// @Failure      504            {object}  response.ErrorResponse
 // @Security     BearerAuth
 // @Router       /rates [get]
 func (h *RateController) GetRates(c *gin.Context) {
@@ -37,16 +52,33 @@ func (h *RateController) GetRates(c *gin.Context) {
 		response.BadRequest(c, "validation_error", "base must be a 3-letter currency code")
 		return
 	}
-	baseOut, rates, updatedAt, err := h.rates.GetRates(base)
+	filter, ok := bindRateFilter(c)
+	if !ok {
+		return
+	}
+	rates, info, err := h.rates.GetRates(c.Request.Context(), base)
 	if err != nil {
+		if timedOut(c, err) || refusedStale(c, info, err) {
+			return
+		}
 		response.BadRequest(c, "rates_unavailable", err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"base":       baseOut,
-		"rates":      rates,
-		"updated_at": updatedAt,
-	})
+	if setCacheHeaders(c, info, info.Base+"|"+filter.Key()) {
+		return
+	}
+	rates, missing := services.FilterRates(rates, filter)
+	body := withFreshness(gin.H{
+		"base":  info.Base,
+		"rates": rates,
+	}, info)
+	if len(filter.Symbols) > 0 {
+		if missing == nil {
+			missing = []string{}
+		}
+		body["missing"] = missing
+	}
+	c.JSON(http.StatusOK, body)
 }
 
 // ConvertCurrency godoc
@@ -55,11 +87,15 @@ func (h *RateController) GetRates(c *gin.Context) {
 // @Tags         Rates
 // @Accept       json
 // @Produce      json
-// @Param        from    query     string  true  "Source currency (3-letter code, e.g., USD)"
-// @Param        to      query     string  true  "Target currency (3-letter code, e.g., THB)"
-// @Param        amount  query     number  true  "Amount to convert (non-negative)"
-// @Success      200     {object}  map[string]interface{}
-// @Failure      400     {object}  response.ErrorResponse
+// @Param        from           query     string  true   "Source currency (3-letter code, e.g., USD)"
+// @Param        to             query     string  true   "Target currency (3-letter code, e.g., THB)"
+// @Param        amount         query     number  true   "Amount to convert (non-negative)"
+// @Param        If-None-Match  header    string  false  "ETag of a previous response"
+// @Success      200            {object}  map[string]interface{}
+// @Success      304            "Rates unchanged since the ETag or If-Modified-Since"
+// @Failure      400            {object}  response.ErrorResponse
+// @Failure      503            {object}  response.ErrorResponse  "Rates are older than RATE_MAX_AGE and RATE_STALE_POLICY is refuse"
+// @Failure      504            {object}  response.ErrorResponse
 // @Security     BearerAuth
 // @Router       /convert [get]
 func (h *RateController) ConvertCurrency(c *gin.Context) {
@@ -81,19 +117,123 @@ func (h *RateController) ConvertCurrency(c *gin.Context) {
 		return
 	}
 
-	rate, result, updatedAt, err := h.rates.Convert(from, to, amount)
+	log := logger.FromContext(c.Request.Context(), h.log)
+	rate, result, info, err := h.rates.Convert(c.Request.Context(), from, to, amount)
 	if err != nil {
+		if timedOut(c, err) || refusedStale(c, info, err) {
+			return
+		}
+		log.Warn("conversion failed", logger.Fields{"from": from, "to": to, "error": err.Error()})
 		response.BadRequest(c, "conversion_failed", err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"from":       from,
-		"to":         to,
-		"amount":     amount,
-		"rate":       rate,
-		"result":     result,
-		"updated_at": updatedAt,
+	log.Debug("currency converted", logger.Fields{"from": from, "to": to, "amount": amount, "rate": rate})
+	notModified := setCacheHeaders(c, info, from+"-"+to+"-"+amountS)
+	h.usage.Record(models.UsageRecord{
+		UserID:       c.GetUint("user_id"),
+		FromCurrency: from,
+		ToCurrency:   to,
+		Amount:       amount,
+		Rate:         rate,
+		SnapshotAt:   info.FetchedAt,
 	})
+	if notModified {
+		return
+	}
+	c.JSON(http.StatusOK, withFreshness(gin.H{
+		"from":   from,
+		"to":     to,
+		"amount": amount,
+		"rate":   rate,
+		"result": result,
+	}, info))
+}
+
+// withFreshness adds the snapshot's provenance and age to a rate response.
+// updated_at predates fetched_at and is kept for existing clients.
+func withFreshness(body gin.H, info services.RateInfo) gin.H {
+	body["updated_at"] = info.FetchedAt
+	body["fetched_at"] = info.FetchedAt
+	body["age_seconds"] = int64(info.Age.Seconds())
+	body["provider"] = info.Provider
+	body["stale"] = info.Stale
+	if len(info.Overrides) > 0 {
+		body["overrides"] = info.Overrides
+	}
+	if info.NextRefreshAt.IsZero() {
+		body["next_refresh_at"] = nil
+	} else {
+		body["next_refresh_at"] = info.NextRefreshAt
+	}
+	return body
+}
+
+func refusedStale(c *gin.Context, info services.RateInfo, err error) bool {
+	if !errors.Is(err, services.ErrRatesStale) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfterSeconds(info.NextRefreshAt)))
+	response.ServiceUnavailable(c, "rates_stale",
+		fmt.Sprintf("rates from %s are %ds old, older than the allowed maximum", info.Provider, int64(info.Age.Seconds())))
+	return true
+}
+
+func retryAfterSeconds(next time.Time) int {
+	s := int(time.Until(next).Seconds())
+	if s < 1 {
+		return 1
+	}
+	return s
+}
+
+const maxRateSymbols = 200
+
+func bindRateFilter(c *gin.Context) (services.RateFilter, bool) {
+	var f services.RateFilter
+	var ok bool
+	if f.Symbols, ok = currencyList(c, "symbols"); !ok {
+		return f, false
+	}
+	if f.Exclude, ok = currencyList(c, "exclude"); !ok {
+		return f, false
+	}
+	f.Region = strings.ToLower(strings.TrimSpace(c.Query("region")))
+	if f.Region != "" && !currency.IsRegion(f.Region) {
+		response.BadRequest(c, "validation_error", "region must be one of "+strings.Join(currency.Regions, ", "))
+		return f, false
+	}
+	f.Type = strings.ToLower(strings.TrimSpace(c.Query("type")))
+	if f.Type != "" && !currency.IsType(f.Type) {
+		response.BadRequest(c, "validation_error", "type must be one of "+strings.Join(currency.Types, ", "))
+		return f, false
+	}
+	return f, true
+}
+
+// currencyList parses a comma separated list of currency codes, dropping
+// duplicates and keeping the order given.
+func currencyList(c *gin.Context, param string) ([]string, bool) {
+	raw := c.Query(param)
+	if raw == "" {
+		return nil, true
+	}
+	var out []string
+	for _, s := range strings.Split(raw, ",") {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s == "" || slices.Contains(out, s) {
+			continue
+		}
+		if !isCurrency(s) {
+			response.BadRequest(c, "validation_error", param+" must be 3-letter currency codes, got "+s)
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	if len(out) > maxRateSymbols {
+		response.BadRequest(c, "validation_error", fmt.Sprintf("%s accepts at most %d currencies", param, maxRateSymbols))
+		return nil, false
+	}
+	return out, true
 }
 
 func isCurrency(s string) bool {
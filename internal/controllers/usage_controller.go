@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"github.com/spksupakorn/Currency-Converter/pkg/response"
+)
+
+type UsageController struct {
+	usage services.UsageService
+	log   *logger.Logger
+}
+
+func NewUsageController(usage services.UsageService, log *logger.Logger) *UsageController {
+	return &UsageController{usage: usage, log: log}
+}
+
+type usageResponse struct {
+	GroupBy []string              `json:"group_by"`
+	Usage   []models.UsageSummary `json:"usage"`
+}
+
+// MyUsage godoc
+// @Summary      Get my conversion usage
+// @Description  Aggregate the current user's conversions by day and/or currency pair. total_amount, in the source currency, is only reported when grouped by pair.
+// @Tags         Account
+// @Produce      json
+// @Param        group_by  query     string  false  "Comma-separated grouping: day, pair (default day,pair)"
+// @Param        from      query     string  false  "Only usage at or after this RFC3339 time"
+// @Param        to        query     string  false  "Only usage before this RFC3339 time"
+// @Success      200       {object}  usageResponse
+// @Failure      400       {object}  response.ErrorResponse
+// @Failure      401       {object}  response.ErrorResponse
+// @Failure      500       {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /me/usage [get]
+func (h *UsageController) MyUsage(c *gin.Context) {
+	f, ok := bindUsageFilter(c, "day,pair")
+	if !ok {
+		return
+	}
+	if slices.Contains(f.GroupBy, repositories.UsageGroupUser) {
+		response.BadRequest(c, "validation_error", "group_by user is not available here")
+		return
+	}
+	userID := c.GetUint("user_id")
+	f.UserID = &userID
+	h.summarize(c, f)
+}
+
+// Report godoc
+// @Summary      Usage report
+// @Description  Aggregate conversions across all users by day, pair and/or user. total_amount, in the source currency, is only reported when grouped by pair. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Param        group_by  query     string  false  "Comma-separated grouping: day, pair, user (default user)"
+// @Param        user_id   query     int     false  "Only usage of this user"
+// @Param        from      query     string  false  "Only usage at or after this RFC3339 time"
+// @Param        to        query     string  false  "Only usage before this RFC3339 time"
+// @Success      200       {object}  usageResponse
+// @Failure      400       {object}  response.ErrorResponse
+// @Failure      401       {object}  response.ErrorResponse
+// @Failure      403       {object}  response.ErrorResponse
+// @Failure      500       {object}  response.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/usage [get]
+func (h *UsageController) Report(c *gin.Context) {
+	f, ok := bindUsageFilter(c, "user")
+	if !ok {
+		return
+	}
+	if s := c.Query("user_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			response.BadRequest(c, "validation_error", "user_id must be a positive integer")
+			return
+		}
+		userID := uint(id)
+		f.UserID = &userID
+	}
+	h.summarize(c, f)
+}
+
+func (h *UsageController) summarize(c *gin.Context, f repositories.UsageFilter) {
+	rows, err := h.usage.Summarize(c.Request.Context(), f)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidQuery) {
+			response.BadRequest(c, "invalid_query", err.Error())
+			return
+		}
+		if timedOut(c, err) {
+			return
+		}
+		logger.FromContext(c.Request.Context(), h.log).Error("failed to summarize usage", logger.Fields{"error": err.Error()})
+		response.InternalError(c, "usage_unavailable", "could not summarize usage")
+		return
+	}
+	if rows == nil {
+		rows = []models.UsageSummary{}
+	}
+	c.JSON(http.StatusOK, usageResponse{GroupBy: f.GroupBy, Usage: rows})
+}
+
+func bindUsageFilter(c *gin.Context, defGroupBy string) (repositories.UsageFilter, bool) {
+	var f repositories.UsageFilter
+	groupBy := c.DefaultQuery("group_by", defGroupBy)
+	for _, g := range strings.Split(groupBy, ",") {
+		g = strings.ToLower(strings.TrimSpace(g))
+		if g != "" && !slices.Contains(f.GroupBy, g) {
+			f.GroupBy = append(f.GroupBy, g)
+		}
+	}
+
+	var err error
+	if f.From, err = queryTime(c, "from"); err != nil {
+		response.BadRequest(c, "validation_error", "from must be an RFC3339 timestamp")
+		return f, false
+	}
+	if f.To, err = queryTime(c, "to"); err != nil {
+		response.BadRequest(c, "validation_error", "to must be an RFC3339 timestamp")
+		return f, false
+	}
+	return f, true
+}
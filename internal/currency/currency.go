@@ -0,0 +1,54 @@
+// Package currency holds static metadata about the currencies the service
+// quotes, used to filter and group rates.
+package currency
+
+import "slices"
+
+const (
+	TypeFiat   = "fiat"
+	TypeCrypto = "crypto"
+	TypeMetal  = "metal"
+)
+
+const (
+	RegionAfrica   = "africa"
+	RegionAmericas = "americas"
+	RegionAsia     = "asia"
+	RegionEurope   = "europe"
+	RegionOceania  = "oceania"
+)
+
+var (
+	Types   = []string{TypeFiat, TypeCrypto, TypeMetal}
+	Regions = []string{RegionAfrica, RegionAmericas, RegionAsia, RegionEurope, RegionOceania}
+)
+
+type Currency struct {
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Region string `json:"region,omitempty"`
+}
+
+var byCode = func() map[string]Currency {
+	m := make(map[string]Currency, len(table))
+	for _, c := range table {
+		m[c.Code] = c
+	}
+	return m
+}()
+
+// Lookup returns the metadata for code. Currencies the provider adds before
+// this table is updated are not found.
+func Lookup(code string) (Currency, bool) {
+	c, ok := byCode[code]
+	return c, ok
+}
+
+func IsType(s string) bool {
+	return slices.Contains(Types, s)
+}
+
+func IsRegion(s string) bool {
+	return slices.Contains(Regions, s)
+}
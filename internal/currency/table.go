@@ -0,0 +1,175 @@
+package currency
+
+// table lists the currencies the upstream provider quotes, plus common
+// metals and crypto assets. Region is empty for assets without a home region.
+var table = []Currency{
+	{Code: "AED", Name: "United Arab Emirates Dirham", Type: TypeFiat, Region: RegionAsia},
+	{Code: "AFN", Name: "Afghan Afghani", Type: TypeFiat, Region: RegionAsia},
+	{Code: "ALL", Name: "Albanian Lek", Type: TypeFiat, Region: RegionEurope},
+	{Code: "AMD", Name: "Armenian Dram", Type: TypeFiat, Region: RegionAsia},
+	{Code: "ANG", Name: "Netherlands Antillean Guilder", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "AOA", Name: "Angolan Kwanza", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "ARS", Name: "Argentine Peso", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "AUD", Name: "Australian Dollar", Type: TypeFiat, Region: RegionOceania},
+	{Code: "AWG", Name: "Aruban Florin", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "AZN", Name: "Azerbaijani Manat", Type: TypeFiat, Region: RegionAsia},
+	{Code: "BAM", Name: "Bosnia-Herzegovina Convertible Mark", Type: TypeFiat, Region: RegionEurope},
+	{Code: "BBD", Name: "Barbadian Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "BDT", Name: "Bangladeshi Taka", Type: TypeFiat, Region: RegionAsia},
+	{Code: "BGN", Name: "Bulgarian Lev", Type: TypeFiat, Region: RegionEurope},
+	{Code: "BHD", Name: "Bahraini Dinar", Type: TypeFiat, Region: RegionAsia},
+	{Code: "BIF", Name: "Burundian Franc", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "BMD", Name: "Bermudian Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "BND", Name: "Brunei Dollar", Type: TypeFiat, Region: RegionAsia},
+	{Code: "BOB", Name: "Bolivian Boliviano", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "BRL", Name: "Brazilian Real", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "BSD", Name: "Bahamian Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "BTC", Name: "Bitcoin", Type: TypeCrypto, Region: ""},
+	{Code: "BTN", Name: "Bhutanese Ngultrum", Type: TypeFiat, Region: RegionAsia},
+	{Code: "BWP", Name: "Botswana Pula", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "BYN", Name: "Belarusian Ruble", Type: TypeFiat, Region: RegionEurope},
+	{Code: "BZD", Name: "Belize Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "CAD", Name: "Canadian Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "CDF", Name: "Congolese Franc", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "CHF", Name: "Swiss Franc", Type: TypeFiat, Region: RegionEurope},
+	{Code: "CLP", Name: "Chilean Peso", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "CNY", Name: "Chinese Yuan", Type: TypeFiat, Region: RegionAsia},
+	{Code: "COP", Name: "Colombian Peso", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "CRC", Name: "Costa Rican Colon", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "CUP", Name: "Cuban Peso", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "CVE", Name: "Cape Verdean Escudo", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "CZK", Name: "Czech Koruna", Type: TypeFiat, Region: RegionEurope},
+	{Code: "DJF", Name: "Djiboutian Franc", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "DKK", Name: "Danish Krone", Type: TypeFiat, Region: RegionEurope},
+	{Code: "DOP", Name: "Dominican Peso", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "DZD", Name: "Algerian Dinar", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "EGP", Name: "Egyptian Pound", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "ERN", Name: "Eritrean Nakfa", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "ETB", Name: "Ethiopian Birr", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "ETH", Name: "Ether", Type: TypeCrypto, Region: ""},
+	{Code: "EUR", Name: "Euro", Type: TypeFiat, Region: RegionEurope},
+	{Code: "FJD", Name: "Fijian Dollar", Type: TypeFiat, Region: RegionOceania},
+	{Code: "FKP", Name: "Falkland Islands Pound", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "FOK", Name: "Faroese Krona", Type: TypeFiat, Region: RegionEurope},
+	{Code: "GBP", Name: "British Pound", Type: TypeFiat, Region: RegionEurope},
+	{Code: "GEL", Name: "Georgian Lari", Type: TypeFiat, Region: RegionAsia},
+	{Code: "GGP", Name: "Guernsey Pound", Type: TypeFiat, Region: RegionEurope},
+	{Code: "GHS", Name: "Ghanaian Cedi", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "GIP", Name: "Gibraltar Pound", Type: TypeFiat, Region: RegionEurope},
+	{Code: "GMD", Name: "Gambian Dalasi", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "GNF", Name: "Guinean Franc", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "GTQ", Name: "Guatemalan Quetzal", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "GYD", Name: "Guyanese Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "HKD", Name: "Hong Kong Dollar", Type: TypeFiat, Region: RegionAsia},
+	{Code: "HNL", Name: "Honduran Lempira", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "HRK", Name: "Croatian Kuna", Type: TypeFiat, Region: RegionEurope},
+	{Code: "HTG", Name: "Haitian Gourde", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "HUF", Name: "Hungarian Forint", Type: TypeFiat, Region: RegionEurope},
+	{Code: "IDR", Name: "Indonesian Rupiah", Type: TypeFiat, Region: RegionAsia},
+	{Code: "ILS", Name: "Israeli New Shekel", Type: TypeFiat, Region: RegionAsia},
+	{Code: "IMP", Name: "Manx Pound", Type: TypeFiat, Region: RegionEurope},
+	{Code: "INR", Name: "Indian Rupee", Type: TypeFiat, Region: RegionAsia},
+	{Code: "IQD", Name: "Iraqi Dinar", Type: TypeFiat, Region: RegionAsia},
+	{Code: "IRR", Name: "Iranian Rial", Type: TypeFiat, Region: RegionAsia},
+	{Code: "ISK", Name: "Icelandic Krona", Type: TypeFiat, Region: RegionEurope},
+	{Code: "JEP", Name: "Jersey Pound", Type: TypeFiat, Region: RegionEurope},
+	{Code: "JMD", Name: "Jamaican Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "JOD", Name: "Jordanian Dinar", Type: TypeFiat, Region: RegionAsia},
+	{Code: "JPY", Name: "Japanese Yen", Type: TypeFiat, Region: RegionAsia},
+	{Code: "KES", Name: "Kenyan Shilling", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "KGS", Name: "Kyrgyzstani Som", Type: TypeFiat, Region: RegionAsia},
+	{Code: "KHR", Name: "Cambodian Riel", Type: TypeFiat, Region: RegionAsia},
+	{Code: "KID", Name: "Kiribati Dollar", Type: TypeFiat, Region: RegionOceania},
+	{Code: "KMF", Name: "Comorian Franc", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "KRW", Name: "South Korean Won", Type: TypeFiat, Region: RegionAsia},
+	{Code: "KWD", Name: "Kuwaiti Dinar", Type: TypeFiat, Region: RegionAsia},
+	{Code: "KYD", Name: "Cayman Islands Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "KZT", Name: "Kazakhstani Tenge", Type: TypeFiat, Region: RegionAsia},
+	{Code: "LAK", Name: "Lao Kip", Type: TypeFiat, Region: RegionAsia},
+	{Code: "LBP", Name: "Lebanese Pound", Type: TypeFiat, Region: RegionAsia},
+	{Code: "LKR", Name: "Sri Lankan Rupee", Type: TypeFiat, Region: RegionAsia},
+	{Code: "LRD", Name: "Liberian Dollar", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "LSL", Name: "Lesotho Loti", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "LYD", Name: "Libyan Dinar", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "MAD", Name: "Moroccan Dirham", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "MDL", Name: "Moldovan Leu", Type: TypeFiat, Region: RegionEurope},
+	{Code: "MGA", Name: "Malagasy Ariary", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "MKD", Name: "Macedonian Denar", Type: TypeFiat, Region: RegionEurope},
+	{Code: "MMK", Name: "Myanmar Kyat", Type: TypeFiat, Region: RegionAsia},
+	{Code: "MNT", Name: "Mongolian Tugrik", Type: TypeFiat, Region: RegionAsia},
+	{Code: "MOP", Name: "Macanese Pataca", Type: TypeFiat, Region: RegionAsia},
+	{Code: "MRU", Name: "Mauritanian Ouguiya", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "MUR", Name: "Mauritian Rupee", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "MVR", Name: "Maldivian Rufiyaa", Type: TypeFiat, Region: RegionAsia},
+	{Code: "MWK", Name: "Malawian Kwacha", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "MXN", Name: "Mexican Peso", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "MYR", Name: "Malaysian Ringgit", Type: TypeFiat, Region: RegionAsia},
+	{Code: "MZN", Name: "Mozambican Metical", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "NAD", Name: "Namibian Dollar", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "NGN", Name: "Nigerian Naira", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "NIO", Name: "Nicaraguan Cordoba", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "NOK", Name: "Norwegian Krone", Type: TypeFiat, Region: RegionEurope},
+	{Code: "NPR", Name: "Nepalese Rupee", Type: TypeFiat, Region: RegionAsia},
+	{Code: "NZD", Name: "New Zealand Dollar", Type: TypeFiat, Region: RegionOceania},
+	{Code: "OMR", Name: "Omani Rial", Type: TypeFiat, Region: RegionAsia},
+	{Code: "PAB", Name: "Panamanian Balboa", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "PEN", Name: "Peruvian Sol", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "PGK", Name: "Papua New Guinean Kina", Type: TypeFiat, Region: RegionOceania},
+	{Code: "PHP", Name: "Philippine Peso", Type: TypeFiat, Region: RegionAsia},
+	{Code: "PKR", Name: "Pakistani Rupee", Type: TypeFiat, Region: RegionAsia},
+	{Code: "PLN", Name: "Polish Zloty", Type: TypeFiat, Region: RegionEurope},
+	{Code: "PYG", Name: "Paraguayan Guarani", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "QAR", Name: "Qatari Riyal", Type: TypeFiat, Region: RegionAsia},
+	{Code: "RON", Name: "Romanian Leu", Type: TypeFiat, Region: RegionEurope},
+	{Code: "RSD", Name: "Serbian Dinar", Type: TypeFiat, Region: RegionEurope},
+	{Code: "RUB", Name: "Russian Ruble", Type: TypeFiat, Region: RegionEurope},
+	{Code: "RWF", Name: "Rwandan Franc", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "SAR", Name: "Saudi Riyal", Type: TypeFiat, Region: RegionAsia},
+	{Code: "SBD", Name: "Solomon Islands Dollar", Type: TypeFiat, Region: RegionOceania},
+	{Code: "SCR", Name: "Seychellois Rupee", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "SDG", Name: "Sudanese Pound", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "SEK", Name: "Swedish Krona", Type: TypeFiat, Region: RegionEurope},
+	{Code: "SGD", Name: "Singapore Dollar", Type: TypeFiat, Region: RegionAsia},
+	{Code: "SHP", Name: "Saint Helena Pound", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "SLE", Name: "Sierra Leonean Leone", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "SLL", Name: "Sierra Leonean Leone (old)", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "SOS", Name: "Somali Shilling", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "SRD", Name: "Surinamese Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "SSP", Name: "South Sudanese Pound", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "STN", Name: "Sao Tome and Principe Dobra", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "SYP", Name: "Syrian Pound", Type: TypeFiat, Region: RegionAsia},
+	{Code: "SZL", Name: "Eswatini Lilangeni", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "THB", Name: "Thai Baht", Type: TypeFiat, Region: RegionAsia},
+	{Code: "TJS", Name: "Tajikistani Somoni", Type: TypeFiat, Region: RegionAsia},
+	{Code: "TMT", Name: "Turkmenistani Manat", Type: TypeFiat, Region: RegionAsia},
+	{Code: "TND", Name: "Tunisian Dinar", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "TOP", Name: "Tongan Paanga", Type: TypeFiat, Region: RegionOceania},
+	{Code: "TRY", Name: "Turkish Lira", Type: TypeFiat, Region: RegionAsia},
+	{Code: "TTD", Name: "Trinidad and Tobago Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "TVD", Name: "Tuvaluan Dollar", Type: TypeFiat, Region: RegionOceania},
+	{Code: "TWD", Name: "New Taiwan Dollar", Type: TypeFiat, Region: RegionAsia},
+	{Code: "TZS", Name: "Tanzanian Shilling", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "UAH", Name: "Ukrainian Hryvnia", Type: TypeFiat, Region: RegionEurope},
+	{Code: "UGX", Name: "Ugandan Shilling", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "USD", Name: "United States Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "UYU", Name: "Uruguayan Peso", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "UZS", Name: "Uzbekistani Som", Type: TypeFiat, Region: RegionAsia},
+	{Code: "VES", Name: "Venezuelan Bolivar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "VND", Name: "Vietnamese Dong", Type: TypeFiat, Region: RegionAsia},
+	{Code: "VUV", Name: "Vanuatu Vatu", Type: TypeFiat, Region: RegionOceania},
+	{Code: "WST", Name: "Samoan Tala", Type: TypeFiat, Region: RegionOceania},
+	{Code: "XAF", Name: "Central African CFA Franc", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "XAG", Name: "Silver (troy ounce)", Type: TypeMetal, Region: ""},
+	{Code: "XAU", Name: "Gold (troy ounce)", Type: TypeMetal, Region: ""},
+	{Code: "XCD", Name: "East Caribbean Dollar", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "XCG", Name: "Caribbean Guilder", Type: TypeFiat, Region: RegionAmericas},
+	{Code: "XDR", Name: "IMF Special Drawing Rights", Type: TypeFiat, Region: ""},
+	{Code: "XOF", Name: "West African CFA Franc", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "XPD", Name: "Palladium (troy ounce)", Type: TypeMetal, Region: ""},
+	{Code: "XPF", Name: "CFP Franc", Type: TypeFiat, Region: RegionOceania},
+	{Code: "XPT", Name: "Platinum (troy ounce)", Type: TypeMetal, Region: ""},
+	{Code: "YER", Name: "Yemeni Rial", Type: TypeFiat, Region: RegionAsia},
+	{Code: "ZAR", Name: "South African Rand", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "ZMW", Name: "Zambian Kwacha", Type: TypeFiat, Region: RegionAfrica},
+	{Code: "ZWL", Name: "Zimbabwean Dollar", Type: TypeFiat, Region: RegionAfrica},
+}
@@ -0,0 +1,86 @@
+// Package events publishes domain events to downstream consumers through a
+// transactional outbox: publishers write events next to the change they
+// describe, and a Relay delivers them to a Sink at least once.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+)
+
+// TypeRatesPublished is emitted for every rate table published.
+const TypeRatesPublished = "rates.published"
+
+// Source identifies this service in every event.
+const Source = "currency-converter"
+
+// Event is the envelope sent to sinks. ID is unique per event, so consumers
+// can drop the duplicates at-least-once delivery may produce; Key groups
+// events that must stay in order, e.g. a Kafka message key.
+type Event struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Source string          `json:"source"`
+	Time   time.Time       `json:"time"`
+	Key    string          `json:"key,omitempty"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// New builds an event of typ with data marshalled as its payload.
+func New(typ, key string, data interface{}) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:     uuid.NewString(),
+		Type:   typ,
+		Source: Source,
+		Time:   time.Now().UTC(),
+		Key:    key,
+		Data:   raw,
+	}, nil
+}
+
+// Publisher records events for delivery. Publish joins the transaction of
+// ctx, so an event is only delivered if the change it describes commits.
+type Publisher interface {
+	Publish(ctx context.Context, evt Event) error
+}
+
+// Discard is a Publisher that drops every event, for when no sink is
+// configured.
+var Discard Publisher = discard{}
+
+type discard struct{}
+
+func (discard) Publish(context.Context, Event) error { return nil }
+
+type outboxPublisher struct {
+	repo repositories.OutboxRepository
+}
+
+// NewOutboxPublisher returns a Publisher that writes events to the outbox
+// table, for a Relay to deliver.
+func NewOutboxPublisher(repo repositories.OutboxRepository) Publisher {
+	return &outboxPublisher{repo: repo}
+}
+
+func (p *outboxPublisher) Publish(ctx context.Context, evt Event) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	return p.repo.Add(ctx, &models.OutboxEvent{
+		EventID:   evt.ID,
+		Type:      evt.Type,
+		Key:       evt.Key,
+		Payload:   string(payload),
+		CreatedAt: evt.Time,
+	})
+}
@@ -0,0 +1,295 @@
+package events_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/events"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories/memory"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// flakySink fails its first failures sends and records the rest.
+type flakySink struct {
+	mu       sync.Mutex
+	failures int
+	sent     []events.Event
+}
+
+func (s *flakySink) Send(_ context.Context, evt events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	s.sent = append(s.sent, evt)
+	return nil
+}
+
+func (s *flakySink) Close() error { return nil }
+
+func (s *flakySink) ids() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, len(s.sent))
+	for i, evt := range s.sent {
+		out[i] = evt.ID
+	}
+	return out
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func publish(t *testing.T, p events.Publisher, n int) []string {
+	t.Helper()
+	var ids []string
+	for i := 0; i < n; i++ {
+		evt, err := events.New(events.TypeRatesPublished, "USD", map[string]int{"n": i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Publish(context.Background(), evt); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+		ids = append(ids, evt.ID)
+	}
+	return ids
+}
+
+func TestRelayDeliversInOrderAfterFailures(t *testing.T) {
+	repo := memory.NewOutboxRepository()
+	want := publish(t, events.NewOutboxPublisher(repo), 3)
+	sink := &flakySink{failures: 2}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go events.NewRelay(repo, sink, time.Millisecond, time.Hour, logger.New(logger.Options{Level: "error"})).Run(ctx)
+
+	waitFor(t, "delivery", func() bool { return len(sink.ids()) == 3 })
+	if got := sink.ids(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+	waitFor(t, "outbox drained", func() bool {
+		pending, _ := repo.Pending(ctx, 10)
+		return len(pending) == 0
+	})
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := events.NewSink("file:" + path)
+	if err != nil {
+		t.Fatalf("new sink: %v", err)
+	}
+	evt, _ := events.New(events.TypeRatesPublished, "USD", map[string]string{"base": "USD"})
+	for i := 0; i < 2; i++ {
+		if err := sink.Send(context.Background(), evt); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	var got events.Event
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[0]), &got) != nil || got.ID != evt.ID || got.Source != events.Source {
+		t.Errorf("file contents = %q", raw)
+	}
+}
+
+func TestNewSinkRejectsUnknownScheme(t *testing.T) {
+	if _, err := events.NewSink("amqp://localhost"); err == nil {
+		t.Error("amqp sink was accepted")
+	}
+}
+
+// fakeNATS greets each client with info and records what it publishes.
+type fakeNATS struct {
+	ln      net.Listener
+	info    string
+	mu      sync.Mutex
+	connect string
+	msgs    []string // subject and payload
+}
+
+func newFakeNATS(t *testing.T, info string) *fakeNATS {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &fakeNATS{ln: ln, info: info}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeNATS) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO %s\r\n", s.info)
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			s.mu.Lock()
+			s.connect = strings.TrimPrefix(line, "CONNECT ")
+			s.mu.Unlock()
+		case strings.HasPrefix(line, "PUB "):
+			f := strings.Fields(line)
+			n, _ := strconv.Atoi(f[len(f)-1])
+			body := make([]byte, n+2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.msgs = append(s.msgs, f[1]+" "+string(body[:n]))
+			s.mu.Unlock()
+		case line == "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		}
+	}
+}
+
+func TestNATSSink(t *testing.T) {
+	srv := newFakeNATS(t, `{"server_id":"fake","max_payload":1048576}`)
+	sink, err := events.NewSink("nats://token@" + srv.ln.Addr().String())
+	if err != nil {
+		t.Fatalf("new sink: %v", err)
+	}
+	defer sink.Close()
+
+	evt, _ := events.New(events.TypeRatesPublished, "USD", map[string]string{"base": "USD"})
+	if err := sink.Send(context.Background(), evt); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !strings.Contains(srv.connect, `"auth_token":"token"`) {
+		t.Errorf("CONNECT %s", srv.connect)
+	}
+	if len(srv.msgs) != 1 || !strings.HasPrefix(srv.msgs[0], "rates.published {") || !strings.Contains(srv.msgs[0], evt.ID) {
+		t.Errorf("published %q", srv.msgs)
+	}
+}
+
+func TestNATSSinkRefusesTLSRequired(t *testing.T) {
+	srv := newFakeNATS(t, `{"server_id":"fake","tls_required":true}`)
+	sink, err := events.NewSink("nats://" + srv.ln.Addr().String())
+	if err != nil {
+		t.Fatalf("new sink: %v", err)
+	}
+	defer sink.Close()
+
+	evt, _ := events.New(events.TypeRatesPublished, "USD", nil)
+	if err := sink.Send(context.Background(), evt); err == nil || !strings.Contains(err.Error(), "requires TLS") {
+		t.Fatalf("send = %v, want a TLS error", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.connect != "" || len(srv.msgs) != 0 {
+		t.Errorf("sink talked to the server: CONNECT %q, published %q", srv.connect, srv.msgs)
+	}
+}
+
+func TestNATSSinkUnreachable(t *testing.T) {
+	sink, err := events.NewSink("nats://127.0.0.1:1")
+	if err != nil {
+		t.Fatalf("new sink: %v", err)
+	}
+	evt, _ := events.New(events.TypeRatesPublished, "USD", nil)
+	if err := sink.Send(context.Background(), evt); err == nil {
+		t.Fatal("send without a server succeeded")
+	}
+}
+
+func TestKafkaRESTSink(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+		keys  []string
+	)
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/vnd.kafka.json.v2+json" {
+			http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+			return
+		}
+		var body struct {
+			Records []struct {
+				Key   string       `json:"key"`
+				Value events.Event `json:"value"`
+			} `json:"records"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		keys = append(keys, body.Records[0].Key)
+		if fail {
+			fail = false
+			_, _ = io.WriteString(w, `{"offsets":[{"partition":null,"offset":null,"error_code":50003,"error":"leader not available"}]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"offsets":[{"partition":0,"offset":7,"error_code":null,"error":null}]}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	sink, err := events.NewSink("kafka+" + srv.URL + "?topic=fx-rates")
+	if err != nil {
+		t.Fatalf("new sink: %v", err)
+	}
+	defer sink.Close()
+	evt, _ := events.New(events.TypeRatesPublished, "USD", nil)
+	if err := sink.Send(context.Background(), evt); err == nil || !strings.Contains(err.Error(), "50003") {
+		t.Errorf("send with a partition error = %v", err)
+	}
+	if err := sink.Send(context.Background(), evt); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 2 || paths[1] != "/topics/fx-rates" || keys[1] != "USD" {
+		t.Errorf("requests = %v, keys %v", paths, keys)
+	}
+}
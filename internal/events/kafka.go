@@ -0,0 +1,93 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const kafkaRESTContentType = "application/vnd.kafka.json.v2+json"
+
+// kafkaRESTSink produces to Kafka through a REST Proxy (Confluent v2 API),
+// keyed by the event key so events of one base stay in one partition.
+type kafkaRESTSink struct {
+	base   string
+	topic  string
+	client *http.Client
+}
+
+func newKafkaRESTSink(u *url.URL, topic string) *kafkaRESTSink {
+	base := *u
+	base.Scheme = strings.TrimPrefix(u.Scheme, "kafka+")
+	base.RawQuery = ""
+	return &kafkaRESTSink{
+		base:   strings.TrimRight(base.String(), "/"),
+		topic:  topic,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key,omitempty"`
+	Value Event  `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		Partition int     `json:"partition"`
+		Offset    int64   `json:"offset"`
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (s *kafkaRESTSink) Send(ctx context.Context, evt Event) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Key: evt.Key, Value: evt}},
+	})
+	if err != nil {
+		return err
+	}
+	endpoint := s.base + "/topics/" + url.PathEscape(subject(s.topic, evt))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaRESTContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("kafka rest proxy answered %s: %s", resp.Status, bytes.TrimSpace(raw))
+	}
+	var out kafkaProduceResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return fmt.Errorf("kafka rest proxy: %w", err)
+	}
+	for _, o := range out.Offsets {
+		if o.ErrorCode != nil {
+			msg := ""
+			if o.Error != nil {
+				msg = *o.Error
+			}
+			return fmt.Errorf("kafka rest proxy: error %d: %s", *o.ErrorCode, msg)
+		}
+	}
+	return nil
+}
+
+func (s *kafkaRESTSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,167 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsTimeout bounds connecting and each publish when ctx has no deadline.
+const natsTimeout = 5 * time.Second
+
+// natsSink speaks just enough of the NATS client protocol to publish:
+// CONNECT, PUB and a PING per event whose PONG confirms the server
+// processed the PUB. The connection is dropped on any error and made again
+// on the next send. It does not speak TLS, so servers that require it are
+// refused.
+type natsSink struct {
+	addr  string
+	user  *url.Userinfo
+	topic string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newNATSSink(u *url.URL, topic string) *natsSink {
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return &natsSink{addr: addr, user: u.User, topic: topic}
+}
+
+func (s *natsSink) Send(ctx context.Context, evt Event) error {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return fmt.Errorf("nats connect: %w", err)
+		}
+	}
+	if err := s.publish(ctx, subject(s.topic, evt), body); err != nil {
+		s.drop()
+		return fmt.Errorf("nats publish: %w", err)
+	}
+	return nil
+}
+
+func (s *natsSink) connect(ctx context.Context) error {
+	d := net.Dialer{Timeout: natsTimeout}
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.conn, s.r = conn, bufio.NewReader(conn)
+	s.setDeadline(ctx)
+
+	line, err := s.readLine()
+	if err != nil {
+		s.drop()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		s.drop()
+		return fmt.Errorf("unexpected greeting %q", line)
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		s.drop()
+		return fmt.Errorf("invalid INFO: %w", err)
+	}
+	if info.TLSRequired {
+		s.drop()
+		return errors.New("server requires TLS, which the nats:// events sink does not support")
+	}
+	opts := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     Source,
+		"lang":     "go",
+	}
+	if s.user != nil {
+		if pass, ok := s.user.Password(); ok {
+			opts["user"], opts["pass"] = s.user.Username(), pass
+		} else {
+			opts["auth_token"] = s.user.Username()
+		}
+	}
+	connect, _ := json.Marshal(opts)
+	if _, err := fmt.Fprintf(s.conn, "CONNECT %s\r\n", connect); err != nil {
+		s.drop()
+		return err
+	}
+	return nil
+}
+
+func (s *natsSink) publish(ctx context.Context, subj string, body []byte) error {
+	if subj == "" || strings.ContainsAny(subj, " \t\r\n") {
+		return fmt.Errorf("invalid subject %q", subj)
+	}
+	s.setDeadline(ctx)
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", subj, len(body), body)
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		return err
+	}
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := s.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// +OK and INFO updates need no answer.
+	}
+}
+
+func (s *natsSink) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(natsTimeout)
+	}
+	_ = s.conn.SetDeadline(deadline)
+}
+
+func (s *natsSink) readLine() (string, error) {
+	line, err := s.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (s *natsSink) drop() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn, s.r = nil, nil
+	}
+}
+
+func (s *natsSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop()
+	return nil
+}
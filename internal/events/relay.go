@@ -0,0 +1,136 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+const (
+	relayBatchSize       = 100
+	relayMaxBackoff      = time.Minute
+	relayCleanupInterval = time.Hour
+)
+
+// Relay delivers outbox events to a sink in the order they were written.
+// A failed event is retried, with a growing pause, before any later one is
+// sent.
+type Relay struct {
+	repo      repositories.OutboxRepository
+	sink      Sink
+	interval  time.Duration
+	retention time.Duration
+	log       *logger.Logger
+}
+
+// NewRelay polls repo every interval. Delivered events are deleted once
+// older than retention; zero keeps them.
+func NewRelay(repo repositories.OutboxRepository, sink Sink, interval, retention time.Duration, log *logger.Logger) *Relay {
+	return &Relay{repo: repo, sink: sink, interval: interval, retention: retention, log: log}
+}
+
+// Run relays events until ctx is cancelled. It may run again afterwards,
+// e.g. when this instance is elected leader once more.
+func (r *Relay) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	lastCleanup := time.Time{}
+	failures := 0
+	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+		if r.relayPending(ctx) {
+			failures = 0
+		} else {
+			failures++
+		}
+		if r.retention > 0 && time.Since(lastCleanup) >= relayCleanupInterval {
+			r.cleanup(ctx)
+			lastCleanup = time.Now()
+		}
+		timer.Reset(r.delay(failures))
+	}
+}
+
+// Close closes the sink, once the relay has stopped for good.
+func (r *Relay) Close() error {
+	return r.sink.Close()
+}
+
+// delay is the poll interval, doubled per consecutive failure up to
+// relayMaxBackoff.
+func (r *Relay) delay(failures int) time.Duration {
+	d := r.interval
+	for i := 0; i < failures && d < relayMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, max(relayMaxBackoff, r.interval))
+}
+
+// relayPending sends pending events until none are left and reports whether
+// all of them were delivered.
+func (r *Relay) relayPending(ctx context.Context) bool {
+	for ctx.Err() == nil {
+		pending, err := r.repo.Pending(ctx, relayBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.log.Error("failed to load outbox events", logger.Fields{"error": err.Error()})
+			}
+			return false
+		}
+		for _, row := range pending {
+			var evt Event
+			if err := json.Unmarshal([]byte(row.Payload), &evt); err != nil {
+				// Cannot happen for rows written by the outbox publisher;
+				// skip rather than block every later event.
+				r.log.Error("dropping malformed outbox event", logger.Fields{"id": row.ID, "error": err.Error()})
+				_ = r.repo.MarkPublished(ctx, row.ID, time.Now().UTC())
+				continue
+			}
+			if err := r.sink.Send(ctx, evt); err != nil {
+				if ctx.Err() != nil {
+					return false
+				}
+				reason := err.Error()
+				if len(reason) > 255 {
+					reason = reason[:255]
+				}
+				_ = r.repo.MarkFailed(ctx, row.ID, reason)
+				r.log.Warn("failed to relay event", logger.Fields{
+					"event_id": evt.ID,
+					"type":     evt.Type,
+					"attempts": row.Attempts + 1,
+					"error":    err.Error(),
+				})
+				return false
+			}
+			if err := r.repo.MarkPublished(ctx, row.ID, time.Now().UTC()); err != nil {
+				// Sent but not marked: it will be sent again, which
+				// at-least-once delivery allows.
+				r.log.Error("failed to mark event published", logger.Fields{"event_id": evt.ID, "error": err.Error()})
+				return false
+			}
+		}
+		if len(pending) < relayBatchSize {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	n, err := r.repo.DeletePublishedBefore(ctx, time.Now().UTC().Add(-r.retention))
+	if err != nil {
+		r.log.Error("outbox retention cleanup failed", logger.Fields{"error": err.Error()})
+		return
+	}
+	if n > 0 {
+		r.log.Info("outbox retention cleanup", logger.Fields{"deleted": n})
+	}
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Sink delivers events to a downstream system. A nil error means the
+// system accepted evt.
+type Sink interface {
+	Send(ctx context.Context, evt Event) error
+	Close() error
+}
+
+// NewSink builds the sink named by rawURL:
+//
+//	stdout                      JSON lines on standard output
+//	file:/var/log/events.jsonl  JSON lines appended to a file
+//	nats://host:4222            NATS core publish
+//	kafka+http://host:8082      Kafka REST Proxy (v2), also kafka+https
+//
+// NATS and Kafka send each event to the subject or topic named after its
+// type, or to the one given as ?topic=.
+func NewSink(rawURL string) (Sink, error) {
+	if rawURL == "stdout" {
+		return &writerSink{w: os.Stdout}, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid events sink %q: %w", rawURL, err)
+	}
+	topic := u.Query().Get("topic")
+	switch u.Scheme {
+	case "file":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		if path == "" {
+			return nil, fmt.Errorf("events sink %q has no file path", rawURL)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		return &writerSink{w: f, c: f}, nil
+	case "nats":
+		return newNATSSink(u, topic), nil
+	case "kafka+http", "kafka+https":
+		return newKafkaRESTSink(u, topic), nil
+	default:
+		return nil, fmt.Errorf("unsupported events sink %q: use stdout, file:, nats:// or kafka+http(s)://", rawURL)
+	}
+}
+
+// writerSink writes one JSON document per line.
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+	c  io.Closer
+}
+
+func (s *writerSink) Send(_ context.Context, evt Event) error {
+	line, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+func (s *writerSink) Close() error {
+	if s.c == nil {
+		return nil
+	}
+	return s.c.Close()
+}
+
+// subject is where evt goes on a broker: the configured topic, or its type.
+func subject(topic string, evt Event) string {
+	if topic != "" {
+		return topic
+	}
+	return strings.TrimSpace(evt.Type)
+}
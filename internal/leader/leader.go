@@ -0,0 +1,162 @@
+// Package leader elects one instance among the replicas sharing a database
+// to run the background work that must not run twice: refreshing rates from
+// the paid provider, sending webhooks and relaying events.
+package leader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// releaseTimeout bounds giving up the lease on shutdown.
+const releaseTimeout = 5 * time.Second
+
+// Elector campaigns for a lease row. While this instance holds it, the
+// tasks registered with OnElected run with a context that is cancelled as
+// soon as the lease is lost or the elector stops. Tasks must return soon
+// after: the lease is not given up before they have.
+type Elector struct {
+	repo  repositories.LeaseRepository
+	name  string
+	id    string
+	ttl   time.Duration
+	renew time.Duration
+	log   *logger.Logger
+
+	mu    sync.Mutex
+	tasks []func(ctx context.Context)
+	// running counts the tasks of the current term.
+	running sync.WaitGroup
+
+	leader    atomic.Bool
+	lastError atomic.Pointer[string]
+}
+
+// Status describes the election as this instance last saw it.
+type Status struct {
+	ID     string
+	Leader bool
+	// Holder is the instance holding the lease, empty when none does.
+	Holder    string
+	ExpiresAt time.Time
+	LastError string
+}
+
+// New campaigns for lease name as instance id. The holder renews the lease
+// every third of ttl, so a crashed leader is replaced within ttl.
+func New(repo repositories.LeaseRepository, name, id string, ttl time.Duration, log *logger.Logger) *Elector {
+	return &Elector{repo: repo, name: name, id: id, ttl: ttl, renew: max(ttl/3, time.Millisecond), log: log}
+}
+
+// OnElected registers fn to run, in its own goroutine, every time this
+// instance becomes leader. It must be called before Run.
+func (e *Elector) OnElected(fn func(ctx context.Context)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tasks = append(e.tasks, fn)
+}
+
+// Run campaigns until ctx is cancelled, then stops the leader tasks and
+// releases the lease so another instance takes over at once.
+func (e *Elector) Run(ctx context.Context) {
+	var (
+		stop    context.CancelFunc
+		renewed time.Time
+	)
+	stepDown := func(reason string) {
+		if stop == nil {
+			return
+		}
+		e.leader.Store(false)
+		stop()
+		stop = nil
+		e.running.Wait()
+		e.log.Warn("lost leadership", logger.Fields{"lease": e.name, "instance": e.id, "reason": reason})
+	}
+	defer func() {
+		stepDown("shutting down")
+		rctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+		defer cancel()
+		if err := e.repo.Release(rctx, e.name, e.id); err != nil {
+			e.log.Warn("failed to release leader lease", logger.Fields{"lease": e.name, "error": err.Error()})
+		}
+	}()
+
+	ticker := time.NewTicker(e.renew)
+	defer ticker.Stop()
+	for {
+		now := time.Now().UTC()
+		ok, err := e.repo.Acquire(ctx, e.name, e.id, now, now.Add(e.ttl))
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			msg := err.Error()
+			e.lastError.Store(&msg)
+			e.log.Error("leader election failed", logger.Fields{"lease": e.name, "error": msg})
+			// Keep leading only while the lease we renewed last is surely
+			// still ours; past that another instance may have taken it.
+			if stop != nil && time.Since(renewed) > e.ttl-e.renew {
+				stepDown("could not renew the lease")
+			}
+		case ok:
+			e.lastError.Store(nil)
+			renewed = now
+			if stop == nil {
+				stop = e.lead(ctx)
+			}
+		default:
+			e.lastError.Store(nil)
+			stepDown("lease held by another instance")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+func (e *Elector) ID() string {
+	return e.id
+}
+
+func (e *Elector) Status(ctx context.Context) Status {
+	st := Status{ID: e.id, Leader: e.leader.Load()}
+	if msg := e.lastError.Load(); msg != nil {
+		st.LastError = *msg
+	}
+	lease, err := e.repo.Find(ctx, e.name)
+	if err == nil && lease.ExpiresAt.After(time.Now()) {
+		st.Holder, st.ExpiresAt = lease.Holder, lease.ExpiresAt
+	}
+	return st
+}
+
+// lead starts the leader tasks and returns the function that stops them.
+func (e *Elector) lead(ctx context.Context) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	e.leader.Store(true)
+	e.log.Info("elected leader", logger.Fields{"lease": e.name, "instance": e.id})
+	e.mu.Lock()
+	tasks := e.tasks
+	e.mu.Unlock()
+	for _, fn := range tasks {
+		e.running.Add(1)
+		go func() {
+			defer e.running.Done()
+			fn(ctx)
+		}()
+	}
+	return cancel
+}
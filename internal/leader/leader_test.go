@@ -0,0 +1,105 @@
+package leader_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/leader"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories/memory"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+const ttl = 150 * time.Millisecond
+
+// brokenLeases fails every call while down is set, like a lost database.
+type brokenLeases struct {
+	repositories.LeaseRepository
+	down atomic.Bool
+}
+
+func (r *brokenLeases) Acquire(ctx context.Context, name, holder string, now, expiresAt time.Time) (bool, error) {
+	if r.down.Load() {
+		return false, errors.New("connection refused")
+	}
+	return r.LeaseRepository.Acquire(ctx, name, holder, now, expiresAt)
+}
+
+// candidate starts an elector whose only task counts itself in running
+// while it leads.
+func candidate(t *testing.T, repo repositories.LeaseRepository, id string, running *atomic.Int32) (*leader.Elector, context.CancelFunc) {
+	t.Helper()
+	e := leader.New(repo, "work", id, ttl, logger.New(logger.Options{Level: "error"}))
+	e.OnElected(func(ctx context.Context) {
+		if n := running.Add(1); n > 1 {
+			t.Errorf("%d leaders at once", n)
+		}
+		<-ctx.Done()
+		running.Add(-1)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go e.Run(ctx)
+	return e, cancel
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestElectorHandsOverOnShutdown(t *testing.T) {
+	repo := memory.NewLeaseRepository()
+	var running atomic.Int32
+	a, stopA := candidate(t, repo, "a", &running)
+	waitFor(t, "a to lead", a.IsLeader)
+	b, _ := candidate(t, repo, "b", &running)
+
+	time.Sleep(2 * ttl)
+	if b.IsLeader() {
+		t.Fatal("b leads while a renews the lease")
+	}
+	if st := b.Status(context.Background()); st.Holder != "a" || st.Leader {
+		t.Fatalf("status of b = %+v", st)
+	}
+
+	// a releases the lease on shutdown, so b need not wait for it to expire.
+	start := time.Now()
+	stopA()
+	waitFor(t, "b to lead", b.IsLeader)
+	if d := time.Since(start); d > ttl {
+		t.Errorf("took over after %s, want within the renew interval", d)
+	}
+	waitFor(t, "one leader task", func() bool { return running.Load() == 1 })
+}
+
+func TestElectorStepsDownWhenRenewalFails(t *testing.T) {
+	shared := memory.NewLeaseRepository()
+	flaky := &brokenLeases{LeaseRepository: shared}
+	var running atomic.Int32
+	a, _ := candidate(t, flaky, "a", &running)
+	waitFor(t, "a to lead", a.IsLeader)
+	b, _ := candidate(t, shared, "b", &running)
+
+	flaky.down.Store(true)
+	waitFor(t, "a to step down", func() bool { return !a.IsLeader() })
+	if st := a.Status(context.Background()); st.LastError == "" {
+		t.Errorf("status of a has no error: %+v", st)
+	}
+	waitFor(t, "b to lead once the lease expired", b.IsLeader)
+
+	flaky.down.Store(false)
+	time.Sleep(2 * ttl)
+	if a.IsLeader() {
+		t.Fatal("a took the lease back from b")
+	}
+}
@@ -0,0 +1,126 @@
+// Package lifecycle owns the background workers of the application and stops
+// them, then the resources they use, when it shuts down.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// Lifecycle runs workers until Stop, which cancels them, waits for them to
+// return and then runs the stop hooks, last registered first.
+type Lifecycle struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	log    *logger.Logger
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	stopped bool
+	running map[string]int
+	hooks   []hook
+}
+
+type hook struct {
+	name string
+	fn   func() error
+}
+
+func New(log *logger.Logger) *Lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Lifecycle{ctx: ctx, cancel: cancel, log: log, running: make(map[string]int)}
+}
+
+// Context is cancelled when Stop begins.
+func (l *Lifecycle) Context() context.Context {
+	return l.ctx
+}
+
+// Go runs worker in its own goroutine. worker must return soon after its
+// context is cancelled, having finished or abandoned what it was doing.
+func (l *Lifecycle) Go(name string, worker func(ctx context.Context)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopped {
+		l.log.Warn("worker not started, shutting down", logger.Fields{"worker": name})
+		return
+	}
+	l.running[name]++
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		defer l.done(name)
+		worker(l.ctx)
+	}()
+}
+
+func (l *Lifecycle) done(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.running[name]--; l.running[name] == 0 {
+		delete(l.running, name)
+	}
+}
+
+// OnStop registers fn to run once every worker has returned, e.g. to close
+// the connections they used. Hooks run in reverse order of registration.
+func (l *Lifecycle) OnStop(name string, fn func() error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook{name: name, fn: fn})
+}
+
+// Stop cancels the workers and waits for them until ctx is done, then runs
+// the stop hooks whether or not they all returned. Calling it again does
+// nothing.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		return nil
+	}
+	l.stopped = true
+	hooks := l.hooks
+	l.mu.Unlock()
+
+	l.cancel()
+	waited := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(waited)
+	}()
+
+	var errs []error
+	select {
+	case <-waited:
+	case <-ctx.Done():
+		names := l.stillRunning()
+		l.log.Error("workers did not stop in time", logger.Fields{"workers": names})
+		errs = append(errs, fmt.Errorf("workers did not stop in time: %v", names))
+	}
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if err := h.fn(); err != nil {
+			l.log.Error("stop hook failed", logger.Fields{"hook": h.name, "error": err.Error()})
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l *Lifecycle) stillRunning() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	names := make([]string, 0, len(l.running))
+	for name := range l.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
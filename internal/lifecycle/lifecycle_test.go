@@ -0,0 +1,70 @@
+package lifecycle_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/lifecycle"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+func TestStopWaitsForWorkersBeforeHooks(t *testing.T) {
+	lc := lifecycle.New(logger.New(logger.Options{Level: "error"}))
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, s)
+	}
+
+	lc.OnStop("database", func() error { record("database"); return nil })
+	lc.OnStop("sink", func() error { record("sink"); return nil })
+	for _, name := range []string{"refresh", "usage"} {
+		lc.Go(name, func(ctx context.Context) {
+			<-ctx.Done()
+			// Work in flight when cancelled, e.g. a final flush.
+			time.Sleep(20 * time.Millisecond)
+			record(name)
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := lc.Stop(ctx); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	// Workers finish in any order; hooks run last registered first.
+	if len(order) != 4 || order[2] != "sink" || order[3] != "database" {
+		t.Errorf("order = %v, want both workers, then sink, then database", order)
+	}
+	if err := lc.Stop(ctx); err != nil {
+		t.Errorf("second stop: %v", err)
+	}
+
+	lc.Go("late", func(ctx context.Context) { t.Error("worker started after stop") })
+}
+
+func TestStopGivesUpOnStuckWorkers(t *testing.T) {
+	lc := lifecycle.New(logger.New(logger.Options{Level: "error"}))
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	lc.Go("stuck", func(ctx context.Context) { <-release })
+	closed := false
+	lc.OnStop("database", func() error { closed = true; return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := lc.Stop(ctx)
+	if err == nil || !strings.Contains(err.Error(), "stuck") {
+		t.Errorf("stop = %v, want the stuck worker named", err)
+	}
+	if !closed {
+		t.Error("stop hooks did not run after the timeout")
+	}
+}
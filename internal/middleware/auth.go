@@ -3,8 +3,10 @@ package middleware
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
 	"github.com/spksupakorn/Currency-Converter/internal/repositories"
 	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
 	"github.com/spksupakorn/Currency-Converter/pkg/response"
 )
 
@@ -29,12 +31,22 @@ func AuthRequired(cfg config.Config, userRepo repositories.UserRepository) gin.H
 			return
 		}
 		// Check token version against DB
-		u, err := userRepo.FindByID(claims.UserID)
+		u, err := userRepo.FindByID(c.Request.Context(), claims.UserID)
 		if err != nil || u == nil {
 			response.Unauthorized(c, "unauthorized", "user not found")
 			c.Abort()
 			return
 		}
+		// A replica that has not yet seen the login which issued this token
+		// reports an older version; confirm against the primary.
+		if u.TokenVersion < claims.TokenVersion {
+			u, err = userRepo.FindByID(repositories.ReadPrimary(c.Request.Context()), claims.UserID)
+			if err != nil || u == nil {
+				response.Unauthorized(c, "unauthorized", "user not found")
+				c.Abort()
+				return
+			}
+		}
 		if u.TokenVersion != claims.TokenVersion {
 			response.Unauthorized(c, "unauthorized", "token revoked")
 			c.Abort()
@@ -43,6 +55,22 @@ func AuthRequired(cfg config.Config, userRepo repositories.UserRepository) gin.H
 
 		c.Set("user_id", u.ID)
 		c.Set("user_email", u.Email)
+		c.Set("user_role", authSvc.Role(u))
+		if l := RequestLog(c, nil); l != nil {
+			setRequestLogger(c, l.WithFields(logger.Fields{"user_id": u.ID}))
+		}
+		c.Next()
+	}
+}
+
+// AdminRequired must run after AuthRequired.
+func AdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("user_role") != models.RoleAdmin {
+			response.Forbidden(c, "forbidden", "admin access required")
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
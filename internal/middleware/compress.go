@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+var encoderPools = map[string]*sync.Pool{
+	encodingBrotli: {New: func() any { return brotli.NewWriterLevel(io.Discard, 4) }},
+	encodingGzip:   {New: func() any { return gzip.NewWriter(io.Discard) }},
+}
+
+type resettableWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// Compress encodes response bodies with brotli or gzip, whichever the client
+// prefers in Accept-Encoding (brotli on a tie). Responses without a body and
+// responses a handler already encoded are passed through, and so are the
+// ones outer middleware writes once the handlers returned, such as the 500 of
+// Recovery and the 504 of Timeout.
+func Compress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks br or gzip from an Accept-Encoding header, honouring
+// q-values; it returns "" when neither is acceptable.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != encodingBrotli && name != encodingGzip {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > bestQ || (q == bestQ && name == encodingBrotli) {
+			best, bestQ = name, q
+		}
+	}
+	if bestQ <= 0 {
+		return ""
+	}
+	return best
+}
+
+// compressWriter decides on the first body write whether to encode, so the
+// status code and headers set by the handler are known by then.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	decided  bool
+	enc      resettableWriter
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.start() {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.enc.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) start() bool {
+	if w.decided {
+		return w.enc != nil
+	}
+	w.decided = true
+	h := w.Header()
+	status := w.Status()
+	if status == http.StatusNoContent || status == http.StatusNotModified || h.Get("Content-Encoding") != "" {
+		return false
+	}
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	enc := encoderPools[w.encoding].Get().(resettableWriter)
+	enc.Reset(w.ResponseWriter)
+	w.enc = enc
+	return true
+}
+
+func (w *compressWriter) close() {
+	if w.enc == nil {
+		return
+	}
+	_ = w.enc.Close()
+	encoderPools[w.encoding].Put(w.enc)
+	w.enc = nil
+}
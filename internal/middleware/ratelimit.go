@@ -1,11 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/internal/lifecycle"
 	"github.com/spksupakorn/Currency-Converter/pkg/response"
 	"golang.org/x/time/rate"
 )
@@ -15,13 +17,23 @@ type visitor struct {
 	lastSeen time.Time
 }
 
-func RateLimit(cfg config.Config) gin.HandlerFunc {
+// RateLimit applies a per-IP token bucket. Limits follow the reloadable
+// RATE_LIMIT_REQUESTS and RATE_LIMIT_WINDOW settings; existing visitors are
+// adjusted in place when they change. Idle visitors are forgotten by a
+// worker that runs until lc stops.
+func RateLimit(settings *config.Reloader, lc *lifecycle.Lifecycle) gin.HandlerFunc {
 	var mu sync.Mutex
 	visitors := make(map[string]*visitor)
-	cleanupTicker := time.NewTicker(5 * time.Minute)
 
-	go func() {
-		for range cleanupTicker.C {
+	lc.Go("rate limit cleanup", func(ctx context.Context) {
+		cleanupTicker := time.NewTicker(5 * time.Minute)
+		defer cleanupTicker.Stop()
+		for {
+			select {
+			case <-cleanupTicker.C:
+			case <-ctx.Done():
+				return
+			}
 			mu.Lock()
 			for ip, v := range visitors {
 				if time.Since(v.lastSeen) > 10*time.Minute {
@@ -30,14 +42,32 @@ func RateLimit(cfg config.Config) gin.HandlerFunc {
 			}
 			mu.Unlock()
 		}
-	}()
+	})
 
-	newVisitor := func() *rate.Limiter {
+	limits := func(cfg config.Config) (rate.Limit, int) {
 		per := cfg.RateLimitWindow
 		req := cfg.RateLimitRequests
-		return rate.NewLimiter(rate.Every(per/time.Duration(req)), req)
+		return rate.Every(per / time.Duration(req)), req
+	}
+
+	newVisitor := func() *rate.Limiter {
+		return rate.NewLimiter(limits(settings.Current()))
 	}
 
+	settings.Subscribe(func(old, new config.Config) error {
+		if old.RateLimitRequests == new.RateLimitRequests && old.RateLimitWindow == new.RateLimitWindow {
+			return nil
+		}
+		limit, burst := limits(new)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, v := range visitors {
+			v.limiter.SetLimit(limit)
+			v.limiter.SetBurst(burst)
+		}
+		return nil
+	})
+
 	getVisitor := func(ip string) *rate.Limiter {
 		mu.Lock()
 		defer mu.Unlock()
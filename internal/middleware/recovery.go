@@ -12,10 +12,8 @@ func Recovery(log *logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if rec := recover(); rec != nil {
-				traceID, _ := c.Get("trace_id")
-				log.Error("panic recovered", logger.Fields{
-					"panic":    rec,
-					"trace_id": traceID,
+				RequestLog(c, log).Error("panic recovered", logger.Fields{
+					"panic": rec,
 				})
 				response.WithStatus(c, http.StatusInternalServerError, "internal_error", "internal server error", nil)
 				c.Abort()
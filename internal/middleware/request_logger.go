@@ -1,27 +1,62 @@
 package middleware
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spksupakorn/Currency-Converter/pkg/logger"
 )
 
-func RequestLogger(log *logger.Logger) gin.HandlerFunc {
+// ContextLogger attaches a request-scoped logger carrying the trace ID and
+// route to the request context. It must run after RequestID.
+func ContextLogger(log *logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		start := time.Now()
 		traceID, _ := c.Get("trace_id")
+		l := log.WithFields(logger.Fields{
+			"trace_id": traceID,
+			"route":    c.FullPath(),
+			"method":   c.Request.Method,
+		})
+		setRequestLogger(c, l)
+		c.Next()
+	}
+}
+
+// RequestLog returns the request-scoped logger, or fallback when the
+// ContextLogger middleware is not installed.
+func RequestLog(c *gin.Context, fallback *logger.Logger) *logger.Logger {
+	return logger.FromContext(c.Request.Context(), fallback)
+}
+
+func setRequestLogger(c *gin.Context, l *logger.Logger) {
+	c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), l))
+}
+
+// RequestLogger writes one "http_request" line per request. With sampleEvery
+// greater than one only every Nth successful request is logged; client and
+// server errors are always logged.
+func RequestLogger(log *logger.Logger, sampleEvery int) gin.HandlerFunc {
+	var counter uint64
+	return func(c *gin.Context) {
+		start := time.Now()
 		c.Next()
 		lat := time.Since(start)
-		log.Info("http_request",
+
+		status := c.Writer.Status()
+		if sampleEvery > 1 && status < 400 {
+			if atomic.AddUint64(&counter, 1)%uint64(sampleEvery) != 0 {
+				return
+			}
+		}
+
+		RequestLog(c, log).Info("http_request",
 			logger.Fields{
-				"method":      c.Request.Method,
-				"path":        c.FullPath(),
-				"status":      c.Writer.Status(),
+				"path":        c.Request.URL.Path,
+				"status":      status,
 				"duration_ms": lat.Milliseconds(),
 				"ip":          c.ClientIP(),
 				"ua":          c.Request.UserAgent(),
-				"trace_id":    traceID,
 			},
 		)
 	}
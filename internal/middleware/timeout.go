@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"github.com/spksupakorn/Currency-Converter/pkg/response"
+)
+
+// Timeout bounds each request with a deadline on its context: the entry in
+// REQUEST_TIMEOUTS for the matched route, else REQUEST_TIMEOUT. Handlers keep
+// running on the request goroutine and are expected to give up once the
+// context is done; if one returns without writing a response after the
+// deadline passed, a 504 is sent.
+func Timeout(cfg config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d, ok := cfg.RouteTimeouts[c.FullPath()]
+		if !ok {
+			d = cfg.RequestTimeout
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if !c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			if l := RequestLog(c, nil); l != nil {
+				l.Warn("request deadline exceeded", logger.Fields{"timeout": d.String()})
+			}
+			response.GatewayTimeout(c, "timeout", "request timed out after "+d.String())
+			c.Abort()
+		}
+	}
+}
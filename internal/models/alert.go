@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+const (
+	AlertAbove  = "above"
+	AlertBelow  = "below"
+	AlertChange = "change"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+const (
+	EventRateAlert = "rate.alert"
+	EventAlertTest = "rate.alert.test"
+)
+
+// AlertRule notifies its owner's webhook when the rate of From in To crosses
+// Threshold (AlertAbove, AlertBelow), or moves by more than Threshold percent
+// within Window (AlertChange). After firing it stays quiet for Cooldown.
+type AlertRule struct {
+	ID            uint          `gorm:"primaryKey" json:"id"`
+	UserID        uint          `gorm:"index;not null" json:"-"`
+	FromCurrency  string        `gorm:"size:3;not null" json:"from"`
+	ToCurrency    string        `gorm:"size:3;not null" json:"to"`
+	Condition     string        `gorm:"size:16;not null" json:"condition"`
+	Threshold     float64       `gorm:"not null" json:"threshold"`
+	Window        time.Duration `gorm:"column:change_window;not null" json:"-"`
+	Cooldown      time.Duration `gorm:"not null" json:"-"`
+	WebhookURL    string        `gorm:"size:2048;not null" json:"webhook_url"`
+	WebhookSecret string        `gorm:"size:100;not null" json:"-"`
+
+	// LastRate is the rate at the previous evaluation, to detect crossings;
+	// BaselineRate is the rate at BaselineAt, the start of the current
+	// window of an AlertChange rule.
+	LastRate        float64    `gorm:"not null" json:"last_rate"`
+	BaselineRate    float64    `gorm:"not null" json:"-"`
+	BaselineAt      time.Time  `json:"-"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+	CreatedAt       time.Time  `gorm:"not null" json:"created_at"`
+}
+
+// AlertDelivery is one webhook call for a fired alert, retried with backoff
+// until it is delivered or runs out of attempts and becomes dead.
+type AlertDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	RuleID         uint       `gorm:"index;not null" json:"rule_id"`
+	UserID         uint       `gorm:"index;not null" json:"-"`
+	Event          string     `gorm:"size:32;not null" json:"event"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	Status         string     `gorm:"size:16;index;not null" json:"status"`
+	Attempts       int        `gorm:"not null" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index;not null" json:"next_attempt_at"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `gorm:"size:255" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"not null" json:"created_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+const (
+	AuditActionRegister     = "auth.register"
+	AuditActionLogin        = "auth.login"
+	AuditActionLogout       = "auth.logout"
+	AuditActionTokenRevoked = "auth.token_revoked"
+	AuditActionRoleChanged  = "auth.role_changed"
+)
+
+// AuditEvent is an append-only record of a security relevant action. Rows are
+// never updated or deleted by the application.
+type AuditEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `gorm:"index;not null" json:"created_at"`
+	ActorID    *uint     `gorm:"index" json:"actor_id,omitempty"`
+	ActorEmail string    `gorm:"size:100" json:"actor_email,omitempty"`
+	Action     string    `gorm:"size:64;index;not null" json:"action"`
+	TargetType string    `gorm:"size:32" json:"target_type,omitempty"`
+	TargetID   string    `gorm:"size:64" json:"target_id,omitempty"`
+	Outcome    string    `gorm:"size:16;index;not null" json:"outcome"`
+	Reason     string    `gorm:"size:255" json:"reason,omitempty"`
+	IP         string    `gorm:"size:64" json:"ip,omitempty"`
+	UserAgent  string    `gorm:"size:255" json:"user_agent,omitempty"`
+	TraceID    string    `gorm:"size:64;index" json:"trace_id,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Lease is a named lock held by one instance until ExpiresAt. The holder
+// renews it while alive; once it expires any instance may take it.
+type Lease struct {
+	Name      string    `gorm:"primaryKey;size:64" json:"name"`
+	Holder    string    `gorm:"size:128;not null" json:"holder"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	RenewedAt time.Time `gorm:"not null" json:"renewed_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// OutboxEvent is a domain event written in the same transaction as the
+// change it describes, and relayed to the event sink afterwards.
+type OutboxEvent struct {
+	ID          uint       `gorm:"primaryKey"`
+	EventID     string     `gorm:"size:36;uniqueIndex;not null"`
+	Type        string     `gorm:"size:64;not null"`
+	Key         string     `gorm:"size:64;not null"`
+	Payload     string     `gorm:"type:text;not null"`
+	Attempts    int        `gorm:"not null"`
+	LastError   string     `gorm:"size:255"`
+	PublishedAt *time.Time `gorm:"index"`
+	CreatedAt   time.Time  `gorm:"not null"`
+}
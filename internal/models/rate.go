@@ -1,9 +1,11 @@
-package models
-
-import "time"
-
-type Rate struct {
-	Currency  string    `gorm:"primaryKey;size:3"`
-	Rate      float64   `gorm:"not null"`
-	UpdatedAt time.Time `gorm:"index"`
-}
+package models
+
+import "time"
+
+type Rate struct {
+	Currency  string    `gorm:"primaryKey;size:3"`
+	Rate      float64   `gorm:"not null"`
+	Base      string    `gorm:"size:3;not null;default:''"`
+	Provider  string    `gorm:"size:100;not null;default:''"`
+	UpdatedAt time.Time `gorm:"index"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	AuditActionRatesRefresh    = "rates.refresh"
+	AuditActionOverrideSet     = "rates.override_set"
+	AuditActionOverrideDeleted = "rates.override_deleted"
+)
+
+// RateOverride pins the rate of Currency, quoted against Base, until
+// ExpiresAt. There is at most one override per currency; setting a new one
+// replaces it.
+type RateOverride struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Currency       string    `gorm:"size:3;uniqueIndex;not null" json:"currency"`
+	Base           string    `gorm:"size:3;not null" json:"base"`
+	Rate           float64   `gorm:"not null" json:"rate"`
+	Reason         string    `gorm:"size:255;not null" json:"reason"`
+	ExpiresAt      time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedByID    *uint     `json:"created_by_id,omitempty"`
+	CreatedByEmail string    `gorm:"size:100" json:"created_by_email,omitempty"`
+	CreatedAt      time.Time `gorm:"not null" json:"created_at"`
+}
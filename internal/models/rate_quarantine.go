@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	QuarantinePending    = "pending"
+	QuarantineApproved   = "approved"
+	QuarantineRejected   = "rejected"
+	QuarantineSuperseded = "superseded"
+)
+
+const (
+	AuditActionQuarantineApproved = "rates.quarantine_approved"
+	AuditActionQuarantineRejected = "rates.quarantine_rejected"
+)
+
+// RateAnomaly is a currency whose rate moved more than RATE_MAX_CHANGE
+// between the published snapshot and a fetched one.
+type RateAnomaly struct {
+	Currency string  `json:"currency"`
+	Previous float64 `json:"previous"`
+	Current  float64 `json:"current"`
+	// Change is relative: 0.5 is a rise of 50%, -0.5 a fall of 50%.
+	Change float64 `json:"change"`
+}
+
+// RateQuarantine is a fetched rate table held back from publishing because
+// of its anomalies until an admin approves or rejects it. A newer fetch, good
+// or quarantined, supersedes a pending one.
+type RateQuarantine struct {
+	ID             uint               `gorm:"primaryKey" json:"id"`
+	Base           string             `gorm:"size:3;not null" json:"base"`
+	Provider       string             `gorm:"size:100;not null" json:"provider"`
+	Rates          map[string]float64 `gorm:"serializer:json;type:text;not null" json:"rates"`
+	Anomalies      []RateAnomaly      `gorm:"serializer:json;type:text;not null" json:"anomalies"`
+	FetchedAt      time.Time          `gorm:"not null" json:"fetched_at"`
+	Status         string             `gorm:"size:16;index;not null" json:"status"`
+	DecidedByID    *uint              `json:"decided_by_id,omitempty"`
+	DecidedByEmail string             `gorm:"size:100" json:"decided_by_email,omitempty"`
+	DecidedAt      *time.Time         `json:"decided_at,omitempty"`
+	CreatedAt      time.Time          `gorm:"not null" json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// UsageRecord is one successful conversion, kept for billing and analytics.
+type UsageRecord struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uint      `gorm:"index:idx_usage_user_created;not null"`
+	FromCurrency string    `gorm:"size:3;not null"`
+	ToCurrency   string    `gorm:"size:3;not null"`
+	Amount       float64   `gorm:"not null"`
+	Rate         float64   `gorm:"not null"`
+	SnapshotAt   time.Time `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"index:idx_usage_user_created;index;not null"`
+}
+
+// UsageSummary is one aggregated row of usage. Only the fields selected by the
+// grouping are populated.
+type UsageSummary struct {
+	Day          string `json:"day,omitempty"`
+	FromCurrency string `json:"from,omitempty"`
+	ToCurrency   string `json:"to,omitempty"`
+	UserID       uint   `json:"user_id,omitempty"`
+	Count        int64  `json:"count"`
+	// TotalAmount sums the amounts in FromCurrency, so it is only set when
+	// usage is grouped by pair.
+	TotalAmount *float64 `json:"total_amount,omitempty"`
+}
@@ -1,13 +1,25 @@
-package models
-
-import (
-	"gorm.io/gorm"
-)
-
-type User struct {
-	gorm.Model
-	Username     string `gorm:"unique;size:100" json:"username"`
-	Email        string `gorm:"uniqueIndex;size:100;not null" json:"email"`
-	Password     string `gorm:"not null" json:"-"`
-	TokenVersion int    `gorm:"not null;default:0"`
-}
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type User struct {
+	gorm.Model
+	Username     string `gorm:"unique;size:100" json:"username"`
+	Email        string `gorm:"uniqueIndex;size:100;not null" json:"email"`
+	Password     string `gorm:"not null" json:"-"`
+	TokenVersion int    `gorm:"not null;default:0"`
+	Role         string `gorm:"size:20;not null;default:user" json:"role"`
+
+	// TokenIssuedAt is when the token of the current version was issued,
+	// nil once it has been revoked.
+	TokenIssuedAt *time.Time `json:"-"`
+}
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
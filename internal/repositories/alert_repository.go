@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"gorm.io/gorm"
+)
+
+type DeliveryFilter struct {
+	UserID uint
+	RuleID uint
+	Status string
+	Limit  int
+}
+
+type AlertRepository interface {
+	CreateRule(ctx context.Context, rule *models.AlertRule) error
+	CountRules(ctx context.Context, userID uint) (int64, error)
+	ListRules(ctx context.Context, userID uint) ([]models.AlertRule, error)
+	// FindRule returns gorm.ErrRecordNotFound unless userID owns rule id.
+	FindRule(ctx context.Context, userID, id uint) (*models.AlertRule, error)
+	// DeleteRule deletes rule id of userID with its deliveries and reports
+	// whether it existed.
+	DeleteRule(ctx context.Context, userID, id uint) (bool, error)
+	AllRules(ctx context.Context) ([]models.AlertRule, error)
+	// UpdateRuleState saves the evaluation state of rule and, when the rule
+	// fired, creates the delivery in the same transaction.
+	UpdateRuleState(ctx context.Context, rule *models.AlertRule, fired *models.AlertDelivery) error
+
+	CreateDelivery(ctx context.Context, d *models.AlertDelivery) error
+	// DueDeliveries returns pending deliveries whose next attempt is due at
+	// now, oldest first.
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.AlertDelivery, error)
+	UpdateDelivery(ctx context.Context, d *models.AlertDelivery) error
+	// ListDeliveries returns matching deliveries, newest first.
+	ListDeliveries(ctx context.Context, f DeliveryFilter) ([]models.AlertDelivery, error)
+	// FindDelivery returns gorm.ErrRecordNotFound unless userID owns
+	// delivery id.
+	FindDelivery(ctx context.Context, userID, id uint) (*models.AlertDelivery, error)
+}
+
+type alertRepository struct {
+	db *gorm.DB
+}
+
+func NewAlertRepository(db *gorm.DB) AlertRepository {
+	return &alertRepository{db: db}
+}
+
+func (r *alertRepository) CreateRule(ctx context.Context, rule *models.AlertRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+func (r *alertRepository) CountRules(ctx context.Context, userID uint) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&models.AlertRule{}).Where("user_id = ?", userID).Count(&n).Error
+	return n, err
+}
+
+func (r *alertRepository) ListRules(ctx context.Context, userID uint) ([]models.AlertRule, error) {
+	var out []models.AlertRule
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&out).Error
+	return out, err
+}
+
+func (r *alertRepository) FindRule(ctx context.Context, userID, id uint) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *alertRepository) DeleteRule(ctx context.Context, userID, id uint) (bool, error) {
+	var found bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ?", userID).Delete(&models.AlertRule{}, id)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		found = true
+		return tx.Where("rule_id = ?", id).Delete(&models.AlertDelivery{}).Error
+	})
+	return found, err
+}
+
+func (r *alertRepository) AllRules(ctx context.Context) ([]models.AlertRule, error) {
+	var out []models.AlertRule
+	err := r.db.WithContext(ctx).Order("id").Find(&out).Error
+	return out, err
+}
+
+func (r *alertRepository) UpdateRuleState(ctx context.Context, rule *models.AlertRule, fired *models.AlertDelivery) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.AlertRule{}).Where("id = ?", rule.ID).Updates(map[string]interface{}{
+			"last_rate":         rule.LastRate,
+			"baseline_rate":     rule.BaselineRate,
+			"baseline_at":       rule.BaselineAt,
+			"last_triggered_at": rule.LastTriggeredAt,
+		}).Error
+		if err != nil || fired == nil {
+			return err
+		}
+		return tx.Create(fired).Error
+	})
+}
+
+func (r *alertRepository) CreateDelivery(ctx context.Context, d *models.AlertDelivery) error {
+	return r.db.WithContext(ctx).Create(d).Error
+}
+
+func (r *alertRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.AlertDelivery, error) {
+	var out []models.AlertDelivery
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at, id").Limit(limit).Find(&out).Error
+	return out, err
+}
+
+func (r *alertRepository) UpdateDelivery(ctx context.Context, d *models.AlertDelivery) error {
+	return r.db.WithContext(ctx).Save(d).Error
+}
+
+func (r *alertRepository) ListDeliveries(ctx context.Context, f DeliveryFilter) ([]models.AlertDelivery, error) {
+	q := r.db.WithContext(ctx).Where("user_id = ?", f.UserID).Order("id DESC").Limit(f.Limit)
+	if f.RuleID != 0 {
+		q = q.Where("rule_id = ?", f.RuleID)
+	}
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	var out []models.AlertDelivery
+	err := q.Find(&out).Error
+	return out, err
+}
+
+func (r *alertRepository) FindDelivery(ctx context.Context, userID, id uint) (*models.AlertDelivery, error) {
+	var d models.AlertDelivery
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&d, id).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
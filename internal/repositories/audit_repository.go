@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuditFilter narrows an audit query. When both ActorID and ActorEmail are
+// set they are OR'ed, so events recorded before the actor was known (e.g.
+// failed logins) are included.
+type AuditFilter struct {
+	ActorID    *uint
+	ActorEmail string
+	Action     string
+	Outcome    string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// AuditRepository only exposes inserts and reads; audit events are immutable.
+type AuditRepository interface {
+	Create(ctx context.Context, evt *models.AuditEvent) error
+	List(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, int64, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Create(ctx context.Context, evt *models.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(evt).Error
+}
+
+func (r *auditRepository) List(ctx context.Context, f AuditFilter) ([]models.AuditEvent, int64, error) {
+	q := r.db.WithContext(ctx).Model(&models.AuditEvent{})
+	switch {
+	case f.ActorID != nil && f.ActorEmail != "":
+		q = q.Where("(actor_id = ? OR actor_email = ?)", *f.ActorID, f.ActorEmail)
+	case f.ActorID != nil:
+		q = q.Where("actor_id = ?", *f.ActorID)
+	case f.ActorEmail != "":
+		q = q.Where("actor_email = ?", f.ActorEmail)
+	}
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if f.Outcome != "" {
+		q = q.Where("outcome = ?", f.Outcome)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("created_at < ?", f.To)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []models.AuditEvent
+	if err := q.Order("created_at DESC, id DESC").Limit(f.Limit).Offset(f.Offset).Find(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
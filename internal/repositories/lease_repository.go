@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LeaseRepository interface {
+	// Acquire takes lease name for holder until expiresAt, or renews it, and
+	// reports whether holder now has it. It succeeds when the lease is free,
+	// expired at now or already held by holder.
+	Acquire(ctx context.Context, name, holder string, now, expiresAt time.Time) (bool, error)
+	// Release gives up lease name if holder has it.
+	Release(ctx context.Context, name, holder string) error
+	// Find returns lease name, or gorm.ErrRecordNotFound when it was never
+	// taken or has been released.
+	Find(ctx context.Context, name string) (*models.Lease, error)
+}
+
+type leaseRepository struct {
+	db *gorm.DB
+}
+
+func NewLeaseRepository(db *gorm.DB) LeaseRepository {
+	return &leaseRepository{db: db}
+}
+
+// Acquire is a conditional update followed, when no row matched, by an
+// insert that does nothing on conflict, so two instances racing for a free
+// lease cannot both win.
+func (r *leaseRepository) Acquire(ctx context.Context, name, holder string, now, expiresAt time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.Lease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": expiresAt, "renewed_at": now})
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error == nil, res.Error
+	}
+	lease := models.Lease{Name: name, Holder: holder, ExpiresAt: expiresAt, RenewedAt: now}
+	res = r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&lease)
+	return res.Error == nil && res.RowsAffected > 0, res.Error
+}
+
+func (r *leaseRepository) Release(ctx context.Context, name, holder string) error {
+	return r.db.WithContext(ctx).Where("name = ? AND holder = ?", name, holder).Delete(&models.Lease{}).Error
+}
+
+func (r *leaseRepository) Find(ctx context.Context, name string) (*models.Lease, error) {
+	var lease models.Lease
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&lease).Error; err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
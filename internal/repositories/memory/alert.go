@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// AlertRepository is an in-memory repositories.AlertRepository.
+type AlertRepository struct {
+	mu           sync.RWMutex
+	rules        map[uint]models.AlertRule
+	deliveries   map[uint]models.AlertDelivery
+	lastRule     uint
+	lastDelivery uint
+}
+
+var _ repositories.AlertRepository = (*AlertRepository)(nil)
+
+func NewAlertRepository() *AlertRepository {
+	return &AlertRepository{
+		rules:      map[uint]models.AlertRule{},
+		deliveries: map[uint]models.AlertDelivery{},
+	}
+}
+
+func (r *AlertRepository) CreateRule(ctx context.Context, rule *models.AlertRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastRule++
+	rule.ID = r.lastRule
+	r.rules[rule.ID] = *rule
+	return nil
+}
+
+func (r *AlertRepository) CountRules(ctx context.Context, userID uint) (int64, error) {
+	rules, _ := r.ListRules(ctx, userID)
+	return int64(len(rules)), nil
+}
+
+func (r *AlertRepository) ListRules(ctx context.Context, userID uint) ([]models.AlertRule, error) {
+	all, _ := r.AllRules(ctx)
+	var out []models.AlertRule
+	for _, rule := range all {
+		if rule.UserID == userID {
+			out = append(out, rule)
+		}
+	}
+	return out, nil
+}
+
+func (r *AlertRepository) FindRule(ctx context.Context, userID, id uint) (*models.AlertRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rule, ok := r.rules[id]
+	if !ok || rule.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rule, nil
+}
+
+func (r *AlertRepository) DeleteRule(ctx context.Context, userID, id uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rule, ok := r.rules[id]
+	if !ok || rule.UserID != userID {
+		return false, nil
+	}
+	delete(r.rules, id)
+	for did, d := range r.deliveries {
+		if d.RuleID == id {
+			delete(r.deliveries, did)
+		}
+	}
+	return true, nil
+}
+
+func (r *AlertRepository) AllRules(ctx context.Context) ([]models.AlertRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]models.AlertRule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		out = append(out, rule)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (r *AlertRepository) UpdateRuleState(ctx context.Context, rule *models.AlertRule, fired *models.AlertDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur, ok := r.rules[rule.ID]
+	if !ok {
+		return nil
+	}
+	cur.LastRate, cur.BaselineRate, cur.BaselineAt, cur.LastTriggeredAt = rule.LastRate, rule.BaselineRate, rule.BaselineAt, rule.LastTriggeredAt
+	r.rules[rule.ID] = cur
+	if fired != nil {
+		r.createDelivery(fired)
+	}
+	return nil
+}
+
+func (r *AlertRepository) CreateDelivery(ctx context.Context, d *models.AlertDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.createDelivery(d)
+	return nil
+}
+
+func (r *AlertRepository) createDelivery(d *models.AlertDelivery) {
+	r.lastDelivery++
+	d.ID = r.lastDelivery
+	r.deliveries[d.ID] = *d
+}
+
+func (r *AlertRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.AlertDelivery, error) {
+	r.mu.RLock()
+	var out []models.AlertDelivery
+	for _, d := range r.deliveries {
+		if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) {
+			out = append(out, d)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].NextAttemptAt.Equal(out[j].NextAttemptAt) {
+			return out[i].NextAttemptAt.Before(out[j].NextAttemptAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *AlertRepository) UpdateDelivery(ctx context.Context, d *models.AlertDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.deliveries[d.ID]; ok {
+		r.deliveries[d.ID] = *d
+	}
+	return nil
+}
+
+func (r *AlertRepository) ListDeliveries(ctx context.Context, f repositories.DeliveryFilter) ([]models.AlertDelivery, error) {
+	r.mu.RLock()
+	var out []models.AlertDelivery
+	for _, d := range r.deliveries {
+		if d.UserID != f.UserID || (f.RuleID != 0 && d.RuleID != f.RuleID) || (f.Status != "" && d.Status != f.Status) {
+			continue
+		}
+		out = append(out, d)
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	if len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+func (r *AlertRepository) FindDelivery(ctx context.Context, userID, id uint) (*models.AlertDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.deliveries[id]
+	if !ok || d.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &d, nil
+}
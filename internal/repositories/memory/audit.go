@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+)
+
+// AuditRepository is an in-memory repositories.AuditRepository.
+type AuditRepository struct {
+	mu     sync.RWMutex
+	events []models.AuditEvent
+}
+
+var _ repositories.AuditRepository = (*AuditRepository)(nil)
+
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{}
+}
+
+func (r *AuditRepository) Create(ctx context.Context, evt *models.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	evt.ID = uint(len(r.events) + 1)
+	r.events = append(r.events, *evt)
+	return nil
+}
+
+func (r *AuditRepository) List(ctx context.Context, f repositories.AuditFilter) ([]models.AuditEvent, int64, error) {
+	r.mu.RLock()
+	var matched []models.AuditEvent
+	for _, e := range r.events {
+		if matchesAudit(e, f) {
+			matched = append(matched, e)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].ID > matched[j].ID
+		}
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+	total := int64(len(matched))
+	return page(matched, f.Offset, f.Limit), total, nil
+}
+
+func matchesAudit(e models.AuditEvent, f repositories.AuditFilter) bool {
+	byID := f.ActorID != nil && e.ActorID != nil && *e.ActorID == *f.ActorID
+	byEmail := f.ActorEmail != "" && e.ActorEmail == f.ActorEmail
+	switch {
+	case f.ActorID != nil && f.ActorEmail != "":
+		if !byID && !byEmail {
+			return false
+		}
+	case f.ActorID != nil:
+		if !byID {
+			return false
+		}
+	case f.ActorEmail != "":
+		if !byEmail {
+			return false
+		}
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.Outcome != "" && e.Outcome != f.Outcome {
+		return false
+	}
+	if !f.From.IsZero() && e.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
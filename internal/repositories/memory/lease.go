@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// LeaseRepository is an in-memory repositories.LeaseRepository. Instances
+// sharing one compete for its leases like replicas sharing a database.
+type LeaseRepository struct {
+	mu     sync.Mutex
+	leases map[string]models.Lease
+}
+
+var _ repositories.LeaseRepository = (*LeaseRepository)(nil)
+
+func NewLeaseRepository() *LeaseRepository {
+	return &LeaseRepository{leases: map[string]models.Lease{}}
+}
+
+func (r *LeaseRepository) Acquire(ctx context.Context, name, holder string, now, expiresAt time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.leases[name]; ok && l.Holder != holder && !l.ExpiresAt.Before(now) {
+		return false, nil
+	}
+	r.leases[name] = models.Lease{Name: name, Holder: holder, ExpiresAt: expiresAt, RenewedAt: now}
+	return true, nil
+}
+
+func (r *LeaseRepository) Release(ctx context.Context, name, holder string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.leases[name]; ok && l.Holder == holder {
+		delete(r.leases, name)
+	}
+	return nil
+}
+
+func (r *LeaseRepository) Find(ctx context.Context, name string) (*models.Lease, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.leases[name]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &l, nil
+}
@@ -0,0 +1,60 @@
+// Package memory provides in-memory implementations of the repository
+// interfaces, for tests and for running the service without a database.
+package memory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spksupakorn/Currency-Converter/database"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// NewRepositories returns a fresh set of empty in-memory repositories.
+func NewRepositories() repositories.Repositories {
+	return repositories.Repositories{
+		Users:      NewUserRepository(),
+		Rates:      NewRateRepository(),
+		Overrides:  NewRateOverrideRepository(),
+		Quarantine: NewRateQuarantineRepository(),
+		Alerts:     NewAlertRepository(),
+		Outbox:     NewOutboxRepository(),
+		Leases:     NewLeaseRepository(),
+		Audit:      NewAuditRepository(),
+		Usage:      NewUsageRepository(),
+		Tx:         Transactor{},
+	}
+}
+
+// Database is a database.Database with no backing store. It is always
+// reachable and has nothing to migrate; pair it with NewRepositories.
+type Database struct{}
+
+var _ database.Database = Database{}
+
+func (Database) ConnectDB() *gorm.DB { return nil }
+
+func (Database) Reader() *gorm.DB { return nil }
+
+func (Database) MigrateDB() error { return nil }
+
+func (Database) Migrator() (*database.Migrator, error) {
+	return nil, errors.New("in-memory database has no migrations")
+}
+
+func (Database) Ping(ctx context.Context) error { return ctx.Err() }
+
+func (Database) Notifier() database.Notifier { return nil }
+
+func (Database) Close() error { return nil }
+
+// Transactor runs functions directly: the in-memory repositories apply each
+// write at once and have nothing to roll back.
+type Transactor struct{}
+
+var _ repositories.Transactor = Transactor{}
+
+func (Transactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+)
+
+// OutboxRepository is an in-memory repositories.OutboxRepository.
+type OutboxRepository struct {
+	mu     sync.RWMutex
+	events []models.OutboxEvent
+}
+
+var _ repositories.OutboxRepository = (*OutboxRepository)(nil)
+
+func NewOutboxRepository() *OutboxRepository {
+	return &OutboxRepository{}
+}
+
+func (r *OutboxRepository) Add(ctx context.Context, evt *models.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var last uint
+	if n := len(r.events); n > 0 {
+		last = r.events[n-1].ID
+	}
+	evt.ID = last + 1
+	r.events = append(r.events, *evt)
+	return nil
+}
+
+func (r *OutboxRepository) Pending(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.OutboxEvent
+	for _, evt := range r.events {
+		if evt.PublishedAt == nil && len(out) < limit {
+			out = append(out, evt)
+		}
+	}
+	return out, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id uint, at time.Time) error {
+	r.update(id, func(evt *models.OutboxEvent) {
+		evt.PublishedAt, evt.LastError = &at, ""
+	})
+	return nil
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uint, reason string) error {
+	r.update(id, func(evt *models.OutboxEvent) {
+		evt.Attempts++
+		evt.LastError = reason
+	})
+	return nil
+}
+
+func (r *OutboxRepository) update(id uint, fn func(*models.OutboxEvent)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.events {
+		if r.events[i].ID == id {
+			fn(&r.events[i])
+			return
+		}
+	}
+}
+
+func (r *OutboxRepository) DeletePublishedBefore(ctx context.Context, t time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.events[:0]
+	for _, evt := range r.events {
+		if evt.PublishedAt == nil || !evt.PublishedAt.Before(t) {
+			kept = append(kept, evt)
+		}
+	}
+	n := int64(len(r.events) - len(kept))
+	r.events = kept
+	return n, nil
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+)
+
+// RateRepository is an in-memory repositories.RateRepository.
+type RateRepository struct {
+	mu   sync.RWMutex
+	rows map[string]rateRow
+}
+
+type rateRow struct {
+	rate      float64
+	base      string
+	provider  string
+	updatedAt time.Time
+}
+
+var _ repositories.RateRepository = (*RateRepository)(nil)
+
+func NewRateRepository() *RateRepository {
+	return &RateRepository{rows: map[string]rateRow{}}
+}
+
+func (r *RateRepository) UpsertRates(ctx context.Context, rates repositories.StoredRates) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for cur, val := range rates.Rates {
+		r.rows[cur] = rateRow{rate: val, base: rates.Base, provider: rates.Provider, updatedAt: rates.UpdatedAt}
+	}
+	return nil
+}
+
+func (r *RateRepository) GetAllRates(ctx context.Context) (repositories.StoredRates, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out repositories.StoredRates
+	for _, row := range r.rows {
+		if row.updatedAt.After(out.UpdatedAt) {
+			out.Base, out.Provider, out.UpdatedAt = row.base, row.provider, row.updatedAt
+		}
+	}
+	out.Rates = make(map[string]float64, len(r.rows))
+	for cur, row := range r.rows {
+		if row.base == out.Base {
+			out.Rates[cur] = row.rate
+		}
+	}
+	return out, nil
+}
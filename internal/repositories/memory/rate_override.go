@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+)
+
+// RateOverrideRepository is an in-memory repositories.RateOverrideRepository.
+type RateOverrideRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	rows   map[string]models.RateOverride
+}
+
+var _ repositories.RateOverrideRepository = (*RateOverrideRepository)(nil)
+
+func NewRateOverrideRepository() *RateOverrideRepository {
+	return &RateOverrideRepository{rows: map[string]models.RateOverride{}}
+}
+
+func (r *RateOverrideRepository) Upsert(ctx context.Context, o *models.RateOverride) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.rows[o.Currency]; ok {
+		o.ID = old.ID
+	} else {
+		r.nextID++
+		o.ID = r.nextID
+	}
+	r.rows[o.Currency] = *o
+	return nil
+}
+
+func (r *RateOverrideRepository) Delete(ctx context.Context, currency string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.rows[currency]
+	delete(r.rows, currency)
+	return ok, nil
+}
+
+func (r *RateOverrideRepository) ListActive(ctx context.Context, now time.Time) ([]models.RateOverride, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.RateOverride
+	for _, o := range r.rows {
+		if o.ExpiresAt.After(now) {
+			out = append(out, o)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Currency < out[j].Currency })
+	return out, nil
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// RateQuarantineRepository is an in-memory
+// repositories.RateQuarantineRepository.
+type RateQuarantineRepository struct {
+	mu   sync.RWMutex
+	rows []models.RateQuarantine
+}
+
+var _ repositories.RateQuarantineRepository = (*RateQuarantineRepository)(nil)
+
+func NewRateQuarantineRepository() *RateQuarantineRepository {
+	return &RateQuarantineRepository{}
+}
+
+func (r *RateQuarantineRepository) Create(ctx context.Context, q *models.RateQuarantine) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.supersedePending()
+	q.ID = uint(len(r.rows) + 1)
+	q.Status = models.QuarantinePending
+	r.rows = append(r.rows, *q)
+	return nil
+}
+
+func (r *RateQuarantineRepository) FindByID(ctx context.Context, id uint) (*models.RateQuarantine, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if id == 0 || int(id) > len(r.rows) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	q := r.rows[id-1]
+	return &q, nil
+}
+
+func (r *RateQuarantineRepository) List(ctx context.Context, status string, limit int) ([]models.RateQuarantine, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.RateQuarantine
+	for i := len(r.rows) - 1; i >= 0 && len(out) < limit; i-- {
+		if status == "" || r.rows[i].Status == status {
+			out = append(out, r.rows[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *RateQuarantineRepository) Decide(ctx context.Context, id uint, d repositories.QuarantineDecision) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.rows) || r.rows[id-1].Status != models.QuarantinePending {
+		return false, nil
+	}
+	q := &r.rows[id-1]
+	at := d.At
+	q.Status, q.DecidedByID, q.DecidedByEmail, q.DecidedAt = d.Status, d.ByID, d.ByEmail, &at
+	return true, nil
+}
+
+func (r *RateQuarantineRepository) SupersedePending(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.supersedePending()
+	return nil
+}
+
+func (r *RateQuarantineRepository) supersedePending() {
+	for i := range r.rows {
+		if r.rows[i].Status == models.QuarantinePending {
+			r.rows[i].Status = models.QuarantineSuperseded
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+)
+
+// UsageRepository is an in-memory repositories.UsageRepository.
+type UsageRepository struct {
+	mu      sync.RWMutex
+	records []models.UsageRecord
+}
+
+var _ repositories.UsageRepository = (*UsageRepository)(nil)
+
+func NewUsageRepository() *UsageRepository {
+	return &UsageRepository{}
+}
+
+func (r *UsageRepository) CreateBatch(ctx context.Context, records []models.UsageRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rec := range records {
+		rec.ID = uint(len(r.records) + 1)
+		r.records = append(r.records, rec)
+	}
+	return nil
+}
+
+func (r *UsageRepository) Summarize(ctx context.Context, f repositories.UsageFilter) ([]models.UsageSummary, error) {
+	byDay := slices.Contains(f.GroupBy, repositories.UsageGroupDay)
+	byPair := slices.Contains(f.GroupBy, repositories.UsageGroupPair)
+	byUser := slices.Contains(f.GroupBy, repositories.UsageGroupUser)
+
+	r.mu.RLock()
+	groups := map[models.UsageSummary]*models.UsageSummary{}
+	for _, rec := range r.records {
+		if f.UserID != nil && rec.UserID != *f.UserID {
+			continue
+		}
+		if !f.From.IsZero() && rec.CreatedAt.Before(f.From) {
+			continue
+		}
+		if !f.To.IsZero() && !rec.CreatedAt.Before(f.To) {
+			continue
+		}
+		var key models.UsageSummary
+		if byDay {
+			key.Day = rec.CreatedAt.UTC().Format(time.DateOnly)
+		}
+		if byPair {
+			key.FromCurrency, key.ToCurrency = rec.FromCurrency, rec.ToCurrency
+		}
+		if byUser {
+			key.UserID = rec.UserID
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &models.UsageSummary{Day: key.Day, FromCurrency: key.FromCurrency, ToCurrency: key.ToCurrency, UserID: key.UserID}
+			groups[key] = g
+		}
+		g.Count++
+		if byPair {
+			if g.TotalAmount == nil {
+				g.TotalAmount = new(float64)
+			}
+			*g.TotalAmount += rec.Amount
+		}
+	}
+	r.mu.RUnlock()
+
+	out := make([]models.UsageSummary, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.FromCurrency != b.FromCurrency {
+			return a.FromCurrency < b.FromCurrency
+		}
+		if a.ToCurrency != b.ToCurrency {
+			return a.ToCurrency < b.ToCurrency
+		}
+		return a.UserID < b.UserID
+	})
+	return out, nil
+}
+
+func (r *UsageRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.records[:0]
+	var deleted int64
+	for _, rec := range r.records {
+		if rec.CreatedAt.Before(t) {
+			deleted++
+			continue
+		}
+		kept = append(kept, rec)
+	}
+	r.records = kept
+	return deleted, nil
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// UserRepository is an in-memory repositories.UserRepository. Lookups that
+// find nothing return gorm.ErrRecordNotFound, like the GORM implementation.
+type UserRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	users  map[uint]models.User
+}
+
+var _ repositories.UserRepository = (*UserRepository)(nil)
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{users: map[uint]models.User{}}
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.find(func(u models.User) bool { return u.Email == email })
+}
+
+func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.find(func(u models.User) bool { return u.Username == username })
+}
+
+func (r *UserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &u, nil
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Email == user.Email || (user.Username != "" && u.Username == user.Username) {
+			return errors.New("duplicate key value violates unique constraint")
+		}
+	}
+	r.nextID++
+	now := time.Now()
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *UserRepository) IncrementTokenVersion(ctx context.Context, userID uint) error {
+	r.bumpTokenVersion(userID, nil)
+	return nil
+}
+
+func (r *UserRepository) IssueToken(ctx context.Context, userID uint, at time.Time) error {
+	at = at.UTC()
+	r.bumpTokenVersion(userID, &at)
+	return nil
+}
+
+func (r *UserRepository) bumpTokenVersion(userID uint, issuedAt *time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok {
+		// Matches an UPDATE that affects no rows.
+		return
+	}
+	u.TokenVersion++
+	u.TokenIssuedAt = issuedAt
+	u.UpdatedAt = time.Now()
+	r.users[userID] = u
+}
+
+func (r *UserRepository) SetRole(ctx context.Context, userID uint, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[userID]; ok {
+		u.Role = role
+		u.UpdatedAt = time.Now()
+		r.users[userID] = u
+	}
+	return nil
+}
+
+func (r *UserRepository) find(match func(models.User) bool) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, u := range r.users {
+		if match(u) {
+			return &u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"gorm.io/gorm"
+)
+
+type OutboxRepository interface {
+	// Add stores evt, in the transaction of ctx when there is one.
+	Add(ctx context.Context, evt *models.OutboxEvent) error
+	// Pending returns unpublished events in the order they were added.
+	Pending(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id uint, at time.Time) error
+	// MarkFailed counts a failed delivery attempt of event id.
+	MarkFailed(ctx context.Context, id uint, reason string) error
+	DeletePublishedBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Add(ctx context.Context, evt *models.OutboxEvent) error {
+	return conn(ctx, r.db).Create(evt).Error
+}
+
+func (r *outboxRepository) Pending(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	var out []models.OutboxEvent
+	err := r.db.WithContext(ctx).Where("published_at IS NULL").Order("id").Limit(limit).Find(&out).Error
+	return out, err
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"published_at": at, "last_error": ""}).Error
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id uint, reason string) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"attempts": gorm.Expr("attempts + 1"), "last_error": reason}).Error
+}
+
+func (r *outboxRepository) DeletePublishedBefore(ctx context.Context, t time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("published_at < ?", t).Delete(&models.OutboxEvent{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RateOverrideRepository interface {
+	// Upsert stores o, replacing any override for the same currency.
+	Upsert(ctx context.Context, o *models.RateOverride) error
+	// Delete removes the override for currency and reports whether there was
+	// one.
+	Delete(ctx context.Context, currency string) (bool, error)
+	// ListActive returns the overrides that have not expired at now, by
+	// currency.
+	ListActive(ctx context.Context, now time.Time) ([]models.RateOverride, error)
+}
+
+type rateOverrideRepository struct {
+	db *gorm.DB
+}
+
+func NewRateOverrideRepository(db *gorm.DB) RateOverrideRepository {
+	return &rateOverrideRepository{db: db}
+}
+
+func (r *rateOverrideRepository) Upsert(ctx context.Context, o *models.RateOverride) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"base", "rate", "reason", "expires_at", "created_by_id", "created_by_email", "created_at"}),
+	}).Create(o).Error
+}
+
+func (r *rateOverrideRepository) Delete(ctx context.Context, currency string) (bool, error) {
+	res := r.db.WithContext(ctx).Where("currency = ?", currency).Delete(&models.RateOverride{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// ListActive always reads the primary: an override an admin just set must be
+// applied by the refresh that follows.
+func (r *rateOverrideRepository) ListActive(ctx context.Context, now time.Time) ([]models.RateOverride, error) {
+	var out []models.RateOverride
+	err := r.db.WithContext(ctx).
+		Where("expires_at > ?", now).
+		Order("currency").
+		Find(&out).Error
+	return out, err
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"gorm.io/gorm"
+)
+
+// QuarantineDecision records who moved a pending quarantine to Status.
+type QuarantineDecision struct {
+	Status  string
+	ByID    *uint
+	ByEmail string
+	At      time.Time
+}
+
+type RateQuarantineRepository interface {
+	// Create stores q as pending and supersedes every other pending
+	// quarantine.
+	Create(ctx context.Context, q *models.RateQuarantine) error
+	// FindByID returns gorm.ErrRecordNotFound when there is no such
+	// quarantine.
+	FindByID(ctx context.Context, id uint) (*models.RateQuarantine, error)
+	// List returns quarantines with status, or all when empty, newest first.
+	List(ctx context.Context, status string, limit int) ([]models.RateQuarantine, error)
+	// Decide moves quarantine id out of pending and reports whether it was
+	// still pending.
+	Decide(ctx context.Context, id uint, d QuarantineDecision) (bool, error)
+	// SupersedePending marks every pending quarantine superseded, after newer
+	// rates were published.
+	SupersedePending(ctx context.Context) error
+}
+
+type rateQuarantineRepository struct {
+	db *gorm.DB
+}
+
+func NewRateQuarantineRepository(db *gorm.DB) RateQuarantineRepository {
+	return &rateQuarantineRepository{db: db}
+}
+
+func (r *rateQuarantineRepository) Create(ctx context.Context, q *models.RateQuarantine) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := supersedePending(tx); err != nil {
+			return err
+		}
+		q.Status = models.QuarantinePending
+		return tx.Create(q).Error
+	})
+}
+
+func (r *rateQuarantineRepository) FindByID(ctx context.Context, id uint) (*models.RateQuarantine, error) {
+	var q models.RateQuarantine
+	if err := conn(ctx, r.db).First(&q, id).Error; err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+func (r *rateQuarantineRepository) List(ctx context.Context, status string, limit int) ([]models.RateQuarantine, error) {
+	q := r.db.WithContext(ctx).Order("id DESC").Limit(limit)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var out []models.RateQuarantine
+	err := q.Find(&out).Error
+	return out, err
+}
+
+func (r *rateQuarantineRepository) Decide(ctx context.Context, id uint, d QuarantineDecision) (bool, error) {
+	res := conn(ctx, r.db).Model(&models.RateQuarantine{}).
+		Where("id = ? AND status = ?", id, models.QuarantinePending).
+		Updates(map[string]interface{}{
+			"status":           d.Status,
+			"decided_by_id":    d.ByID,
+			"decided_by_email": d.ByEmail,
+			"decided_at":       d.At,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *rateQuarantineRepository) SupersedePending(ctx context.Context) error {
+	return supersedePending(conn(ctx, r.db))
+}
+
+func supersedePending(db *gorm.DB) error {
+	return db.Model(&models.RateQuarantine{}).
+		Where("status = ?", models.QuarantinePending).
+		Update("status", models.QuarantineSuperseded).Error
+}
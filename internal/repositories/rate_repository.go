@@ -1,59 +1,101 @@
-package repositories
-
-import (
-	"time"
-
-	"github.com/spksupakorn/Currency-Converter/internal/models"
-	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
-)
-
-type RateRepository interface {
-	UpsertRates(rates map[string]float64, now time.Time) error
-	GetAllRates() (map[string]float64, time.Time, error)
-}
-
-type rateRepository struct {
-	db *gorm.DB
-}
-
-func NewRateRepository(db *gorm.DB) RateRepository {
-	return &rateRepository{db: db}
-}
-
-func (r *rateRepository) UpsertRates(rates map[string]float64, now time.Time) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		for cur, val := range rates {
-			rt := models.Rate{
-				Currency:  cur,
-				Rate:      val,
-				UpdatedAt: now,
-			}
-			if err := tx.Clauses(
-				clause.OnConflict{
-					Columns:   []clause.Column{{Name: "currency"}},
-					DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
-				},
-			).Create(&rt).Error; err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-}
-
-func (r *rateRepository) GetAllRates() (map[string]float64, time.Time, error) {
-	var rows []models.Rate
-	if err := r.db.Find(&rows).Error; err != nil {
-		return nil, time.Time{}, err
-	}
-	out := make(map[string]float64, len(rows))
-	var latest time.Time
-	for _, rr := range rows {
-		out[rr.Currency] = rr.Rate
-		if rr.UpdatedAt.After(latest) {
-			latest = rr.UpdatedAt
-		}
-	}
-	return out, latest, nil
-}
+package repositories
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StoredRates is one rate table as written by UpsertRates: every rate is the
+// price of a currency in Base, as fetched from Provider at UpdatedAt.
+type StoredRates struct {
+	Base      string
+	Provider  string
+	Rates     map[string]float64
+	UpdatedAt time.Time
+}
+
+type RateRepository interface {
+	UpsertRates(ctx context.Context, rates StoredRates) error
+	// GetAllRates returns the rows quoted in the base of the most recent
+	// write. Base is empty for rows stored before it was recorded.
+	GetAllRates(ctx context.Context) (StoredRates, error)
+}
+
+type rateRepository struct {
+	db     *gorm.DB
+	reader *gorm.DB
+}
+
+// NewRateRepository serves GetAllRates from reader when it is not nil.
+func NewRateRepository(db, reader *gorm.DB) RateRepository {
+	return &rateRepository{db: db, reader: reader}
+}
+
+// rateUpsertBatch bounds the rows per INSERT so that the bind parameters stay
+// well below what Postgres (65535) and SQLite (32766) accept.
+const rateUpsertBatch = 1000
+
+// UpsertRates writes the table with one multi-row INSERT ... ON CONFLICT
+// per rateUpsertBatch currencies, instead of a round trip per currency. Rows
+// are sorted so concurrent writers lock them in the same order.
+func (r *rateRepository) UpsertRates(ctx context.Context, rates StoredRates) error {
+	if len(rates.Rates) == 0 {
+		return nil
+	}
+	rows := make([]models.Rate, 0, len(rates.Rates))
+	for cur, val := range rates.Rates {
+		rows = append(rows, models.Rate{
+			Currency:  cur,
+			Rate:      val,
+			Base:      rates.Base,
+			Provider:  rates.Provider,
+			UpdatedAt: rates.UpdatedAt,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Currency < rows[j].Currency })
+	return conn(ctx, r.db).Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "currency"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rate", "base", "provider", "updated_at"}),
+		},
+	).CreateInBatches(&rows, rateUpsertBatch).Error
+}
+
+// GetAllRates selects only the rows in the base of the newest one, in a
+// single query.
+func (r *rateRepository) GetAllRates(ctx context.Context) (StoredRates, error) {
+	db := readerFor(ctx, r.db, r.reader)
+	newest := db.Session(&gorm.Session{NewDB: true}).Model(&models.Rate{}).
+		Select("base").Order("updated_at DESC").Limit(1)
+	var rows []models.Rate
+	if err := db.Where("base = (?)", newest).Find(&rows).Error; err != nil {
+		return StoredRates{}, err
+	}
+	return latestRates(rows), nil
+}
+
+// latestRates keeps the rows quoted in the same base as the newest row:
+// currencies left over from a fetch under another base are not comparable.
+// GetAllRates already filters them out in SQL; this also dates the table.
+func latestRates(rows []models.Rate) StoredRates {
+	var out StoredRates
+	for _, rr := range rows {
+		if rr.UpdatedAt.After(out.UpdatedAt) {
+			out.UpdatedAt = rr.UpdatedAt
+			out.Base = rr.Base
+			out.Provider = rr.Provider
+		}
+	}
+	out.Rates = make(map[string]float64, len(rows))
+	for _, rr := range rows {
+		if rr.Base == out.Base {
+			out.Rates[rr.Currency] = rr.Rate
+		}
+	}
+	return out
+}
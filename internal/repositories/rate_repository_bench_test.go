@@ -0,0 +1,129 @@
+package repositories_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// benchTable returns a provider-sized table of 160 currencies.
+func benchTable(at time.Time) repositories.StoredRates {
+	rates := map[string]float64{"USD": 1, "EUR": 0.92, "THB": 36.5}
+	for i := 0; len(rates) < 160; i++ {
+		rates[fmt.Sprintf("%c%c%c", 'A'+i/26%26, 'A'+i%26, 'X')] = 1 + float64(i)/10
+	}
+	return repositories.StoredRates{Base: "USD", Provider: "bench", Rates: rates, UpdatedAt: at}
+}
+
+// upsertPerRow is how UpsertRates wrote a table before it used one
+// statement: an upsert per currency inside a transaction. It is kept as the
+// baseline for BenchmarkUpsertRates.
+func upsertPerRow(db *gorm.DB, rates repositories.StoredRates) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for cur, val := range rates.Rates {
+			rt := models.Rate{Currency: cur, Rate: val, Base: rates.Base, Provider: rates.Provider, UpdatedAt: rates.UpdatedAt}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "currency"}},
+				DoUpdates: clause.AssignmentColumns([]string{"rate", "base", "provider", "updated_at"}),
+			}).Create(&rt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// loadAllRows is how GetAllRates read a table before it filtered by the
+// newest base in SQL: every row, grouped in Go.
+func loadAllRows(db *gorm.DB) (map[string]float64, error) {
+	var rows []models.Rate
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	var newest models.Rate
+	for _, r := range rows {
+		if r.UpdatedAt.After(newest.UpdatedAt) {
+			newest = r
+		}
+	}
+	out := make(map[string]float64, len(rows))
+	for _, r := range rows {
+		if r.Base == newest.Base {
+			out[r.Currency] = r.Rate
+		}
+	}
+	return out, nil
+}
+
+// BenchmarkUpsertRates writes a whole table over the previous one, as every
+// refresh does.
+func BenchmarkUpsertRates(b *testing.B) {
+	ctx := context.Background()
+	writers := []struct {
+		name  string
+		write func(db *gorm.DB, repo repositories.RateRepository, t repositories.StoredRates) error
+	}{
+		{"OneStatement", func(db *gorm.DB, repo repositories.RateRepository, t repositories.StoredRates) error {
+			return repo.UpsertRates(ctx, t)
+		}},
+		{"StatementPerCurrency", func(db *gorm.DB, repo repositories.RateRepository, t repositories.StoredRates) error {
+			return upsertPerRow(db, t)
+		}},
+	}
+	for _, w := range writers {
+		b.Run(w.name, func(b *testing.B) {
+			db := openSQLite(b)
+			repo := repositories.NewRateRepository(db, nil)
+			table := benchTable(time.Now().UTC())
+			if err := w.write(db, repo, table); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				table.UpdatedAt = table.UpdatedAt.Add(time.Second)
+				if err := w.write(db, repo, table); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetAllRates(b *testing.B) {
+	ctx := context.Background()
+	db := openSQLite(b)
+	repo := repositories.NewRateRepository(db, nil)
+	if err := repo.UpsertRates(ctx, benchTable(time.Now().UTC())); err != nil {
+		b.Fatal(err)
+	}
+	readers := []struct {
+		name string
+		read func() (int, error)
+	}{
+		{"NewestBaseInSQL", func() (int, error) {
+			got, err := repo.GetAllRates(ctx)
+			return len(got.Rates), err
+		}},
+		{"AllRowsGroupedInGo", func() (int, error) {
+			got, err := loadAllRows(db.WithContext(ctx))
+			return len(got), err
+		}},
+	}
+	for _, r := range readers {
+		b.Run(r.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if n, err := r.read(); err != nil || n != 160 {
+					b.Fatalf("got %d rates, %v", n, err)
+				}
+			}
+		})
+	}
+}
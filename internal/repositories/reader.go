@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type readPrimaryKey struct{}
+
+// ReadPrimary marks ctx so that repositories read from the primary even for
+// queries normally served by the read replica. Use it when a caller must see
+// its own recent writes.
+func ReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPrimaryKey{}, true)
+}
+
+// readerFor picks the replica for ctx unless ReadPrimary was requested or no
+// replica is configured.
+func readerFor(ctx context.Context, primary, replica *gorm.DB) *gorm.DB {
+	if replica == nil {
+		return primary.WithContext(ctx)
+	}
+	if isReadPrimary(ctx) {
+		return primary.WithContext(ctx)
+	}
+	return replica.WithContext(ctx)
+}
+
+func isReadPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(readPrimaryKey{}).(bool)
+	return v
+}
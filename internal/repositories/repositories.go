@@ -0,0 +1,37 @@
+package repositories
+
+import "gorm.io/gorm"
+
+// Repositories groups every repository the application needs, so alternative
+// implementations (e.g. in-memory ones for tests) can be swapped in together.
+type Repositories struct {
+	Users      UserRepository
+	Rates      RateRepository
+	Overrides  RateOverrideRepository
+	Quarantine RateQuarantineRepository
+	Alerts     AlertRepository
+	Outbox     OutboxRepository
+	Leases     LeaseRepository
+	Audit      AuditRepository
+	Usage      UsageRepository
+
+	// Tx runs a function in a transaction across these repositories.
+	Tx Transactor
+}
+
+// NewRepositories builds the GORM repositories. Lag-tolerant reads go to
+// reader, which may be the same handle as db.
+func NewRepositories(db, reader *gorm.DB) Repositories {
+	return Repositories{
+		Users:      NewUserRepository(db, reader),
+		Rates:      NewRateRepository(db, reader),
+		Overrides:  NewRateOverrideRepository(db),
+		Quarantine: NewRateQuarantineRepository(db),
+		Alerts:     NewAlertRepository(db),
+		Outbox:     NewOutboxRepository(db),
+		Leases:     NewLeaseRepository(db),
+		Audit:      NewAuditRepository(db),
+		Usage:      NewUsageRepository(db),
+		Tx:         NewTransactor(db),
+	}
+}
@@ -0,0 +1,462 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/database"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// openSQLite returns a migrated SQLite database in a per-test temp dir.
+func openSQLite(t testing.TB) *gorm.DB {
+	t.Helper()
+	cfg := config.Config{DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "test.db")}
+	db, err := database.New(cfg, logger.New(logger.Options{Level: "error"}))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.MigrateDB(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db.ConnectDB()
+}
+
+func TestRateRepositoryUpsertRates(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	repo := repositories.NewRateRepository(db, db)
+
+	first := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := repo.UpsertRates(ctx, repositories.StoredRates{Base: "USD", Rates: map[string]float64{"USD": 1, "THB": 36.5}, UpdatedAt: first}); err != nil {
+		t.Fatalf("first upsert: %v", err)
+	}
+	second := first.Add(time.Hour)
+	if err := repo.UpsertRates(ctx, repositories.StoredRates{Base: "USD", Provider: "test", Rates: map[string]float64{"THB": 35.9, "EUR": 0.92}, UpdatedAt: second}); err != nil {
+		t.Fatalf("second upsert: %v", err)
+	}
+
+	stored, err := repo.GetAllRates(ctx)
+	if err != nil {
+		t.Fatalf("get rates: %v", err)
+	}
+	rates, updatedAt := stored.Rates, stored.UpdatedAt
+	if stored.Base != "USD" || stored.Provider != "test" {
+		t.Errorf("base %q, provider %q, want USD and test", stored.Base, stored.Provider)
+	}
+	want := map[string]float64{"USD": 1, "THB": 35.9, "EUR": 0.92}
+	if len(rates) != len(want) {
+		t.Fatalf("got %d rates, want %d: %v", len(rates), len(want), rates)
+	}
+	for cur, v := range want {
+		if rates[cur] != v {
+			t.Errorf("rate %s = %v, want %v", cur, rates[cur], v)
+		}
+	}
+	if !updatedAt.Equal(second) {
+		t.Errorf("updatedAt = %v, want %v", updatedAt, second)
+	}
+}
+
+func TestUserRepositoryIncrementTokenVersion(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	repo := repositories.NewUserRepository(db, db)
+
+	u := &models.User{Username: "alice", Email: "alice@example.com", Password: "x", Role: models.RoleUser}
+	if err := repo.Create(ctx, u); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := repo.IncrementTokenVersion(ctx, u.ID); err != nil {
+			t.Fatalf("increment: %v", err)
+		}
+	}
+
+	got, err := repo.FindByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if got.TokenVersion != 2 {
+		t.Errorf("TokenVersion = %d, want 2", got.TokenVersion)
+	}
+}
+
+func TestUserRepositoryIssueToken(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	repo := repositories.NewUserRepository(db, db)
+
+	u := &models.User{Username: "alice", Email: "alice@example.com", Password: "x", Role: models.RoleUser}
+	if err := repo.Create(ctx, u); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	issued := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := repo.IssueToken(ctx, u.ID, issued); err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	got, err := repo.FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if got.TokenVersion != 1 || got.TokenIssuedAt == nil || !got.TokenIssuedAt.Equal(issued) {
+		t.Errorf("after issue: version %d, issued at %v, want 1 and %v", got.TokenVersion, got.TokenIssuedAt, issued)
+	}
+
+	// Revoking clears the issue time along with the version bump.
+	if err := repo.IncrementTokenVersion(ctx, u.ID); err != nil {
+		t.Fatalf("increment: %v", err)
+	}
+	if got, err = repo.FindByID(ctx, u.ID); err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if got.TokenVersion != 2 || got.TokenIssuedAt != nil {
+		t.Errorf("after revoke: version %d, issued at %v, want 2 and none", got.TokenVersion, got.TokenIssuedAt)
+	}
+}
+
+func TestUsageRepositorySummarizeTotalsOnlyByPair(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewUsageRepository(openSQLite(t))
+	now := time.Now().UTC()
+	records := []models.UsageRecord{
+		{UserID: 1, FromCurrency: "USD", ToCurrency: "THB", Amount: 10, Rate: 36, SnapshotAt: now, CreatedAt: now},
+		{UserID: 1, FromCurrency: "USD", ToCurrency: "THB", Amount: 5, Rate: 36, SnapshotAt: now, CreatedAt: now},
+		{UserID: 1, FromCurrency: "JPY", ToCurrency: "THB", Amount: 1000, Rate: 0.23, SnapshotAt: now, CreatedAt: now},
+	}
+	if err := repo.CreateBatch(ctx, records); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	byPair, err := repo.Summarize(ctx, repositories.UsageFilter{GroupBy: []string{repositories.UsageGroupPair}})
+	if err != nil {
+		t.Fatalf("summarize by pair: %v", err)
+	}
+	want := map[string]float64{"JPY": 1000, "USD": 15}
+	if len(byPair) != len(want) {
+		t.Fatalf("by pair = %+v, want %d rows", byPair, len(want))
+	}
+	for _, row := range byPair {
+		if row.TotalAmount == nil || *row.TotalAmount != want[row.FromCurrency] {
+			t.Errorf("%s total = %v, want %v", row.FromCurrency, row.TotalAmount, want[row.FromCurrency])
+		}
+	}
+
+	byUser, err := repo.Summarize(ctx, repositories.UsageFilter{GroupBy: []string{repositories.UsageGroupUser}})
+	if err != nil {
+		t.Fatalf("summarize by user: %v", err)
+	}
+	if len(byUser) != 1 || byUser[0].Count != 3 || byUser[0].TotalAmount != nil {
+		t.Errorf("by user = %+v, want 3 conversions and no total", byUser)
+	}
+}
+
+func TestRateRepositoryCancelledContext(t *testing.T) {
+	db := openSQLite(t)
+	repo := repositories.NewRateRepository(db, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := repo.UpsertRates(ctx, repositories.StoredRates{Base: "USD", Rates: map[string]float64{"USD": 1}, UpdatedAt: time.Now()}); err == nil {
+		t.Fatal("upsert with cancelled context succeeded")
+	}
+	if _, err := repo.GetAllRates(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("get rates error = %v, want context.Canceled", err)
+	}
+}
+
+func TestRateRepositoryReadsFromReplica(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := openSQLite(t), openSQLite(t)
+	repo := repositories.NewRateRepository(primary, replica)
+
+	if err := repo.UpsertRates(ctx, repositories.StoredRates{Base: "USD", Rates: map[string]float64{"USD": 1}, UpdatedAt: time.Now()}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	stored, err := repo.GetAllRates(ctx)
+	if err != nil {
+		t.Fatalf("get rates: %v", err)
+	}
+	if len(stored.Rates) != 0 {
+		t.Errorf("replica read returned %v, want no rates", stored.Rates)
+	}
+	stored, err = repo.GetAllRates(repositories.ReadPrimary(ctx))
+	if err != nil {
+		t.Fatalf("get rates from primary: %v", err)
+	}
+	if stored.Rates["USD"] != 1 {
+		t.Errorf("primary read returned %v, want USD=1", stored.Rates)
+	}
+}
+
+func TestRateRepositoryKeepsLatestBase(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	repo := repositories.NewRateRepository(db, db)
+
+	first := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := repo.UpsertRates(ctx, repositories.StoredRates{Base: "USD", Rates: map[string]float64{"USD": 1, "THB": 36.5, "XAU": 0.0004}, UpdatedAt: first}); err != nil {
+		t.Fatalf("usd upsert: %v", err)
+	}
+	// The base changed and the provider no longer quotes XAU.
+	if err := repo.UpsertRates(ctx, repositories.StoredRates{Base: "EUR", Rates: map[string]float64{"EUR": 1, "USD": 1.09, "THB": 39.7}, UpdatedAt: first.Add(time.Hour)}); err != nil {
+		t.Fatalf("eur upsert: %v", err)
+	}
+
+	stored, err := repo.GetAllRates(ctx)
+	if err != nil {
+		t.Fatalf("get rates: %v", err)
+	}
+	if stored.Base != "EUR" {
+		t.Errorf("base = %q, want EUR", stored.Base)
+	}
+	if _, ok := stored.Rates["XAU"]; ok || len(stored.Rates) != 3 {
+		t.Errorf("rates = %v, want only the EUR-based rows", stored.Rates)
+	}
+}
+
+func TestRateOverrideRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewRateOverrideRepository(openSQLite(t))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, o := range []models.RateOverride{
+		{Currency: "THB", Base: "USD", Rate: 35, Reason: "first", ExpiresAt: now.Add(time.Hour), CreatedAt: now},
+		{Currency: "EUR", Base: "USD", Rate: 0.9, Reason: "expired", ExpiresAt: now.Add(-time.Minute), CreatedAt: now},
+		{Currency: "THB", Base: "USD", Rate: 36, Reason: "replaced", ExpiresAt: now.Add(2 * time.Hour), CreatedAt: now},
+	} {
+		if err := repo.Upsert(ctx, &o); err != nil {
+			t.Fatalf("upsert %s: %v", o.Currency, err)
+		}
+	}
+
+	active, err := repo.ListActive(ctx, now)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(active) != 1 || active[0].Currency != "THB" || active[0].Rate != 36 || active[0].Reason != "replaced" {
+		t.Fatalf("active overrides = %+v, want only the replaced THB override", active)
+	}
+
+	if found, err := repo.Delete(ctx, "THB"); err != nil || !found {
+		t.Fatalf("delete THB: found %v, err %v", found, err)
+	}
+	if found, err := repo.Delete(ctx, "THB"); err != nil || found {
+		t.Errorf("delete THB again: found %v, err %v", found, err)
+	}
+}
+
+func TestRateQuarantineRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewRateQuarantineRepository(openSQLite(t))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newQuarantine := func(thb float64) *models.RateQuarantine {
+		return &models.RateQuarantine{
+			Base:      "USD",
+			Provider:  "test",
+			Rates:     map[string]float64{"USD": 1, "THB": thb},
+			Anomalies: []models.RateAnomaly{{Currency: "THB", Previous: 36, Current: thb, Change: thb/36 - 1}},
+			FetchedAt: now,
+			CreatedAt: now,
+		}
+	}
+	first, second := newQuarantine(80), newQuarantine(90)
+	for _, q := range []*models.RateQuarantine{first, second} {
+		if err := repo.Create(ctx, q); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	pending, err := repo.List(ctx, models.QuarantinePending, 10)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != second.ID || pending[0].Rates["THB"] != 90 || pending[0].Anomalies[0].Current != 90 {
+		t.Fatalf("pending = %+v, want only the second quarantine", pending)
+	}
+	if got, err := repo.FindByID(ctx, first.ID); err != nil || got.Status != models.QuarantineSuperseded {
+		t.Errorf("first quarantine = %+v, %v; want superseded", got, err)
+	}
+
+	by := uint(7)
+	d := repositories.QuarantineDecision{Status: models.QuarantineApproved, ByID: &by, ByEmail: "root@example.com", At: now}
+	if ok, err := repo.Decide(ctx, second.ID, d); err != nil || !ok {
+		t.Fatalf("decide: ok %v, err %v", ok, err)
+	}
+	if ok, err := repo.Decide(ctx, second.ID, d); err != nil || ok {
+		t.Errorf("decide again: ok %v, err %v", ok, err)
+	}
+	got, err := repo.FindByID(ctx, second.ID)
+	if err != nil || got.Status != models.QuarantineApproved || got.DecidedByEmail != "root@example.com" || got.DecidedAt == nil {
+		t.Errorf("decided quarantine = %+v, %v", got, err)
+	}
+
+	all, _ := repo.List(ctx, "", 10)
+	if len(all) != 2 || all[0].ID != second.ID {
+		t.Errorf("all quarantines = %+v, want newest first", all)
+	}
+	if _, err := repo.FindByID(ctx, 999); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("find missing: %v", err)
+	}
+}
+
+func TestAlertRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewAlertRepository(openSQLite(t))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	rule := &models.AlertRule{
+		UserID: 1, FromCurrency: "USD", ToCurrency: "THB", Condition: models.AlertChange, Threshold: 1,
+		Window: 24 * time.Hour, Cooldown: time.Hour, WebhookURL: "http://localhost/hook", WebhookSecret: "whsec_x", CreatedAt: now,
+	}
+	if err := repo.CreateRule(ctx, rule); err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	if _, err := repo.FindRule(ctx, 2, rule.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("find another user's rule: %v", err)
+	}
+
+	rule.LastRate, rule.BaselineRate, rule.BaselineAt, rule.LastTriggeredAt = 36.5, 36, now, &now
+	fired := &models.AlertDelivery{RuleID: rule.ID, UserID: 1, Event: models.EventRateAlert, Payload: "{}", Status: models.DeliveryPending, NextAttemptAt: now, CreatedAt: now}
+	if err := repo.UpdateRuleState(ctx, rule, fired); err != nil {
+		t.Fatalf("update rule state: %v", err)
+	}
+	got, err := repo.FindRule(ctx, 1, rule.ID)
+	if err != nil || got.LastRate != 36.5 || got.Window != 24*time.Hour || got.LastTriggeredAt == nil || fired.ID == 0 {
+		t.Fatalf("rule after update = %+v, %v", got, err)
+	}
+
+	later := &models.AlertDelivery{RuleID: rule.ID, UserID: 1, Event: models.EventRateAlert, Payload: "{}", Status: models.DeliveryPending, NextAttemptAt: now.Add(time.Hour), CreatedAt: now}
+	if err := repo.CreateDelivery(ctx, later); err != nil {
+		t.Fatalf("create delivery: %v", err)
+	}
+	due, err := repo.DueDeliveries(ctx, now, 10)
+	if err != nil || len(due) != 1 || due[0].ID != fired.ID {
+		t.Fatalf("due deliveries = %+v, %v", due, err)
+	}
+	due[0].Status, due[0].Attempts = models.DeliveryDead, 3
+	if err := repo.UpdateDelivery(ctx, &due[0]); err != nil {
+		t.Fatalf("update delivery: %v", err)
+	}
+	if dead, _ := repo.ListDeliveries(ctx, repositories.DeliveryFilter{UserID: 1, Status: models.DeliveryDead, Limit: 10}); len(dead) != 1 || dead[0].Attempts != 3 {
+		t.Errorf("dead deliveries = %+v", dead)
+	}
+
+	if found, err := repo.DeleteRule(ctx, 1, rule.ID); err != nil || !found {
+		t.Fatalf("delete rule: found %v, err %v", found, err)
+	}
+	if all, _ := repo.ListDeliveries(ctx, repositories.DeliveryFilter{UserID: 1, Limit: 10}); len(all) != 0 {
+		t.Errorf("deliveries after deleting the rule = %+v", all)
+	}
+}
+
+func TestOutboxRepository(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	repo := repositories.NewOutboxRepository(db)
+	tx := repositories.NewTransactor(db)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	rolledBack := errors.New("roll back")
+	err := tx.InTx(ctx, func(ctx context.Context) error {
+		if err := repo.Add(ctx, &models.OutboxEvent{EventID: "a", Type: "rates.published", Payload: "{}", CreatedAt: now}); err != nil {
+			return err
+		}
+		return rolledBack
+	})
+	if !errors.Is(err, rolledBack) {
+		t.Fatalf("InTx = %v", err)
+	}
+	if pending, _ := repo.Pending(ctx, 10); len(pending) != 0 {
+		t.Fatalf("event of a rolled back transaction was kept: %+v", pending)
+	}
+
+	err = tx.InTx(ctx, func(ctx context.Context) error {
+		for _, id := range []string{"b", "c"} {
+			if err := repo.Add(ctx, &models.OutboxEvent{EventID: id, Type: "rates.published", Payload: "{}", CreatedAt: now}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("InTx: %v", err)
+	}
+	pending, err := repo.Pending(ctx, 10)
+	if err != nil || len(pending) != 2 || pending[0].EventID != "b" {
+		t.Fatalf("pending = %+v, %v", pending, err)
+	}
+	if err := repo.MarkFailed(ctx, pending[0].ID, "sink down"); err != nil {
+		t.Fatalf("mark failed: %v", err)
+	}
+	if err := repo.MarkPublished(ctx, pending[0].ID, now); err != nil {
+		t.Fatalf("mark published: %v", err)
+	}
+	pending, _ = repo.Pending(ctx, 10)
+	if len(pending) != 1 || pending[0].EventID != "c" {
+		t.Fatalf("pending after publishing b = %+v", pending)
+	}
+	if n, err := repo.DeletePublishedBefore(ctx, now.Add(time.Second)); err != nil || n != 1 {
+		t.Errorf("delete published: %d, %v", n, err)
+	}
+}
+
+func TestLeaseRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewLeaseRepository(openSQLite(t))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ttl := 15 * time.Second
+
+	acquire := func(holder string, at time.Time) bool {
+		t.Helper()
+		ok, err := repo.Acquire(ctx, "rates", holder, at, at.Add(ttl))
+		if err != nil {
+			t.Fatalf("acquire as %s: %v", holder, err)
+		}
+		return ok
+	}
+	if !acquire("a", now) {
+		t.Fatal("a did not get the free lease")
+	}
+	if acquire("b", now.Add(time.Second)) {
+		t.Fatal("b took a lease a holds")
+	}
+	if !acquire("a", now.Add(5*time.Second)) {
+		t.Fatal("a could not renew its lease")
+	}
+	if acquire("b", now.Add(15*time.Second)) {
+		t.Fatal("b took a renewed lease before it expired")
+	}
+	if !acquire("b", now.Add(21*time.Second)) {
+		t.Fatal("b did not take the expired lease")
+	}
+	lease, err := repo.Find(ctx, "rates")
+	if err != nil || lease.Holder != "b" {
+		t.Fatalf("lease = %+v, %v", lease, err)
+	}
+
+	// Only the holder can release it.
+	if err := repo.Release(ctx, "rates", "a"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, err := repo.Find(ctx, "rates"); err != nil {
+		t.Fatalf("lease released by a non-holder: %v", err)
+	}
+	if err := repo.Release(ctx, "rates", "b"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, err := repo.Find(ctx, "rates"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("find after release = %v", err)
+	}
+	if !acquire("a", now.Add(22*time.Second)) {
+		t.Fatal("a did not get the released lease")
+	}
+}
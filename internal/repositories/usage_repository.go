@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	UsageGroupDay  = "day"
+	UsageGroupPair = "pair"
+	UsageGroupUser = "user"
+)
+
+type UsageFilter struct {
+	UserID  *uint
+	From    time.Time
+	To      time.Time
+	GroupBy []string
+}
+
+type UsageRepository interface {
+	CreateBatch(ctx context.Context, records []models.UsageRecord) error
+	Summarize(ctx context.Context, filter UsageFilter) ([]models.UsageSummary, error)
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type usageRepository struct {
+	db *gorm.DB
+}
+
+func NewUsageRepository(db *gorm.DB) UsageRepository {
+	return &usageRepository{db: db}
+}
+
+func (r *usageRepository) CreateBatch(ctx context.Context, records []models.UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(records, 500).Error
+}
+
+// groupColumns maps a grouping key to the SQL expressions selected and
+// grouped on. DATE() is understood by both Postgres and SQLite; the cast keeps
+// the day as a plain YYYY-MM-DD string on either.
+var groupColumns = map[string][]string{
+	UsageGroupDay:  {"CAST(DATE(created_at) AS TEXT) AS day"},
+	UsageGroupPair: {"from_currency", "to_currency"},
+	UsageGroupUser: {"user_id"},
+}
+
+func (r *usageRepository) Summarize(ctx context.Context, f UsageFilter) ([]models.UsageSummary, error) {
+	selects := []string{"COUNT(*) AS count"}
+	// Amounts in different source currencies cannot be added up.
+	if slices.Contains(f.GroupBy, UsageGroupPair) {
+		selects = append(selects, "COALESCE(SUM(amount), 0) AS total_amount")
+	}
+	var groups, orders []string
+	for _, g := range f.GroupBy {
+		for _, col := range groupColumns[g] {
+			selects = append(selects, col)
+			name := col
+			if i := strings.LastIndex(col, " AS "); i >= 0 {
+				name = col[i+4:]
+			}
+			groups = append(groups, name)
+			orders = append(orders, name)
+		}
+	}
+
+	q := r.db.WithContext(ctx).Model(&models.UsageRecord{}).Select(strings.Join(selects, ", "))
+	if f.UserID != nil {
+		q = q.Where("user_id = ?", *f.UserID)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("created_at < ?", f.To)
+	}
+	if len(groups) > 0 {
+		q = q.Group(strings.Join(groups, ", ")).Order(strings.Join(orders, ", "))
+	}
+
+	var out []models.UsageSummary
+	if err := q.Scan(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *usageRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("created_at < ?", t).Delete(&models.UsageRecord{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,214 @@
+package repositories
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/database"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// UserInvalidationChannel carries the ID of a user whose token version or
+// role changed, so every instance drops its cached copy.
+const UserInvalidationChannel = "user_invalidated"
+
+// CachedUserRepository wraps a UserRepository with a bounded, TTL-based
+// cache of FindByID, the lookup AuthRequired makes on every request. Entries
+// are dropped as soon as IncrementTokenVersion, IssueToken or SetRole runs
+// here, or on another instance when a Notifier is available; the TTL bounds
+// staleness otherwise.
+type CachedUserRepository struct {
+	UserRepository
+
+	ttl      time.Duration
+	size     int
+	notifier database.Notifier
+	log      *logger.Logger
+
+	mu      sync.Mutex
+	entries map[uint]*list.Element
+	order   *list.List // front is most recently used
+	// gens counts the invalidations of each user and epoch those of the
+	// whole cache, so a lookup that raced with one does not store the row
+	// it read before the change.
+	gens  map[uint]uint64
+	epoch uint64
+}
+
+// generation identifies the invalidations a lookup has seen.
+type generation struct {
+	epoch, user uint64
+}
+
+type userCacheEntry struct {
+	user    models.User
+	expires time.Time
+}
+
+var _ UserRepository = (*CachedUserRepository)(nil)
+
+// NewCachedUserRepository caches up to size users for ttl. notifier may be
+// nil, in which case invalidations stay local to this instance.
+func NewCachedUserRepository(inner UserRepository, ttl time.Duration, size int, notifier database.Notifier, log *logger.Logger) *CachedUserRepository {
+	if size <= 0 {
+		size = 1
+	}
+	return &CachedUserRepository{
+		UserRepository: inner,
+		ttl:            ttl,
+		size:           size,
+		notifier:       notifier,
+		log:            log,
+		entries:        map[uint]*list.Element{},
+		order:          list.New(),
+		gens:           map[uint]uint64{},
+	}
+}
+
+// FindByID answers from the cache when it holds a fresh entry. A ReadPrimary
+// context always goes to the repository and refreshes the entry.
+func (r *CachedUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	if !isReadPrimary(ctx) {
+		if u, ok := r.get(id); ok {
+			return &u, nil
+		}
+	}
+	gen := r.generation(id)
+	// A lagging replica could return the row from before an invalidation,
+	// which would then be cached for the whole TTL.
+	u, err := r.UserRepository.FindByID(ReadPrimary(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	r.put(*u, gen)
+	return u, nil
+}
+
+func (r *CachedUserRepository) IncrementTokenVersion(ctx context.Context, userID uint) error {
+	if err := r.UserRepository.IncrementTokenVersion(ctx, userID); err != nil {
+		return err
+	}
+	r.broadcastInvalidation(ctx, userID)
+	return nil
+}
+
+func (r *CachedUserRepository) IssueToken(ctx context.Context, userID uint, at time.Time) error {
+	if err := r.UserRepository.IssueToken(ctx, userID, at); err != nil {
+		return err
+	}
+	r.broadcastInvalidation(ctx, userID)
+	return nil
+}
+
+func (r *CachedUserRepository) SetRole(ctx context.Context, userID uint, role string) error {
+	if err := r.UserRepository.SetRole(ctx, userID, role); err != nil {
+		return err
+	}
+	r.broadcastInvalidation(ctx, userID)
+	return nil
+}
+
+// broadcastInvalidation drops the user here and on every other instance.
+func (r *CachedUserRepository) broadcastInvalidation(ctx context.Context, userID uint) {
+	r.Invalidate(userID)
+	if r.notifier == nil {
+		return
+	}
+	// The change is already stored; other instances fall back to the TTL if
+	// the broadcast fails, so it is logged rather than returned.
+	if err := r.notifier.Notify(context.WithoutCancel(ctx), UserInvalidationChannel, strconv.FormatUint(uint64(userID), 10)); err != nil {
+		r.log.Error("failed to broadcast user invalidation", logger.Fields{"user_id": userID, "error": err.Error()})
+	}
+}
+
+// Invalidate drops the cached copy of the user, if any, and any copy a
+// lookup in flight is about to store.
+func (r *CachedUserRepository) Invalidate(userID uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gens[userID]++
+	if el, ok := r.entries[userID]; ok {
+		r.order.Remove(el)
+		delete(r.entries, userID)
+	}
+}
+
+// Clear drops every cached user.
+func (r *CachedUserRepository) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.epoch++
+	// The epoch alone fences lookups in flight, so the counters can restart.
+	clear(r.gens)
+	clear(r.entries)
+	r.order.Init()
+}
+
+// Listen applies invalidations broadcast by other instances until ctx is
+// done. Invalidations sent while the listener was reconnecting are lost, so
+// the whole cache is dropped every time it connects. It returns immediately
+// without a notifier.
+func (r *CachedUserRepository) Listen(ctx context.Context) {
+	if r.notifier == nil {
+		return
+	}
+	err := r.notifier.Listen(ctx, UserInvalidationChannel, r.Clear, func(payload string) {
+		id, err := strconv.ParseUint(payload, 10, 64)
+		if err != nil {
+			r.log.Warn("ignoring malformed user invalidation", logger.Fields{"payload": payload})
+			return
+		}
+		r.Invalidate(uint(id))
+	})
+	if err != nil {
+		r.log.Error("user invalidation listener stopped", logger.Fields{"error": err.Error()})
+	}
+}
+
+func (r *CachedUserRepository) get(id uint) (models.User, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	el, ok := r.entries[id]
+	if !ok {
+		return models.User{}, false
+	}
+	e := el.Value.(*userCacheEntry)
+	if !time.Now().Before(e.expires) {
+		r.order.Remove(el)
+		delete(r.entries, id)
+		return models.User{}, false
+	}
+	r.order.MoveToFront(el)
+	return e.user, true
+}
+
+func (r *CachedUserRepository) generation(id uint) generation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return generation{epoch: r.epoch, user: r.gens[id]}
+}
+
+// put stores u unless it was invalidated since gen was taken.
+func (r *CachedUserRepository) put(u models.User, gen generation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if gen != (generation{epoch: r.epoch, user: r.gens[u.ID]}) {
+		return
+	}
+	e := &userCacheEntry{user: u, expires: time.Now().Add(r.ttl)}
+	if el, ok := r.entries[u.ID]; ok {
+		el.Value = e
+		r.order.MoveToFront(el)
+		return
+	}
+	r.entries[u.ID] = r.order.PushFront(e)
+	for r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*userCacheEntry).user.ID)
+	}
+}
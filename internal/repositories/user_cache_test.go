@@ -0,0 +1,282 @@
+package repositories_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories/memory"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// countingUsers counts FindByID calls that reach the wrapped repository.
+type countingUsers struct {
+	repositories.UserRepository
+	mu    sync.Mutex
+	finds int
+}
+
+func (c *countingUsers) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	c.mu.Lock()
+	c.finds++
+	c.mu.Unlock()
+	return c.UserRepository.FindByID(ctx, id)
+}
+
+// loopbackNotifier delivers notifications to the listener registered last,
+// standing in for Postgres LISTEN/NOTIFY between two instances.
+type loopbackNotifier struct {
+	mu       sync.Mutex
+	fn       func(string)
+	onListen func()
+}
+
+// reconnect simulates the listener losing its connection and listening again.
+func (n *loopbackNotifier) reconnect() {
+	n.mu.Lock()
+	onListen := n.onListen
+	n.mu.Unlock()
+	onListen()
+}
+
+func (n *loopbackNotifier) Notify(ctx context.Context, channel, payload string) error {
+	n.mu.Lock()
+	fn := n.fn
+	n.mu.Unlock()
+	if fn != nil {
+		fn(payload)
+	}
+	return nil
+}
+
+func (n *loopbackNotifier) Listen(ctx context.Context, channel string, onListen func(), fn func(string)) error {
+	n.mu.Lock()
+	n.fn = fn
+	n.onListen = onListen
+	n.mu.Unlock()
+	if onListen != nil {
+		onListen()
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// pausingUsers blocks FindByID after reading the row until release is
+// closed, like a slow query whose result arrives after a concurrent write.
+type pausingUsers struct {
+	repositories.UserRepository
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (p *pausingUsers) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	u, err := p.UserRepository.FindByID(ctx, id)
+	if p.read != nil {
+		close(p.read)
+		<-p.release
+		p.read = nil
+	}
+	return u, err
+}
+
+func newCachedUsers(t *testing.T, ttl time.Duration, size int) (*repositories.CachedUserRepository, *countingUsers, *models.User) {
+	t.Helper()
+	inner := &countingUsers{UserRepository: memory.NewUserRepository()}
+	u := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := inner.Create(context.Background(), u); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	log := logger.New(logger.Options{Level: "error"})
+	return repositories.NewCachedUserRepository(inner, ttl, size, nil, log), inner, u
+}
+
+func TestCachedUserRepositoryInvalidatesOnTokenVersion(t *testing.T) {
+	ctx := context.Background()
+	cache, inner, u := newCachedUsers(t, time.Minute, 10)
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.FindByID(ctx, u.ID); err != nil {
+			t.Fatalf("find: %v", err)
+		}
+	}
+	if inner.finds != 1 {
+		t.Fatalf("repository hit %d times, want 1", inner.finds)
+	}
+
+	if err := cache.IncrementTokenVersion(ctx, u.ID); err != nil {
+		t.Fatalf("increment: %v", err)
+	}
+	got, err := cache.FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if got.TokenVersion != 1 {
+		t.Errorf("TokenVersion = %d after revocation, want 1", got.TokenVersion)
+	}
+	if inner.finds != 2 {
+		t.Errorf("repository hit %d times, want 2", inner.finds)
+	}
+}
+
+func TestCachedUserRepositoryFillsFromPrimary(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := openSQLite(t), openSQLite(t)
+	u := &models.User{Email: "kim@example.com", Password: "x", Role: models.RoleUser}
+	if err := primary.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	// The replica has the row but not the writes that follow.
+	if err := replica.Create(&models.User{Model: u.Model, Email: u.Email, Password: u.Password, Role: u.Role}).Error; err != nil {
+		t.Fatal(err)
+	}
+	cache := repositories.NewCachedUserRepository(repositories.NewUserRepository(primary, replica), time.Minute, 10, nil, logger.New(logger.Options{Level: "error"}))
+
+	if err := cache.IncrementTokenVersion(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := cache.FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TokenVersion != 1 {
+		t.Errorf("cached token version = %d, want 1 from the primary", got.TokenVersion)
+	}
+}
+
+func TestCachedUserRepositoryExpiresAndEvicts(t *testing.T) {
+	ctx := context.Background()
+	cache, inner, u := newCachedUsers(t, 20*time.Millisecond, 1)
+
+	_, _ = cache.FindByID(ctx, u.ID)
+	time.Sleep(30 * time.Millisecond)
+	_, _ = cache.FindByID(ctx, u.ID)
+	if inner.finds != 2 {
+		t.Fatalf("repository hit %d times after expiry, want 2", inner.finds)
+	}
+
+	bob := &models.User{Username: "bob", Email: "bob@example.com", Password: "x"}
+	if err := inner.Create(ctx, bob); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	_, _ = cache.FindByID(ctx, bob.ID) // evicts alice: the cache holds one user
+	_, _ = cache.FindByID(ctx, u.ID)
+	if inner.finds != 4 {
+		t.Errorf("repository hit %d times after eviction, want 4", inner.finds)
+	}
+}
+
+func TestCachedUserRepositoryRemoteInvalidation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	shared := memory.NewUserRepository()
+	u := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := shared.Create(ctx, u); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	notifier := &loopbackNotifier{}
+	log := logger.New(logger.Options{Level: "error"})
+	writer := repositories.NewCachedUserRepository(shared, time.Minute, 10, notifier, log)
+	reader := repositories.NewCachedUserRepository(shared, time.Minute, 10, notifier, log)
+	go reader.Listen(ctx)
+
+	if _, err := reader.FindByID(ctx, u.ID); err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		notifier.mu.Lock()
+		ready := notifier.fn != nil
+		notifier.mu.Unlock()
+		if ready || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := writer.IncrementTokenVersion(ctx, u.ID); err != nil {
+		t.Fatalf("increment: %v", err)
+	}
+	got, err := reader.FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if got.TokenVersion != 1 {
+		t.Errorf("other instance sees TokenVersion %d, want 1", got.TokenVersion)
+	}
+}
+
+func TestCachedUserRepositoryDropsLookupsRacingInvalidation(t *testing.T) {
+	ctx := context.Background()
+	inner := &pausingUsers{UserRepository: memory.NewUserRepository(), read: make(chan struct{}), release: make(chan struct{})}
+	u := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := inner.Create(ctx, u); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	cache := repositories.NewCachedUserRepository(inner, time.Minute, 10, nil, logger.New(logger.Options{Level: "error"}))
+
+	done := make(chan *models.User)
+	go func() {
+		got, _ := cache.FindByID(ctx, u.ID)
+		done <- got
+	}()
+	<-inner.read
+	// The lookup holds version 0 when the token is revoked.
+	if err := cache.IncrementTokenVersion(ctx, u.ID); err != nil {
+		t.Fatalf("increment: %v", err)
+	}
+	close(inner.release)
+	if stale := <-done; stale.TokenVersion != 0 {
+		t.Fatalf("racing lookup read TokenVersion %d, want the old 0", stale.TokenVersion)
+	}
+
+	got, err := cache.FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if got.TokenVersion != 1 {
+		t.Errorf("TokenVersion = %d after the race, want 1: the stale row was cached", got.TokenVersion)
+	}
+}
+
+func TestCachedUserRepositoryClearsOnReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inner := &countingUsers{UserRepository: memory.NewUserRepository()}
+	u := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := inner.Create(ctx, u); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	notifier := &loopbackNotifier{}
+	cache := repositories.NewCachedUserRepository(inner, time.Minute, 10, notifier, logger.New(logger.Options{Level: "error"}))
+	go cache.Listen(ctx)
+	deadline := time.Now().Add(time.Second)
+	for {
+		notifier.mu.Lock()
+		ready := notifier.onListen != nil
+		notifier.mu.Unlock()
+		if ready || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	_, _ = cache.FindByID(ctx, u.ID)
+	_, _ = cache.FindByID(ctx, u.ID)
+	// A revocation on another instance while this one was disconnected.
+	if err := inner.IncrementTokenVersion(ctx, u.ID); err != nil {
+		t.Fatalf("increment: %v", err)
+	}
+	notifier.reconnect()
+
+	got, err := cache.FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if got.TokenVersion != 1 || inner.finds != 2 {
+		t.Errorf("TokenVersion %d after %d lookups, want 1 after 2: the cache survived the reconnect", got.TokenVersion, inner.finds)
+	}
+}
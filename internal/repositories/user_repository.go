@@ -1,56 +1,84 @@
-package repositories
-
-import (
-	"github.com/spksupakorn/Currency-Converter/internal/models"
-	"gorm.io/gorm"
-)
-
-type UserRepository interface {
-	FindByEmail(email string) (*models.User, error)
-	FindByUsername(username string) (*models.User, error)
-	FindByID(id uint) (*models.User, error)
-	Create(user *models.User) error
-	IncrementTokenVersion(userID uint) error
-}
-
-type userRepository struct {
-	db *gorm.DB
-}
-
-func NewUserRepository(db *gorm.DB) UserRepository {
-	return &userRepository{db: db}
-}
-
-func (r *userRepository) FindByEmail(email string) (*models.User, error) {
-	var u models.User
-	if err := r.db.Where("email = ?", email).First(&u).Error; err != nil {
-		return nil, err
-	}
-	return &u, nil
-}
-
-func (r *userRepository) FindByUsername(username string) (*models.User, error) {
-	var u models.User
-	if err := r.db.Where("username = ?", username).First(&u).Error; err != nil {
-		return nil, err
-	}
-	return &u, nil
-}
-
-func (r *userRepository) FindByID(id uint) (*models.User, error) {
-	var u models.User
-	if err := r.db.First(&u, id).Error; err != nil {
-		return nil, err
-	}
-	return &u, nil
-}
-
-func (r *userRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
-}
-
-func (r *userRepository) IncrementTokenVersion(userID uint) error {
-	return r.db.Model(&models.User{}).
-		Where("id = ?", userID).
-		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
-}
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"gorm.io/gorm"
+)
+
+type UserRepository interface {
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	IncrementTokenVersion(ctx context.Context, userID uint) error
+	// IssueToken increments the token version like IncrementTokenVersion and
+	// records that a token of the new version was issued at at.
+	IssueToken(ctx context.Context, userID uint, at time.Time) error
+	SetRole(ctx context.Context, userID uint, role string) error
+}
+
+type userRepository struct {
+	db     *gorm.DB
+	reader *gorm.DB
+}
+
+// NewUserRepository serves FindByID, the per-request lookup in AuthRequired,
+// from reader when it is not nil.
+func NewUserRepository(db, reader *gorm.DB) UserRepository {
+	return &userRepository{db: db, reader: reader}
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var u models.User
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&u).Error; err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	var u models.User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&u).Error; err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	var u models.User
+	if err := readerFor(ctx, r.db, r.reader).First(&u, id).Error; err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *userRepository) IncrementTokenVersion(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumns(map[string]interface{}{
+			"token_version":   gorm.Expr("token_version + 1"),
+			"token_issued_at": nil,
+		}).Error
+}
+
+func (r *userRepository) IssueToken(ctx context.Context, userID uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumns(map[string]interface{}{
+			"token_version":   gorm.Expr("token_version + 1"),
+			"token_issued_at": at.UTC(),
+		}).Error
+}
+
+func (r *userRepository) SetRole(ctx context.Context, userID uint, role string) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumn("role", role).Error
+}
@@ -2,48 +2,158 @@ package router
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/database"
 	"github.com/spksupakorn/Currency-Converter/internal/controllers"
+	"github.com/spksupakorn/Currency-Converter/internal/events"
+	"github.com/spksupakorn/Currency-Converter/internal/leader"
+	"github.com/spksupakorn/Currency-Converter/internal/lifecycle"
 	"github.com/spksupakorn/Currency-Converter/internal/middleware"
 	"github.com/spksupakorn/Currency-Converter/internal/repositories"
 	"github.com/spksupakorn/Currency-Converter/internal/services"
 	"github.com/spksupakorn/Currency-Converter/pkg/logger"
-	"gorm.io/gorm"
 )
 
-func NewRouter(db *gorm.DB, log *logger.Logger, cfg config.Config, route *gin.Engine) {
-	// Health
-	route.GET("/healthcheck", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
+// leaderLease names the lease the instance doing the background work holds.
+const leaderLease = "background-work"
+
+// UseMiddleware installs the global middleware chain on route.
+func UseMiddleware(route *gin.Engine, log *logger.Logger, settings *config.Reloader, lc *lifecycle.Lifecycle) {
+	route.Use(middleware.Recovery(log))
+	route.Use(middleware.RequestID())
+	route.Use(middleware.ContextLogger(log))
+	route.Use(middleware.RequestLogger(log, settings.Current().LogRequestSample))
+	route.Use(middleware.Timeout(settings.Current()))
+	route.Use(middleware.SecurityHeaders())
+	route.Use(middleware.Compress())
+	route.Use(middleware.RateLimit(settings, lc))
+}
+
+func NewRouter(db database.Database, log *logger.Logger, settings *config.Reloader, route *gin.Engine, lc *lifecycle.Lifecycle) error {
+	return NewRouterWithRepositories(db, repositories.NewRepositories(db.ConnectDB(), db.Reader()), log, settings, route, lc)
+}
+
+// NewRouterWithRepositories registers every route using the given
+// repositories instead of GORM-backed ones built from db. Background workers
+// run until lc stops; none is started when an error is returned.
+func NewRouterWithRepositories(db database.Database, repos repositories.Repositories, log *logger.Logger, settings *config.Reloader, route *gin.Engine, lc *lifecycle.Lifecycle) error {
+	cfg := settings.Current()
+
+	var sink events.Sink
+	if cfg.EventsSink != "" {
+		var err error
+		if sink, err = events.NewSink(cfg.EventsSink); err != nil {
+			return fmt.Errorf("open events sink: %w", err)
+		}
+	}
 
 	// Repos
-	userRepo := repositories.NewUserRepository(db)
-	rateRepo := repositories.NewRateRepository(db)
+	userRepo := repos.Users
+	if cfg.AuthCacheTTL > 0 {
+		cached := repositories.NewCachedUserRepository(repos.Users, cfg.AuthCacheTTL, cfg.AuthCacheSize, db.Notifier(), log)
+		lc.Go("user cache listener", cached.Listen)
+		userRepo = cached
+	}
+	auditRepo := repos.Audit
+	usageRepo := repos.Usage
+
+	// Leader: refreshes rates, delivers webhooks and relays events on one
+	// instance only; every instance leads when election is disabled.
+	var elector *leader.Elector
+	leaderTask := lc.Go
+	if cfg.LeaderElection {
+		elector = leader.New(repos.Leases, leaderLease, cfg.InstanceID, cfg.LeaderLeaseTTL, log)
+		leaderTask = func(_ string, fn func(ctx context.Context)) { elector.OnElected(fn) }
+	}
+
+	// Events: written to the outbox with the change they describe and relayed
+	// to EVENTS_SINK; dropped when no sink is configured.
+	publisher := events.Discard
+	if sink != nil {
+		publisher = events.NewOutboxPublisher(repos.Outbox)
+		relay := events.NewRelay(repos.Outbox, sink, cfg.EventsPollInterval, cfg.EventsRetention, log)
+		leaderTask("events relay", relay.Run)
+		lc.OnStop("events sink", relay.Close)
+	}
 
 	// Services
 	authSvc := services.NewAuthService(cfg, userRepo, log)
-	rateSvc := services.NewRateService(cfg, rateRepo, log)
-	// Start rates background refresher
-	rateSvc.StartBackgroundRefresh(context.Background())
+	rateSvc := services.NewRateService(cfg, repos, publisher, db.Notifier(), log)
+	auditSvc := services.NewAuditService(auditRepo, log)
+	usageSvc := services.NewUsageService(cfg, usageRepo, log)
+	alertSvc := services.NewAlertService(cfg, repos.Alerts, rateSvc, log)
+	rateSvc.OnPublish(alertSvc.Evaluate)
+	settings.Subscribe(rateSvc.UpdateSettings)
+	// Start rates background refresher and webhook delivery on the leader
+	leaderTask("rate refresh", rateSvc.RunBackgroundRefresh)
+	leaderTask("webhook dispatcher", alertSvc.RunDispatcher)
+	// Keep the rates of this instance in step with the stored ones
+	lc.Go("rate sync", rateSvc.RunSync)
+	// Start usage ledger writer
+	lc.Go("usage writer", usageSvc.Run)
+	// Start alert evaluation
+	lc.Go("alert evaluator", alertSvc.RunEvaluator)
+	if elector != nil {
+		lc.Go("leader election", elector.Run)
+	}
+
+	// Health
+	healthH := controllers.NewHealthController(db, rateSvc, elector, log)
+	route.GET("/healthcheck", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	route.GET("/livez", healthH.Livez)
+	route.GET("/readyz", healthH.Readyz)
 
 	// API v1
 	v1 := route.Group("/api/v1")
 	{
-		authH := controllers.NewAuthController(authSvc, log)
+		authH := controllers.NewAuthController(authSvc, auditSvc, log)
 		v1.POST("/auth/register", authH.Register)
 		v1.POST("/auth/login", authH.Login)
 		v1.POST("/auth/logout", middleware.AuthRequired(cfg, userRepo), authH.Logout)
 
-		rateH := controllers.NewRateController(rateSvc, log)
+		rateH := controllers.NewRateController(rateSvc, usageSvc, log)
 		protected := v1.Group("/")
 		protected.Use(middleware.AuthRequired(cfg, userRepo))
 		{
 			protected.GET("/rates", rateH.GetRates)          // ?base=USD
 			protected.GET("/convert", rateH.ConvertCurrency) // ?from=USD&to=THB&amount=123.45
+
+			auditH := controllers.NewAuditController(auditSvc, log)
+			protected.GET("/me/activity", auditH.MyActivity)
+
+			usageH := controllers.NewUsageController(usageSvc, log)
+			protected.GET("/me/usage", usageH.MyUsage) // ?group_by=day,pair&from=...&to=...
+
+			alertH := controllers.NewAlertController(alertSvc, log)
+			protected.POST("/alerts", alertH.CreateAlert)
+			protected.GET("/alerts", alertH.ListAlerts)
+			protected.DELETE("/alerts/:id", alertH.DeleteAlert)
+			protected.POST("/alerts/:id/test", alertH.TestAlert)
+			protected.GET("/alerts/deliveries", alertH.ListDeliveries) // ?alert_id=1&status=dead
+			protected.POST("/alerts/deliveries/:id/retry", alertH.RetryDelivery)
+
+			admin := protected.Group("/admin")
+			admin.Use(middleware.AdminRequired())
+			{
+				admin.GET("/audit-events", auditH.ListEvents) // ?action=auth.login&outcome=failure
+				admin.GET("/usage", usageH.Report)            // ?group_by=user,day
+
+				rateAdminH := controllers.NewRateAdminController(rateSvc, auditSvc, log)
+				admin.POST("/rates/refresh", rateAdminH.Refresh)
+				admin.GET("/rates/overrides", rateAdminH.ListOverrides)
+				admin.PUT("/rates/overrides/:currency", rateAdminH.SetOverride)
+				admin.DELETE("/rates/overrides/:currency", rateAdminH.DeleteOverride)
+				admin.GET("/rates/quarantine", rateAdminH.ListQuarantines)
+				admin.POST("/rates/quarantine/:id/approve", rateAdminH.ApproveQuarantine)
+				admin.POST("/rates/quarantine/:id/reject", rateAdminH.RejectQuarantine)
+			}
 		}
 	}
+	return nil
 }
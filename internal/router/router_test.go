@@ -0,0 +1,949 @@
+package router_test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/internal/lifecycle"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories/memory"
+	"github.com/spksupakorn/Currency-Converter/internal/router"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/internal/testutil"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+func TestAuthConvertLogoutFlow(t *testing.T) {
+	h := testutil.New(t)
+	h.WaitReady()
+
+	h.Register("alice@example.com", "password123")
+	token := h.Login("alice@example.com", "password123")
+
+	rec := h.Do(http.MethodGet, "/api/v1/convert?from=USD&to=THB&amount=10", nil, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("convert: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var conv struct {
+		Rate   float64 `json:"rate"`
+		Result float64 `json:"result"`
+	}
+	h.Decode(rec, &conv)
+	if conv.Rate != 36.5 || conv.Result != 365 {
+		t.Errorf("convert = rate %v result %v, want 36.5 and 365", conv.Rate, conv.Result)
+	}
+
+	rec = h.Do(http.MethodPost, "/api/v1/auth/logout", nil, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("logout: status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = h.Do(http.MethodGet, "/api/v1/rates", nil, token)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("rates with revoked token: status %d, want 401", rec.Code)
+	}
+
+	// A new login works, and revokes the token issued before it.
+	second := h.Login("alice@example.com", "password123")
+	third := h.Login("alice@example.com", "password123")
+	if rec := h.Do(http.MethodGet, "/api/v1/rates", nil, second); rec.Code != http.StatusUnauthorized {
+		t.Errorf("rates with superseded token: status %d, want 401", rec.Code)
+	}
+	if rec := h.Do(http.MethodGet, "/api/v1/rates", nil, third); rec.Code != http.StatusOK {
+		t.Errorf("rates with latest token: status %d, want 200", rec.Code)
+	}
+}
+
+func TestRatesDerivedBase(t *testing.T) {
+	h := testutil.New(t)
+	h.WaitReady()
+	h.Register("bob@example.com", "password123")
+	token := h.Login("bob@example.com", "password123")
+
+	rec := h.Do(http.MethodGet, "/api/v1/rates?base=EUR", nil, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rates: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		Base  string             `json:"base"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	h.Decode(rec, &out)
+	if out.Base != "EUR" || out.Rates["EUR"] != 1 {
+		t.Fatalf("base = %s, EUR = %v", out.Base, out.Rates["EUR"])
+	}
+	if want := 36.5 / 0.92; math.Abs(out.Rates["THB"]-want) > 1e-9 {
+		t.Errorf("EUR->THB = %v, want %v", out.Rates["THB"], want)
+	}
+
+	if rec := h.Do(http.MethodGet, "/api/v1/rates?base=usd1", nil, token); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid base: status %d, want 400", rec.Code)
+	}
+}
+
+func TestActivityAndUsage(t *testing.T) {
+	h := testutil.New(t)
+	h.WaitReady()
+	h.Register("carol@example.com", "password123")
+	if rec := h.Do(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": "carol@example.com", "password": "wrong-password"}, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad login: status %d, want 401", rec.Code)
+	}
+	token := h.Login("carol@example.com", "password123")
+
+	for i := 0; i < 3; i++ {
+		if rec := h.Do(http.MethodGet, "/api/v1/convert?from=USD&to=JPY&amount=2", nil, token); rec.Code != http.StatusOK {
+			t.Fatalf("convert: status %d", rec.Code)
+		}
+	}
+
+	var activity struct {
+		Events []struct {
+			Action  string `json:"action"`
+			Outcome string `json:"outcome"`
+		} `json:"events"`
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/me/activity?action=auth.login", nil, token), &activity)
+	if len(activity.Events) != 2 || activity.Events[1].Outcome != "failure" {
+		t.Errorf("login activity = %+v, want a success after a failure", activity.Events)
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/me/activity?action=auth.token_revoked", nil, token), &activity)
+	if len(activity.Events) != 0 {
+		t.Errorf("token revocations = %+v, want none for a first login", activity.Events)
+	}
+	if rec := h.Do(http.MethodGet, "/api/v1/me/activity?from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z", nil, token); rec.Code != http.StatusBadRequest {
+		t.Errorf("reversed range: status %d, want 400", rec.Code)
+	}
+
+	// Usage is written asynchronously.
+	var usage struct {
+		Usage []struct {
+			Count       int64   `json:"count"`
+			TotalAmount float64 `json:"total_amount"`
+		} `json:"usage"`
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		h.Decode(h.Do(http.MethodGet, "/api/v1/me/usage?group_by=pair", nil, token), &usage)
+		if len(usage.Usage) == 1 && usage.Usage[0].Count == 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(usage.Usage) != 1 || usage.Usage[0].Count != 3 || usage.Usage[0].TotalAmount != 6 {
+		t.Errorf("usage = %+v, want one pair with 3 conversions totalling 6", usage.Usage)
+	}
+	// Without the pair the amounts would mix currencies.
+	rec := h.Do(http.MethodGet, "/api/v1/me/usage?group_by=day", nil, token)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "total_amount") {
+		t.Errorf("usage by day: status %d: %s, want no total_amount", rec.Code, rec.Body.String())
+	}
+	if rec := h.Do(http.MethodGet, "/api/v1/me/usage?group_by=week", nil, token); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown group_by: status %d, want 400", rec.Code)
+	}
+
+	if rec := h.Do(http.MethodGet, "/api/v1/admin/usage", nil, token); rec.Code != http.StatusForbidden {
+		t.Errorf("admin usage as regular user: status %d, want 403", rec.Code)
+	}
+}
+
+func TestAdminRoleFromListAndGrant(t *testing.T) {
+	h := testutil.New(t, func(c *config.Config) {
+		c.AdminEmails = []string{"root@example.com"}
+		// The grant below bypasses the cache, as another process would.
+		c.AuthCacheTTL = 0
+	})
+	h.WaitReady()
+	h.Register("root@example.com", "password123")
+	h.Register("dave@example.com", "password123")
+	root := h.Login("root@example.com", "password123")
+	dave := h.Login("dave@example.com", "password123")
+
+	if rec := h.Do(http.MethodGet, "/api/v1/admin/usage", nil, root); rec.Code != http.StatusOK {
+		t.Errorf("admin usage as listed email: status %d, want 200", rec.Code)
+	}
+	u, err := h.Repos.Users.FindByEmail(context.Background(), "dave@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := h.Do(http.MethodGet, "/api/v1/admin/usage", nil, dave); rec.Code != http.StatusForbidden {
+		t.Errorf("admin usage before grant: status %d, want 403", rec.Code)
+	}
+	if err := h.Repos.Users.SetRole(context.Background(), u.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if rec := h.Do(http.MethodGet, "/api/v1/admin/usage", nil, dave); rec.Code != http.StatusOK {
+		t.Errorf("admin usage after grant: status %d, want 200", rec.Code)
+	}
+}
+
+func TestRouterReportsAnUnusableEventsSink(t *testing.T) {
+	cfg := testutil.Config("http://127.0.0.1:1")
+	cfg.EventsSink = "file:" + t.TempDir() + "/missing/events.jsonl"
+	log := logger.New(logger.Options{Level: "error"})
+	lc := lifecycle.New(log)
+	t.Cleanup(func() { _ = lc.Stop(context.Background()) })
+
+	err := router.NewRouterWithRepositories(memory.Database{}, memory.NewRepositories(), log, config.NewReloader(cfg, nil), gin.New(), lc)
+	if err == nil || !strings.Contains(err.Error(), "open events sink") {
+		t.Fatalf("router = %v, want the sink error", err)
+	}
+}
+
+func TestReadinessRequiresRates(t *testing.T) {
+	// The fake provider has no GBP table, so the initial refresh fails.
+	h := testutil.New(t, func(cfg *config.Config) { cfg.RateBaseCurrency = "GBP" })
+
+	if rec := h.Do(http.MethodGet, "/livez", nil, ""); rec.Code != http.StatusOK {
+		t.Errorf("livez: status %d, want 200", rec.Code)
+	}
+
+	rec := h.Do(http.MethodGet, "/readyz", nil, "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz: status %d, want 503: %s", rec.Code, rec.Body.String())
+	}
+	var report struct {
+		Checks map[string]struct {
+			Status string `json:"status"`
+		} `json:"checks"`
+	}
+	h.Decode(rec, &report)
+	if report.Checks["database"].Status != "ok" || report.Checks["rates"].Status != "fail" {
+		t.Errorf("checks = %+v, want database ok and rates fail", report.Checks)
+	}
+}
+
+func TestRatesConditionalRequestsAndCompression(t *testing.T) {
+	h := testutil.New(t)
+	h.WaitReady()
+	h.Register("erin@example.com", "password123")
+	token := h.Login("erin@example.com", "password123")
+
+	get := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		return h.Send(req)
+	}
+
+	rec := get("/api/v1/rates?base=EUR", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rates: status %d: %s", rec.Code, rec.Body.String())
+	}
+	etag, lastMod := rec.Header().Get("ETag"), rec.Header().Get("Last-Modified")
+	if etag == "" || lastMod == "" {
+		t.Fatalf("missing validators: ETag %q, Last-Modified %q", etag, lastMod)
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "private, max-age=") || cc == "private, max-age=0" {
+		t.Errorf("Cache-Control = %q, want a positive max-age", cc)
+	}
+
+	if rec := get("/api/v1/rates?base=EUR", map[string]string{"If-None-Match": etag}); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("If-None-Match: status %d, body %q, want empty 304", rec.Code, rec.Body.String())
+	}
+	if rec := get("/api/v1/rates?base=USD", map[string]string{"If-None-Match": etag}); rec.Code != http.StatusOK {
+		t.Errorf("other base with same ETag: status %d, want 200", rec.Code)
+	}
+	if rec := get("/api/v1/rates?base=EUR", map[string]string{"If-Modified-Since": lastMod}); rec.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since: status %d, want 304", rec.Code)
+	}
+
+	rec = get("/api/v1/rates", map[string]string{"Accept-Encoding": "gzip"})
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	var out struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(zr).Decode(&out); err != nil {
+		t.Fatalf("decode gzip body: %v", err)
+	}
+	if out.Rates["THB"] != 36.5 {
+		t.Errorf("THB = %v, want 36.5", out.Rates["THB"])
+	}
+
+	if rec := get("/api/v1/rates", map[string]string{"Accept-Encoding": "gzip, br"}); rec.Header().Get("Content-Encoding") != "br" {
+		t.Errorf("Content-Encoding = %q, want br", rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestStaleRatesAreNotNotModified(t *testing.T) {
+	h := testutil.New(t, func(c *config.Config) { c.RateMaxAge = time.Second })
+	h.WaitReady()
+	h.Register("erin@example.com", "password123")
+	token := h.Login("erin@example.com", "password123")
+	get := func(header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/rates", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		return h.Send(req)
+	}
+
+	fresh := get(nil)
+	if fresh.Code != http.StatusOK || !strings.Contains(fresh.Body.String(), `"stale":false`) {
+		t.Fatalf("fresh rates: status %d: %s", fresh.Code, fresh.Body.String())
+	}
+	time.Sleep(time.Second)
+
+	for _, header := range []map[string]string{
+		{"If-None-Match": fresh.Header().Get("ETag")},
+		{"If-Modified-Since": fresh.Header().Get("Last-Modified")},
+	} {
+		rec := get(header)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"stale":true`) {
+			t.Errorf("%v once stale: status %d: %s, want the stale rates", header, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("ETag") == fresh.Header().Get("ETag") {
+			t.Errorf("%v: stale rates kept the ETag of the fresh ones", header)
+		}
+	}
+}
+
+func TestLateErrorsWithCompression(t *testing.T) {
+	h := testutil.New(t, func(c *config.Config) {
+		c.RouteTimeouts = map[string]time.Duration{"/test/slow": 20 * time.Millisecond}
+	})
+	h.Engine.GET("/test/panic", func(c *gin.Context) { panic("boom") })
+	h.Engine.GET("/test/slow", func(c *gin.Context) { <-c.Request.Context().Done() })
+
+	tests := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/test/panic", http.StatusInternalServerError, "internal_error"},
+		{"/test/slow", http.StatusGatewayTimeout, "timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := h.Send(req)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			var body io.Reader = rec.Body
+			if rec.Header().Get("Content-Encoding") == "gzip" {
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("gzip reader: %v", err)
+				}
+				body = zr
+			}
+			var out struct {
+				Code string `json:"code"`
+			}
+			if err := json.NewDecoder(body).Decode(&out); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if out.Code != tt.code {
+				t.Errorf("code = %q, want %q", out.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestRatesFiltering(t *testing.T) {
+	h := testutil.New(t)
+	h.WaitReady()
+	h.Register("frank@example.com", "password123")
+	token := h.Login("frank@example.com", "password123")
+
+	type ratesBody struct {
+		Base    string             `json:"base"`
+		Rates   map[string]float64 `json:"rates"`
+		Missing []string           `json:"missing"`
+	}
+	get := func(query string) ratesBody {
+		t.Helper()
+		rec := h.Do(http.MethodGet, "/api/v1/rates?"+query, nil, token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("rates?%s: status %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var out ratesBody
+		h.Decode(rec, &out)
+		return out
+	}
+	keys := func(m map[string]float64) string {
+		var ks []string
+		for k := range m {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		return strings.Join(ks, ",")
+	}
+
+	out := get("symbols=eur,thb,XYZ,ABC&base=JPY")
+	if keys(out.Rates) != "EUR,THB" || strings.Join(out.Missing, ",") != "ABC,XYZ" {
+		t.Errorf("symbols: rates %s, missing %v", keys(out.Rates), out.Missing)
+	}
+	if out := get("exclude=USD,JPY"); keys(out.Rates) != "EUR,GBP,THB" || out.Missing != nil {
+		t.Errorf("exclude: rates %s, missing %v", keys(out.Rates), out.Missing)
+	}
+	if out := get("region=asia"); keys(out.Rates) != "JPY,THB" {
+		t.Errorf("region=asia: rates %s", keys(out.Rates))
+	}
+	if out := get("region=europe&exclude=GBP&type=fiat"); keys(out.Rates) != "EUR" {
+		t.Errorf("region=europe&exclude=GBP: rates %s", keys(out.Rates))
+	}
+	if out := get("type=metal"); len(out.Rates) != 0 {
+		t.Errorf("type=metal: rates %s, want none", keys(out.Rates))
+	}
+
+	// The unfiltered table is shared between requests and must be intact.
+	if out := get(""); keys(out.Rates) != "EUR,GBP,JPY,THB,USD" {
+		t.Errorf("unfiltered: rates %s", keys(out.Rates))
+	}
+
+	for _, q := range []string{"symbols=EURO", "region=mars", "type=stock"} {
+		if rec := h.Do(http.MethodGet, "/api/v1/rates?"+q, nil, token); rec.Code != http.StatusBadRequest {
+			t.Errorf("rates?%s: status %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+func TestRatesFreshnessAndStalePolicy(t *testing.T) {
+	type freshness struct {
+		FetchedAt     time.Time  `json:"fetched_at"`
+		AgeSeconds    *int64     `json:"age_seconds"`
+		NextRefreshAt *time.Time `json:"next_refresh_at"`
+		Provider      string     `json:"provider"`
+		Stale         bool       `json:"stale"`
+	}
+
+	h := testutil.New(t)
+	h.WaitReady()
+	h.Register("gina@example.com", "password123")
+	token := h.Login("gina@example.com", "password123")
+	for _, path := range []string{"/api/v1/rates", "/api/v1/convert?from=USD&to=THB&amount=1"} {
+		rec := h.Do(http.MethodGet, path, nil, token)
+		var out freshness
+		h.Decode(rec, &out)
+		if out.FetchedAt.IsZero() || out.AgeSeconds == nil || out.NextRefreshAt == nil || out.Stale {
+			t.Errorf("%s: freshness = %+v", path, out)
+		}
+		if !strings.HasPrefix(h.Provider.URL(), "http://"+out.Provider) {
+			t.Errorf("%s: provider = %q, want the provider host", path, out.Provider)
+		}
+	}
+
+	staleAfter := func(policy string) testutil.Option {
+		return func(c *config.Config) {
+			c.RateMaxAge = time.Millisecond
+			c.RateStalePolicy = policy
+		}
+	}
+
+	served := testutil.New(t, staleAfter(config.StalePolicyServe))
+	served.WaitReady()
+	served.Register("hal@example.com", "password123")
+	token = served.Login("hal@example.com", "password123")
+	rec := served.Do(http.MethodGet, "/api/v1/rates", nil, token)
+	var out freshness
+	served.Decode(rec, &out)
+	if rec.Code != http.StatusOK || !out.Stale {
+		t.Errorf("serve policy: status %d, stale %v, want 200 and stale", rec.Code, out.Stale)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "private, max-age=0" {
+		t.Errorf("stale Cache-Control = %q", cc)
+	}
+
+	refused := testutil.New(t, staleAfter(config.StalePolicyRefuse))
+	refused.WaitReady()
+	refused.Register("ivy@example.com", "password123")
+	token = refused.Login("ivy@example.com", "password123")
+	for _, path := range []string{"/api/v1/rates", "/api/v1/convert?from=USD&to=THB&amount=1"} {
+		rec := refused.Do(http.MethodGet, path, nil, token)
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "rates_stale") {
+			t.Errorf("refuse policy %s: status %d: %s", path, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Retry-After") == "" {
+			t.Errorf("refuse policy %s: missing Retry-After", path)
+		}
+	}
+}
+
+func TestAdminRefreshAndOverrides(t *testing.T) {
+	h := testutil.New(t, func(c *config.Config) { c.AdminEmails = []string{"root@example.com"} })
+	h.WaitReady()
+	h.Register("root@example.com", "password123")
+	admin := h.Login("root@example.com", "password123")
+	h.Register("kim@example.com", "password123")
+	user := h.Login("kim@example.com", "password123")
+
+	if rec := h.Do(http.MethodPost, "/api/v1/admin/rates/refresh", nil, user); rec.Code != http.StatusForbidden {
+		t.Errorf("refresh as regular user: status %d, want 403", rec.Code)
+	}
+
+	h.Provider.FailWith(http.StatusUnauthorized)
+	rec := h.Do(http.MethodPost, "/api/v1/admin/rates/refresh", nil, admin)
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "refresh_failed") {
+		t.Errorf("failed refresh: status %d: %s", rec.Code, rec.Body.String())
+	}
+	h.Provider.FailWith(http.StatusOK)
+	h.Provider.SetRates("USD", map[string]float64{"USD": 1, "THB": 40, "EUR": 0.9, "JPY": 150})
+	rec = h.Do(http.MethodPost, "/api/v1/admin/rates/refresh", nil, admin)
+	var refreshed struct {
+		Base  string `json:"base"`
+		Count int    `json:"count"`
+	}
+	h.Decode(rec, &refreshed)
+	if rec.Code != http.StatusOK || refreshed.Base != "USD" || refreshed.Count != 4 {
+		t.Fatalf("refresh: status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	type ratesBody struct {
+		Rates     map[string]float64 `json:"rates"`
+		Overrides []string           `json:"overrides"`
+	}
+	getRates := func() (ratesBody, string) {
+		rec := h.Do(http.MethodGet, "/api/v1/rates", nil, user)
+		var out ratesBody
+		h.Decode(rec, &out)
+		return out, rec.Header().Get("ETag")
+	}
+	before, etag := getRates()
+	if before.Rates["THB"] != 40 || before.Overrides != nil {
+		t.Fatalf("rates after refresh = %+v", before)
+	}
+
+	put := func(cur string, body map[string]interface{}) *httptest.ResponseRecorder {
+		return h.Do(http.MethodPut, "/api/v1/admin/rates/overrides/"+cur, body, admin)
+	}
+	for name, body := range map[string]map[string]interface{}{
+		"no expiry":     {"rate": 35, "reason": "upstream glitch"},
+		"no reason":     {"rate": 35, "ttl": "1h"},
+		"negative rate": {"rate": -1, "reason": "upstream glitch", "ttl": "1h"},
+		"unknown base":  {"rate": 35, "base": "XYZ", "reason": "upstream glitch", "ttl": "1h"},
+	} {
+		if rec := put("THB", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := put("THB", map[string]interface{}{"rate": 35, "reason": "upstream glitch", "ttl": "1h"}); rec.Code != http.StatusOK {
+		t.Fatalf("set THB override: status %d: %s", rec.Code, rec.Body.String())
+	}
+	// 160 JPY per EUR is 144 JPY per USD at 0.9 EUR per USD.
+	if rec := put("JPY", map[string]interface{}{"rate": 160, "base": "EUR", "reason": "pegged", "ttl": "1h"}); rec.Code != http.StatusOK {
+		t.Fatalf("set JPY override: status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	after, newETag := getRates()
+	if after.Rates["THB"] != 35 || math.Abs(after.Rates["JPY"]-144) > 1e-9 || strings.Join(after.Overrides, ",") != "JPY,THB" {
+		t.Errorf("rates with overrides = %+v", after)
+	}
+	if newETag == etag {
+		t.Error("ETag unchanged after setting overrides")
+	}
+	var conv struct {
+		Result float64 `json:"result"`
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/convert?from=USD&to=THB&amount=2", nil, user), &conv)
+	if conv.Result != 70 {
+		t.Errorf("convert with override = %v, want 70", conv.Result)
+	}
+
+	var list struct {
+		Overrides []struct {
+			Currency string `json:"currency"`
+			Reason   string `json:"reason"`
+		} `json:"overrides"`
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/admin/rates/overrides", nil, admin), &list)
+	if len(list.Overrides) != 2 || list.Overrides[0].Currency != "JPY" || list.Overrides[1].Reason != "upstream glitch" {
+		t.Errorf("overrides = %+v", list.Overrides)
+	}
+
+	if rec := h.Do(http.MethodDelete, "/api/v1/admin/rates/overrides/THB", nil, admin); rec.Code != http.StatusNoContent {
+		t.Errorf("delete override: status %d", rec.Code)
+	}
+	if rec := h.Do(http.MethodDelete, "/api/v1/admin/rates/overrides/THB", nil, admin); rec.Code != http.StatusNotFound {
+		t.Errorf("delete missing override: status %d, want 404", rec.Code)
+	}
+	if got, _ := getRates(); got.Rates["THB"] != 40 {
+		t.Errorf("THB after delete = %v, want the provider rate", got.Rates["THB"])
+	}
+
+	// Overrides lapse on their own.
+	if rec := put("EUR", map[string]interface{}{"rate": 1, "reason": "short", "ttl": "30ms"}); rec.Code != http.StatusOK {
+		t.Fatalf("set EUR override: status %d: %s", rec.Code, rec.Body.String())
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got, _ := getRates(); got.Rates["EUR"] != 0.9 || strings.Join(got.Overrides, ",") != "JPY" {
+		t.Errorf("rates after expiry = %+v", got)
+	}
+
+	var audit struct {
+		Total int `json:"total"`
+	}
+	for action, want := range map[string]int{"rates.refresh": 2, "rates.override_set": 5, "rates.override_deleted": 1} {
+		h.Decode(h.Do(http.MethodGet, "/api/v1/admin/audit-events?action="+action, nil, admin), &audit)
+		if audit.Total != want {
+			t.Errorf("%s audit events = %d, want %d", action, audit.Total, want)
+		}
+	}
+}
+
+func TestRateValidationAndQuarantine(t *testing.T) {
+	h := testutil.New(t, func(c *config.Config) {
+		c.AdminEmails = []string{"root@example.com"}
+		c.RateMaxChange = 0.5
+		c.RateRequiredCurrencies = []string{"USD", "EUR"}
+	})
+	h.WaitReady()
+	h.Register("root@example.com", "password123")
+	admin := h.Login("root@example.com", "password123")
+
+	thb := func() float64 {
+		var out struct {
+			Rates map[string]float64 `json:"rates"`
+		}
+		h.Decode(h.Do(http.MethodGet, "/api/v1/rates", nil, admin), &out)
+		return out.Rates["THB"]
+	}
+	refresh := func(rates map[string]float64) *httptest.ResponseRecorder {
+		h.Provider.SetRates("USD", rates)
+		return h.Do(http.MethodPost, "/api/v1/admin/rates/refresh", nil, admin)
+	}
+
+	for name, rates := range map[string]map[string]float64{
+		"zero rate":        {"USD": 1, "EUR": 0.92, "THB": 0},
+		"missing required": {"USD": 1, "THB": 36},
+	} {
+		if rec := refresh(rates); rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "rates_rejected") {
+			t.Errorf("%s: status %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if got := thb(); got != 36.5 {
+		t.Errorf("THB after rejected refreshes = %v, want 36.5", got)
+	}
+
+	// THB more than doubles: quarantined, the last good rates stay served.
+	if rec := refresh(map[string]float64{"USD": 1, "EUR": 0.92, "THB": 80}); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "rates_quarantined") {
+		t.Fatalf("anomalous refresh: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := thb(); got != 36.5 {
+		t.Errorf("THB while quarantined = %v, want 36.5", got)
+	}
+
+	type quarantine struct {
+		ID        uint   `json:"id"`
+		Status    string `json:"status"`
+		Anomalies []struct {
+			Currency string  `json:"currency"`
+			Change   float64 `json:"change"`
+		} `json:"anomalies"`
+	}
+	var list struct {
+		Quarantines []quarantine `json:"quarantines"`
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/admin/rates/quarantine?status=pending", nil, admin), &list)
+	if len(list.Quarantines) != 1 || len(list.Quarantines[0].Anomalies) != 1 || list.Quarantines[0].Anomalies[0].Currency != "THB" {
+		t.Fatalf("pending quarantines = %+v", list.Quarantines)
+	}
+	first := list.Quarantines[0].ID
+
+	var ready struct {
+		Checks map[string]struct {
+			Details map[string]interface{} `json:"details"`
+		} `json:"checks"`
+	}
+	h.Decode(h.Do(http.MethodGet, "/readyz", nil, ""), &ready)
+	if ready.Checks["rates"].Details["pending_quarantine"] != float64(first) {
+		t.Errorf("readiness details = %+v", ready.Checks["rates"].Details)
+	}
+
+	// A newer anomalous fetch supersedes the pending one.
+	if rec := refresh(map[string]float64{"USD": 1, "EUR": 0.92, "THB": 90}); rec.Code != http.StatusConflict {
+		t.Fatalf("second anomalous refresh: status %d", rec.Code)
+	}
+	if rec := h.Do(http.MethodPost, fmt.Sprintf("/api/v1/admin/rates/quarantine/%d/approve", first), nil, admin); rec.Code != http.StatusConflict {
+		t.Errorf("approve superseded: status %d, want 409", rec.Code)
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/admin/rates/quarantine?status=pending", nil, admin), &list)
+	if len(list.Quarantines) != 1 {
+		t.Fatalf("pending quarantines = %+v", list.Quarantines)
+	}
+	second := list.Quarantines[0].ID
+
+	var approved quarantine
+	rec := h.Do(http.MethodPost, fmt.Sprintf("/api/v1/admin/rates/quarantine/%d/approve", second), nil, admin)
+	h.Decode(rec, &approved)
+	if rec.Code != http.StatusOK || approved.Status != "approved" {
+		t.Fatalf("approve: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := thb(); got != 90 {
+		t.Errorf("THB after approval = %v, want 90", got)
+	}
+	if rec := h.Do(http.MethodPost, fmt.Sprintf("/api/v1/admin/rates/quarantine/%d/reject", second), nil, admin); rec.Code != http.StatusConflict {
+		t.Errorf("reject approved: status %d, want 409", rec.Code)
+	}
+	if rec := h.Do(http.MethodPost, "/api/v1/admin/rates/quarantine/999/reject", nil, admin); rec.Code != http.StatusNotFound {
+		t.Errorf("reject missing: status %d, want 404", rec.Code)
+	}
+
+	// Rejecting keeps the approved rates.
+	if rec := refresh(map[string]float64{"USD": 1, "EUR": 0.92, "THB": 10}); rec.Code != http.StatusConflict {
+		t.Fatalf("third anomalous refresh: status %d", rec.Code)
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/admin/rates/quarantine?status=pending", nil, admin), &list)
+	if len(list.Quarantines) != 1 {
+		t.Fatalf("pending quarantines = %+v", list.Quarantines)
+	}
+	if rec := h.Do(http.MethodPost, fmt.Sprintf("/api/v1/admin/rates/quarantine/%d/reject", list.Quarantines[0].ID), nil, admin); rec.Code != http.StatusOK {
+		t.Errorf("reject: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := thb(); got != 90 {
+		t.Errorf("THB after rejection = %v, want 90", got)
+	}
+
+	var audit struct {
+		Total int `json:"total"`
+	}
+	for action, want := range map[string]int{"rates.quarantine_approved": 2, "rates.quarantine_rejected": 2} {
+		h.Decode(h.Do(http.MethodGet, "/api/v1/admin/audit-events?action="+action, nil, admin), &audit)
+		if audit.Total != want {
+			t.Errorf("%s audit events = %d, want %d", action, audit.Total, want)
+		}
+	}
+}
+
+// webhookReceiver records the calls it gets and answers with status.
+type webhookReceiver struct {
+	*httptest.Server
+	mu     sync.Mutex
+	status int
+	calls  []webhookCall
+}
+
+type webhookCall struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T, status int) *webhookReceiver {
+	r := &webhookReceiver{status: status}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.calls = append(r.calls, webhookCall{header: req.Header.Clone(), body: body})
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *webhookReceiver) received() []webhookCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.calls)
+}
+
+func TestWebhooksToInternalAddressesRefused(t *testing.T) {
+	h := testutil.New(t, func(c *config.Config) { c.WebhookAllowedNetworks = nil })
+	h.WaitReady()
+	h.Register("kim@example.com", "password123")
+	user := h.Login("kim@example.com", "password123")
+	receiver := newWebhookReceiver(t, http.StatusNoContent)
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(receiver.URL, "http://"))
+
+	create := func(url string) *httptest.ResponseRecorder {
+		return h.Do(http.MethodPost, "/api/v1/alerts", map[string]interface{}{"from": "USD", "to": "THB", "condition": "above", "threshold": 37, "webhook_url": url}, user)
+	}
+	for _, url := range []string{receiver.URL, "http://10.0.0.1/hook", "http://[::1]:" + port, "http://169.254.169.254/latest/meta-data"} {
+		if rec := create(url); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", url, rec.Code)
+		}
+	}
+
+	// A host name passes validation but is checked again once resolved.
+	rec := create("http://localhost:" + port)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var rule struct {
+		ID uint `json:"id"`
+	}
+	h.Decode(rec, &rule)
+	var tested struct {
+		Status    string `json:"status"`
+		LastError string `json:"last_error"`
+	}
+	h.Decode(h.Do(http.MethodPost, fmt.Sprintf("/api/v1/alerts/%d/test", rule.ID), nil, user), &tested)
+	if tested.Status == "delivered" || !strings.Contains(tested.LastError, "private, loopback or link-local") {
+		t.Errorf("test delivery = %+v, want it refused", tested)
+	}
+	if n := len(receiver.received()); n != 0 {
+		t.Errorf("receiver got %d calls", n)
+	}
+}
+
+func TestRateAlertsAndWebhooks(t *testing.T) {
+	h := testutil.New(t, func(c *config.Config) { c.AdminEmails = []string{"root@example.com"} })
+	h.WaitReady()
+	h.Register("root@example.com", "password123")
+	admin := h.Login("root@example.com", "password123")
+	h.Register("kim@example.com", "password123")
+	user := h.Login("kim@example.com", "password123")
+	h.Register("lee@example.com", "password123")
+	other := h.Login("lee@example.com", "password123")
+
+	ok := newWebhookReceiver(t, http.StatusNoContent)
+	broken := newWebhookReceiver(t, http.StatusInternalServerError)
+
+	type alert struct {
+		ID            uint   `json:"id"`
+		Cooldown      string `json:"cooldown"`
+		WebhookSecret string `json:"webhook_secret"`
+	}
+	create := func(body map[string]interface{}) (alert, *httptest.ResponseRecorder) {
+		rec := h.Do(http.MethodPost, "/api/v1/alerts", body, user)
+		var a alert
+		if rec.Code == http.StatusCreated {
+			h.Decode(rec, &a)
+		}
+		return a, rec
+	}
+	for name, body := range map[string]map[string]interface{}{
+		"bad condition": {"from": "USD", "to": "THB", "condition": "sideways", "threshold": 36, "webhook_url": ok.URL},
+		"same pair":     {"from": "USD", "to": "usd", "condition": "above", "threshold": 36, "webhook_url": ok.URL},
+		"bad url":       {"from": "USD", "to": "THB", "condition": "above", "threshold": 36, "webhook_url": "ftp://example.com"},
+		"unknown cur":   {"from": "USD", "to": "XYZ", "condition": "above", "threshold": 36, "webhook_url": ok.URL},
+		"bad cooldown":  {"from": "USD", "to": "THB", "condition": "above", "threshold": 36, "cooldown": "soon", "webhook_url": ok.URL},
+	} {
+		if _, rec := create(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+
+	above, rec := create(map[string]interface{}{"from": "USD", "to": "THB", "condition": "above", "threshold": 37, "cooldown": "1h", "webhook_url": ok.URL})
+	if rec.Code != http.StatusCreated || !strings.HasPrefix(above.WebhookSecret, "whsec_") || above.Cooldown != "1h0m0s" {
+		t.Fatalf("create above alert: status %d: %s", rec.Code, rec.Body.String())
+	}
+	change, _ := create(map[string]interface{}{"from": "EUR", "to": "JPY", "condition": "change", "threshold": 1, "webhook_url": broken.URL})
+
+	var list struct {
+		Alerts []alert `json:"alerts"`
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/alerts", nil, user), &list)
+	if len(list.Alerts) != 2 || list.Alerts[0].WebhookSecret != "" {
+		t.Errorf("alerts = %+v, want two without secrets", list.Alerts)
+	}
+	h.Decode(h.Do(http.MethodGet, "/api/v1/alerts", nil, other), &list)
+	if len(list.Alerts) != 0 {
+		t.Errorf("another user's alerts = %+v", list.Alerts)
+	}
+
+	type delivery struct {
+		ID       uint   `json:"id"`
+		RuleID   uint   `json:"rule_id"`
+		Event    string `json:"event"`
+		Status   string `json:"status"`
+		Attempts int    `json:"attempts"`
+		Payload  string `json:"payload"`
+	}
+	var tested delivery
+	h.Decode(h.Do(http.MethodPost, fmt.Sprintf("/api/v1/alerts/%d/test", above.ID), nil, user), &tested)
+	if tested.Status != "delivered" || tested.Event != "rate.alert.test" || len(ok.received()) != 1 {
+		t.Fatalf("test delivery = %+v", tested)
+	}
+	call := ok.received()[0]
+	if want := services.SignWebhook(above.WebhookSecret, call.header.Get("X-Webhook-Timestamp"), call.body); call.header.Get("X-Webhook-Signature") != want {
+		t.Errorf("signature %q, want %q", call.header.Get("X-Webhook-Signature"), want)
+	}
+	if rec := h.Do(http.MethodPost, fmt.Sprintf("/api/v1/alerts/%d/test", above.ID), nil, other); rec.Code != http.StatusNotFound {
+		t.Errorf("test another user's alert: status %d, want 404", rec.Code)
+	}
+
+	refresh := func(rates map[string]float64) {
+		t.Helper()
+		h.Provider.SetRates("USD", rates)
+		if rec := h.Do(http.MethodPost, "/api/v1/admin/rates/refresh", nil, admin); rec.Code != http.StatusOK {
+			t.Fatalf("refresh: status %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	// THB crosses 37 and EUR/JPY moves by about 2.5%.
+	refresh(map[string]float64{"USD": 1, "THB": 37.5, "EUR": 0.9, "JPY": 150.25})
+	h.WaitFor("alert delivery", func() bool { return len(ok.received()) == 2 })
+	var event struct {
+		Type          string  `json:"type"`
+		Rate          float64 `json:"rate"`
+		ReferenceRate float64 `json:"reference_rate"`
+		Rule          struct {
+			ID uint `json:"id"`
+		} `json:"rule"`
+	}
+	if err := json.Unmarshal(ok.received()[1].body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != "rate.alert" || event.Rate != 37.5 || event.ReferenceRate != 36.5 || event.Rule.ID != above.ID {
+		t.Errorf("alert event = %+v", event)
+	}
+
+	// The change alert's webhook fails every attempt and is dead-lettered.
+	deliveries := func(query string) []delivery {
+		var out struct {
+			Deliveries []delivery `json:"deliveries"`
+		}
+		h.Decode(h.Do(http.MethodGet, "/api/v1/alerts/deliveries"+query, nil, user), &out)
+		return out.Deliveries
+	}
+	h.WaitFor("dead letter", func() bool { return len(deliveries("?status=dead")) == 1 })
+	dead := deliveries("?status=dead")[0]
+	if dead.RuleID != change.ID || dead.Attempts != 3 || len(broken.received()) != 3 {
+		t.Errorf("dead delivery = %+v after %d calls", dead, len(broken.received()))
+	}
+
+	// Back under and over again within the cooldown: no new alert.
+	refresh(map[string]float64{"USD": 1, "THB": 36, "EUR": 0.9, "JPY": 150.25})
+	refresh(map[string]float64{"USD": 1, "THB": 38, "EUR": 0.9, "JPY": 150.25})
+	time.Sleep(50 * time.Millisecond)
+	if n := len(deliveries(fmt.Sprintf("?alert_id=%d", above.ID))); n != 2 {
+		t.Errorf("deliveries of the above alert = %d, want 2 (test and one alert)", n)
+	}
+
+	broken.mu.Lock()
+	broken.status = http.StatusOK
+	broken.mu.Unlock()
+	if rec := h.Do(http.MethodPost, fmt.Sprintf("/api/v1/alerts/deliveries/%d/retry", dead.ID), nil, user); rec.Code != http.StatusOK {
+		t.Fatalf("retry dead delivery: status %d: %s", rec.Code, rec.Body.String())
+	}
+	h.WaitFor("redelivery", func() bool { return len(deliveries("?status=delivered&alert_id="+strconv.Itoa(int(change.ID)))) == 1 })
+	if rec := h.Do(http.MethodPost, fmt.Sprintf("/api/v1/alerts/deliveries/%d/retry", dead.ID), nil, user); rec.Code != http.StatusConflict {
+		t.Errorf("retry delivered: status %d, want 409", rec.Code)
+	}
+
+	if rec := h.Do(http.MethodDelete, fmt.Sprintf("/api/v1/alerts/%d", change.ID), nil, other); rec.Code != http.StatusNotFound {
+		t.Errorf("delete another user's alert: status %d, want 404", rec.Code)
+	}
+	if rec := h.Do(http.MethodDelete, fmt.Sprintf("/api/v1/alerts/%d", change.ID), nil, user); rec.Code != http.StatusNoContent {
+		t.Errorf("delete alert: status %d", rec.Code)
+	}
+	if n := len(deliveries(fmt.Sprintf("?alert_id=%d", change.ID))); n != 0 {
+		t.Errorf("deliveries of a deleted alert = %d", n)
+	}
+}
@@ -0,0 +1,106 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// The FX week is defined in New York time, which must resolve even on
+	// hosts without a zoneinfo database.
+	_ "time/tzdata"
+)
+
+// Market calendars accepted by NewCalendar.
+const (
+	// CalendarAlways is open around the clock, except on holidays.
+	CalendarAlways = "always"
+	// CalendarFX follows the spot FX week: open from Sunday 17:00 to Friday
+	// 17:00 New York time, and closed on holidays.
+	CalendarFX = "fx"
+)
+
+const holidayLayout = "2006-01-02"
+
+// Calendar tells when a market is open.
+type Calendar struct {
+	name     string
+	fx       bool
+	newYork  *time.Location
+	loc      *time.Location
+	holidays map[string]bool
+}
+
+// NewCalendar returns the calendar called name, CalendarAlways when empty,
+// with holidays, given as YYYY-MM-DD, closed for the whole day in loc.
+func NewCalendar(name string, holidays []string, loc *time.Location) (*Calendar, error) {
+	if name == "" {
+		name = CalendarAlways
+	}
+	c := &Calendar{name: name, loc: loc, holidays: make(map[string]bool, len(holidays))}
+	switch name {
+	case CalendarAlways:
+	case CalendarFX:
+		ny, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			return nil, err
+		}
+		c.fx, c.newYork = true, ny
+	default:
+		return nil, fmt.Errorf("unknown market calendar %q: use %s or %s", name, CalendarAlways, CalendarFX)
+	}
+	for _, h := range holidays {
+		h = strings.TrimSpace(h)
+		if _, err := time.ParseInLocation(holidayLayout, h, loc); err != nil {
+			return nil, fmt.Errorf("invalid holiday %q: want YYYY-MM-DD", h)
+		}
+		c.holidays[h] = true
+	}
+	return c, nil
+}
+
+// Open reports whether the market is open at t.
+func (c *Calendar) Open(t time.Time) bool {
+	if c.holidays[t.In(c.loc).Format(holidayLayout)] {
+		return false
+	}
+	if !c.fx {
+		return true
+	}
+	ny := t.In(c.newYork)
+	switch ny.Weekday() {
+	case time.Saturday:
+		return false
+	case time.Friday:
+		return ny.Hour() < 17
+	case time.Sunday:
+		return ny.Hour() >= 17
+	}
+	return true
+}
+
+// NextOpen returns the first time at or after t at which the market is
+// open, or the zero time if it stays closed for more than a year.
+func (c *Calendar) NextOpen(t time.Time) time.Time {
+	end := t.AddDate(1, 0, 0)
+	for t.Before(end) {
+		if c.Open(t) {
+			return t
+		}
+		local := t.In(c.loc)
+		if c.holidays[local.Format(holidayLayout)] {
+			t = time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		// Closed for the weekend: the week opens on Sunday at 17:00.
+		ny := t.In(c.newYork)
+		days := (7 - int(ny.Weekday())) % 7
+		t = time.Date(ny.Year(), ny.Month(), ny.Day()+days, 17, 0, 0, 0, c.newYork)
+	}
+	return time.Time{}
+}
+
+func (c *Calendar) String() string {
+	if len(c.holidays) == 0 {
+		return c.name
+	}
+	return fmt.Sprintf("%s, %d holidays", c.name, len(c.holidays))
+}
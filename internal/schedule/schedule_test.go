@@ -0,0 +1,90 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/schedule"
+)
+
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2025-01-03 is a Friday.
+	fri := func(h, m int) time.Time { return time.Date(2025, 1, 3, h, m, 30, 0, time.UTC) }
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"5 16 * * 1-5", fri(10, 0), time.Date(2025, 1, 3, 16, 5, 0, 0, time.UTC)},
+		{"5 16 * * 1-5", fri(16, 5), time.Date(2025, 1, 6, 16, 5, 0, 0, time.UTC)},
+		{"*/15 * * * *", fri(10, 7), time.Date(2025, 1, 3, 10, 15, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", fri(13, 0), time.Date(2025, 1, 3, 17, 0, 0, 0, time.UTC)},
+		{"30 6 1,15 * *", fri(0, 0), time.Date(2025, 1, 15, 6, 30, 0, 0, time.UTC)},
+		{"0 0 * FEB SUN", fri(0, 0), time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", fri(0, 0), time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches.
+		{"0 12 10 * MON", fri(0, 0), time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)},
+		{"@daily", fri(23, 59), time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"5 16 * * *", fri(10, 0).In(berlin), time.Date(2025, 1, 3, 16, 5, 0, 0, berlin)},
+		{"0 0 30 2 *", fri(0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		c, err := schedule.ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.expr, err)
+		}
+		if got := c.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q after %s = %s, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestParseCronRejects(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "* * * * FUNDAY"} {
+		if _, err := schedule.ParseCron(expr); err == nil {
+			t.Errorf("%q was accepted", expr)
+		}
+	}
+}
+
+func TestFXCalendar(t *testing.T) {
+	cal, err := schedule.NewCalendar(schedule.CalendarFX, []string{"2025-01-01"}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ny, _ := time.LoadLocation("America/New_York")
+	tests := []struct {
+		at       time.Time
+		open     bool
+		nextOpen time.Time
+	}{
+		{time.Date(2025, 1, 3, 16, 59, 0, 0, ny), true, time.Date(2025, 1, 3, 16, 59, 0, 0, ny)},
+		{time.Date(2025, 1, 3, 17, 0, 0, 0, ny), false, time.Date(2025, 1, 5, 17, 0, 0, 0, ny)},
+		{time.Date(2025, 1, 4, 12, 0, 0, 0, ny), false, time.Date(2025, 1, 5, 17, 0, 0, 0, ny)},
+		{time.Date(2025, 1, 5, 16, 0, 0, 0, ny), false, time.Date(2025, 1, 5, 17, 0, 0, 0, ny)},
+		{time.Date(2025, 1, 6, 3, 0, 0, 0, ny), true, time.Date(2025, 1, 6, 3, 0, 0, 0, ny)},
+		// New Year's Day is a holiday in UTC.
+		{time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), false, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := cal.Open(tt.at); got != tt.open {
+			t.Errorf("Open(%s) = %v, want %v", tt.at, got, tt.open)
+		}
+		if got := cal.NextOpen(tt.at); !got.Equal(tt.nextOpen) {
+			t.Errorf("NextOpen(%s) = %s, want %s", tt.at, got, tt.nextOpen)
+		}
+	}
+}
+
+func TestNewCalendarRejects(t *testing.T) {
+	if _, err := schedule.NewCalendar("nyse", nil, time.UTC); err == nil {
+		t.Error("unknown calendar was accepted")
+	}
+	if _, err := schedule.NewCalendar(schedule.CalendarAlways, []string{"25/12/2025"}, time.UTC); err == nil {
+		t.Error("malformed holiday was accepted")
+	}
+}
@@ -16,16 +16,18 @@ import (
 	"github.com/spksupakorn/Currency-Converter/database"
 	"github.com/spksupakorn/Currency-Converter/docs"
 	"github.com/spksupakorn/Currency-Converter/internal/controllers"
-	"github.com/spksupakorn/Currency-Converter/internal/middleware"
+	"github.com/spksupakorn/Currency-Converter/internal/lifecycle"
 	"github.com/spksupakorn/Currency-Converter/internal/router"
 	"github.com/spksupakorn/Currency-Converter/pkg/logger"
 )
 
 type ginServer struct {
-	app *gin.Engine
-	log *logger.Logger
-	db  database.Database
-	cfg config.Config
+	app      *gin.Engine
+	log      *logger.Logger
+	db       database.Database
+	cfg      config.Config
+	settings *config.Reloader
+	lc       *lifecycle.Lifecycle
 }
 
 var (
@@ -33,7 +35,10 @@ var (
 	app  *ginServer
 )
 
-func NewGinServer(db database.Database, log *logger.Logger, cfg config.Config) Server {
+// NewGinServer serves the API until SIGINT or SIGTERM, then drains requests
+// and stops lc, which owns the background workers and the database.
+func NewGinServer(db database.Database, log *logger.Logger, settings *config.Reloader, lc *lifecycle.Lifecycle) Server {
+	cfg := settings.Current()
 	if cfg.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -41,24 +46,47 @@ func NewGinServer(db database.Database, log *logger.Logger, cfg config.Config) S
 
 	once.Do(func() {
 		app = &ginServer{
-			app: ginApp,
-			log: log,
-			db:  db,
-			cfg: cfg,
+			app:      ginApp,
+			log:      log,
+			db:       db,
+			cfg:      cfg,
+			settings: settings,
+			lc:       lc,
 		}
 	})
 	return app
 }
 
-func (s *ginServer) Start() {
-	s.app.Use(middleware.Recovery(s.log))
-	s.app.Use(middleware.RequestID())
-	s.app.Use(middleware.RequestLogger(s.log))
-	s.app.Use(middleware.SecurityHeaders())
-	s.app.Use(middleware.RateLimit(s.cfg))
+func (s *ginServer) Start() error {
+	router.UseMiddleware(s.app, s.log, s.settings, s.lc)
 
-	s.initRoutes()
+	if err := s.initRoutes(); err != nil {
+		return err
+	}
+	s.lc.Go("settings watcher", func(ctx context.Context) {
+		s.settings.Watch(ctx, s.cfg.ConfigFile, s.cfg.ConfigWatchInterval, s.logReload)
+	})
 	s.httpListenAndServe()
+	return nil
+}
+
+func (s *ginServer) logReload(changes []config.Change, err error) {
+	if err != nil {
+		s.log.Error("settings reload rejected, keeping current settings", zapErr(err))
+		return
+	}
+	if len(changes) == 0 {
+		s.log.Info("settings reloaded, nothing changed")
+		return
+	}
+	for _, ch := range changes {
+		fields := logger.Fields{"setting": ch.Key, "old": ch.Old, "new": ch.New}
+		if ch.Applied {
+			s.log.Info("setting changed", fields)
+		} else {
+			s.log.Warn("setting changed but requires a restart", fields)
+		}
+	}
 }
 
 func (s *ginServer) httpListenAndServe() {
@@ -85,16 +113,23 @@ func (s *ginServer) httpListenAndServe() {
 	<-quit
 
 	s.log.Info("server shutting down...")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
 	defer cancel()
+	// Requests first, as they may still queue work for the workers; then the
+	// workers, and the database they write to last.
 	if err := srv.Shutdown(ctx); err != nil {
 		s.log.Error("server shutdown error", zapErr(err))
 	}
+	if err := s.lc.Stop(ctx); err != nil {
+		s.log.Error("background workers shutdown error", zapErr(err))
+	}
 	s.log.Info("server exited")
 }
 
-func (s *ginServer) initRoutes() {
-	router.NewRouter(s.db.ConnectDB(), s.log, s.cfg, s.app)
+func (s *ginServer) initRoutes() error {
+	if err := router.NewRouter(s.db, s.log, s.settings, s.app, s.lc); err != nil {
+		return err
+	}
 
 	// Swagger setup
 	docs.SwaggerInfo.Title = "Currency Converter API Documentation"
@@ -114,6 +149,7 @@ func (s *ginServer) initRoutes() {
 
 	// Serve RapiDoc HTML
 	s.app.GET("/docs", controllers.RapiDoc)
+	return nil
 }
 
 func zapErr(err error) logger.Fields {
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAlertNotFound    = errors.New("alert not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	// ErrDeliveryNotDead is returned when retrying a delivery that has not
+	// been dead-lettered.
+	ErrDeliveryNotDead = errors.New("only dead deliveries can be retried")
+)
+
+const (
+	defaultAlertWindow = 24 * time.Hour
+	maxAlertDuration   = 30 * 24 * time.Hour
+	maxDeliveryList    = 100
+)
+
+type AlertService interface {
+	// RunEvaluator evaluates published rates until ctx is cancelled, and
+	// the table still queued then before it returns.
+	RunEvaluator(ctx context.Context)
+	// RunDispatcher sends due webhook deliveries until ctx is cancelled.
+	// Only one instance should run it.
+	RunDispatcher(ctx context.Context)
+	// Evaluate queues published rates for evaluation against every rule
+	// without blocking; only the latest table is kept. It is meant to be
+	// registered with RateService.OnPublish.
+	Evaluate(p PublishedRates)
+
+	// CreateRule validates rule and stores it with a new webhook secret,
+	// which is only returned here.
+	CreateRule(ctx context.Context, rule models.AlertRule) (models.AlertRule, error)
+	ListRules(ctx context.Context, userID uint) ([]models.AlertRule, error)
+	DeleteRule(ctx context.Context, userID, id uint) error
+	// TestRule sends a signed test event to the rule's webhook right away,
+	// once, and returns the logged delivery.
+	TestRule(ctx context.Context, userID, id uint) (models.AlertDelivery, error)
+
+	ListDeliveries(ctx context.Context, f repositories.DeliveryFilter) ([]models.AlertDelivery, error)
+	// RetryDelivery queues a dead delivery again with a fresh set of
+	// attempts.
+	RetryDelivery(ctx context.Context, userID, id uint) (models.AlertDelivery, error)
+}
+
+type alertService struct {
+	cfg     config.Config
+	repo    repositories.AlertRepository
+	rates   RateService
+	log     *logger.Logger
+	webhook *webhookClient
+
+	// latest is the newest rate table not evaluated yet; evaluate and
+	// deliver wake the two workers.
+	latest   atomic.Pointer[PublishedRates]
+	evaluate chan struct{}
+	deliver  chan struct{}
+}
+
+func NewAlertService(cfg config.Config, repo repositories.AlertRepository, rates RateService, log *logger.Logger) AlertService {
+	return &alertService{
+		cfg:      cfg,
+		repo:     repo,
+		rates:    rates,
+		log:      log,
+		webhook:  newWebhookClient(cfg.WebhookTimeout, cfg.WebhookAllowedNetworks),
+		evaluate: make(chan struct{}, 1),
+		deliver:  make(chan struct{}, 1),
+	}
+}
+
+func (s *alertService) Evaluate(p PublishedRates) {
+	s.latest.Store(&p)
+	wake(s.evaluate)
+}
+
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func (s *alertService) CreateRule(ctx context.Context, rule models.AlertRule) (models.AlertRule, error) {
+	rule.FromCurrency = normalizeCurrency(rule.FromCurrency)
+	rule.ToCurrency = normalizeCurrency(rule.ToCurrency)
+	if rule.Condition == models.AlertChange && rule.Window == 0 {
+		rule.Window = defaultAlertWindow
+	}
+	if err := validateAlertRule(rule, s.cfg.WebhookAllowedNetworks); err != nil {
+		return rule, err
+	}
+	n, err := s.repo.CountRules(ctx, rule.UserID)
+	if err != nil {
+		return rule, err
+	}
+	if n >= int64(s.cfg.AlertMaxRules) {
+		return rule, fmt.Errorf("at most %d alerts are allowed per user", s.cfg.AlertMaxRules)
+	}
+
+	now := time.Now().UTC()
+	// Start from the current rate so that a rule only fires on a later
+	// crossing or move; without rates yet, the first refresh sets it.
+	if rates, _, err := s.rates.GetRates(ctx, ""); err == nil {
+		from, okFrom := rates[rule.FromCurrency]
+		to, okTo := rates[rule.ToCurrency]
+		switch {
+		case !okFrom:
+			return rule, fmt.Errorf("unsupported currency: %s", rule.FromCurrency)
+		case !okTo:
+			return rule, fmt.Errorf("unsupported currency: %s", rule.ToCurrency)
+		}
+		rule.LastRate = to / from
+		rule.BaselineRate, rule.BaselineAt = rule.LastRate, now
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return rule, err
+	}
+	rule.ID = 0
+	rule.WebhookSecret = "whsec_" + hex.EncodeToString(secret)
+	rule.LastTriggeredAt = nil
+	rule.CreatedAt = now
+	if err := s.repo.CreateRule(ctx, &rule); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+func validateAlertRule(r models.AlertRule, allowed []netip.Prefix) error {
+	switch {
+	case len(r.FromCurrency) != 3 || len(r.ToCurrency) != 3:
+		return errors.New("from and to must be 3-letter currency codes")
+	case r.FromCurrency == r.ToCurrency:
+		return errors.New("from and to must differ")
+	case r.Threshold <= 0 || math.IsNaN(r.Threshold) || math.IsInf(r.Threshold, 0):
+		return errors.New("threshold must be a positive number")
+	case r.Cooldown < 0 || r.Cooldown > maxAlertDuration:
+		return fmt.Errorf("cooldown must be between 0 and %s", maxAlertDuration)
+	}
+	switch r.Condition {
+	case models.AlertAbove, models.AlertBelow:
+	case models.AlertChange:
+		if r.Window < time.Minute || r.Window > maxAlertDuration {
+			return fmt.Errorf("window must be between 1m and %s", maxAlertDuration)
+		}
+	default:
+		return errors.New("condition must be above, below or change")
+	}
+	u, err := url.Parse(r.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook_url must be an absolute http or https URL")
+	}
+	// Hosts that resolve to such an address are refused when delivering.
+	if ip, err := netip.ParseAddr(strings.Trim(u.Hostname(), "[]")); err == nil && blockedWebhookAddr(ip, allowed) {
+		return errors.New("webhook_url must not point to a private, loopback or link-local address")
+	}
+	return nil
+}
+
+func (s *alertService) ListRules(ctx context.Context, userID uint) ([]models.AlertRule, error) {
+	return s.repo.ListRules(ctx, userID)
+}
+
+func (s *alertService) DeleteRule(ctx context.Context, userID, id uint) error {
+	found, err := s.repo.DeleteRule(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrAlertNotFound
+	}
+	return nil
+}
+
+func (s *alertService) TestRule(ctx context.Context, userID, id uint) (models.AlertDelivery, error) {
+	rule, err := s.repo.FindRule(ctx, userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.AlertDelivery{}, ErrAlertNotFound
+	}
+	if err != nil {
+		return models.AlertDelivery{}, err
+	}
+	now := time.Now().UTC()
+	d, err := newDelivery(rule, models.EventAlertTest, alertEvent{
+		Type:      models.EventAlertTest,
+		CreatedAt: now,
+		Rule:      newAlertEventRule(rule),
+		Rate:      rule.LastRate,
+	}, now)
+	if err != nil {
+		return models.AlertDelivery{}, err
+	}
+	// Not due before the call made here has timed out, so the dispatcher
+	// does not send it too.
+	d.NextAttemptAt = now.Add(2 * s.cfg.WebhookTimeout)
+	if err := s.repo.CreateDelivery(ctx, d); err != nil {
+		return *d, err
+	}
+	// A test is attempted once: the caller sees the outcome right away.
+	s.attempt(ctx, rule, d, 1)
+	return *d, nil
+}
+
+func (s *alertService) ListDeliveries(ctx context.Context, f repositories.DeliveryFilter) ([]models.AlertDelivery, error) {
+	switch f.Status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		return nil, errors.New("status must be pending, delivered or dead")
+	}
+	if f.Limit <= 0 || f.Limit > maxDeliveryList {
+		f.Limit = maxDeliveryList
+	}
+	return s.repo.ListDeliveries(ctx, f)
+}
+
+func (s *alertService) RetryDelivery(ctx context.Context, userID, id uint) (models.AlertDelivery, error) {
+	d, err := s.repo.FindDelivery(ctx, userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.AlertDelivery{}, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return models.AlertDelivery{}, err
+	}
+	if d.Status != models.DeliveryDead {
+		return *d, ErrDeliveryNotDead
+	}
+	d.Status = models.DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now().UTC()
+	if err := s.repo.UpdateDelivery(ctx, d); err != nil {
+		return *d, err
+	}
+	wake(s.deliver)
+	return *d, nil
+}
+
+func (s *alertService) RunEvaluator(ctx context.Context) {
+	for {
+		select {
+		case <-s.evaluate:
+			if p := s.latest.Swap(nil); p != nil {
+				s.evaluateRules(ctx, *p)
+			}
+		case <-ctx.Done():
+			// Detached from ctx so that rates published just before
+			// shutdown still queue their deliveries.
+			if p := s.latest.Swap(nil); p != nil {
+				s.evaluateRules(context.WithoutCancel(ctx), *p)
+			}
+			return
+		}
+	}
+}
+
+// evaluateRules checks every rule against p, saving each rule's state and
+// queueing a delivery for those that fired.
+func (s *alertService) evaluateRules(ctx context.Context, p PublishedRates) {
+	rules, err := s.repo.AllRules(ctx)
+	if err != nil {
+		s.log.Error("failed to load alert rules", logger.Fields{"error": err.Error()})
+		return
+	}
+	now := time.Now().UTC()
+	fired := 0
+	for i := range rules {
+		rule := &rules[i]
+		from, to := p.Rates[rule.FromCurrency], p.Rates[rule.ToCurrency]
+		if from == 0 || to == 0 {
+			continue
+		}
+		rate := to / from
+		ref, fire := checkAlert(rule, rate, now)
+		var d *models.AlertDelivery
+		if fire {
+			d, err = newDelivery(rule, models.EventRateAlert, alertEvent{
+				Type:           models.EventRateAlert,
+				CreatedAt:      now,
+				Rule:           newAlertEventRule(rule),
+				Rate:           rate,
+				ReferenceRate:  ref,
+				ChangePercent:  (rate - ref) / ref * 100,
+				RatesFetchedAt: p.FetchedAt,
+			}, now)
+			if err != nil {
+				s.log.Error("failed to build alert event", logger.Fields{"rule_id": rule.ID, "error": err.Error()})
+				continue
+			}
+		}
+		if err := s.repo.UpdateRuleState(ctx, rule, d); err != nil {
+			s.log.Error("failed to save alert state", logger.Fields{"rule_id": rule.ID, "error": err.Error()})
+			continue
+		}
+		if d != nil {
+			fired++
+			s.log.Info("rate alert fired", logger.Fields{
+				"rule_id":   rule.ID,
+				"user_id":   rule.UserID,
+				"pair":      rule.FromCurrency + "/" + rule.ToCurrency,
+				"condition": rule.Condition,
+				"rate":      rate,
+			})
+		}
+	}
+	if fired > 0 {
+		wake(s.deliver)
+	}
+}
+
+// checkAlert advances the state of rule to rate at now and reports whether
+// it fires, along with the rate it was compared with. Above and below fire
+// when the rate crosses the threshold; change fires when the rate moved by
+// more than threshold percent since the start of the window, which then
+// restarts. A rule in its cooldown does not fire.
+func checkAlert(rule *models.AlertRule, rate float64, now time.Time) (ref float64, fire bool) {
+	prev := rule.LastRate
+	rule.LastRate = rate
+	switch rule.Condition {
+	case models.AlertAbove:
+		ref, fire = prev, prev > 0 && prev <= rule.Threshold && rate > rule.Threshold
+	case models.AlertBelow:
+		ref, fire = prev, prev > 0 && prev >= rule.Threshold && rate < rule.Threshold
+	case models.AlertChange:
+		if rule.BaselineRate <= 0 || now.Sub(rule.BaselineAt) >= rule.Window {
+			rule.BaselineRate, rule.BaselineAt = rate, now
+			if prev > 0 {
+				rule.BaselineRate = prev
+			}
+		}
+		ref = rule.BaselineRate
+		fire = math.Abs(rate-ref)/ref*100 > rule.Threshold
+	}
+	if fire && rule.LastTriggeredAt != nil && now.Sub(*rule.LastTriggeredAt) < rule.Cooldown {
+		return ref, false
+	}
+	if fire {
+		rule.LastTriggeredAt = &now
+		if rule.Condition == models.AlertChange {
+			rule.BaselineRate, rule.BaselineAt = rate, now
+		}
+	}
+	return ref, fire
+}
+
+// alertEvent is the JSON body of a webhook call.
+type alertEvent struct {
+	Type           string         `json:"type"`
+	CreatedAt      time.Time      `json:"created_at"`
+	Rule           alertEventRule `json:"rule"`
+	Rate           float64        `json:"rate"`
+	ReferenceRate  float64        `json:"reference_rate,omitempty"`
+	ChangePercent  float64        `json:"change_percent,omitempty"`
+	RatesFetchedAt time.Time      `json:"rates_fetched_at,omitzero"`
+}
+
+type alertEventRule struct {
+	ID        uint    `json:"id"`
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Condition string  `json:"condition"`
+	Threshold float64 `json:"threshold"`
+}
+
+func newAlertEventRule(r *models.AlertRule) alertEventRule {
+	return alertEventRule{ID: r.ID, From: r.FromCurrency, To: r.ToCurrency, Condition: r.Condition, Threshold: r.Threshold}
+}
+
+func newDelivery(rule *models.AlertRule, event string, body alertEvent, now time.Time) (*models.AlertDelivery, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AlertDelivery{
+		RuleID:        rule.ID,
+		UserID:        rule.UserID,
+		Event:         event,
+		Payload:       string(payload),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+// ErrInvalidQuery wraps the errors of a listing whose filter is invalid, as
+// opposed to one the store failed to run.
+var ErrInvalidQuery = errors.New("invalid query")
+
+type AuditService interface {
+	Record(ctx context.Context, evt models.AuditEvent)
+	List(ctx context.Context, filter repositories.AuditFilter) ([]models.AuditEvent, int64, error)
+}
+
+type auditService struct {
+	repo repositories.AuditRepository
+	log  *logger.Logger
+}
+
+func NewAuditService(repo repositories.AuditRepository, log *logger.Logger) AuditService {
+	return &auditService{repo: repo, log: log}
+}
+
+// Record persists evt. A failure to write the audit trail must not fail the
+// user-facing request, so errors are logged instead of returned. The write
+// is not cancelled with ctx: a client hanging up must not drop its events.
+func (s *auditService) Record(ctx context.Context, evt models.AuditEvent) {
+	if evt.CreatedAt.IsZero() {
+		evt.CreatedAt = time.Now().UTC()
+	}
+	if evt.Outcome == "" {
+		evt.Outcome = models.AuditOutcomeSuccess
+	}
+	if err := s.repo.Create(context.WithoutCancel(ctx), &evt); err != nil {
+		s.log.Error("failed to record audit event", logger.Fields{
+			"action":   evt.Action,
+			"outcome":  evt.Outcome,
+			"trace_id": evt.TraceID,
+			"error":    err.Error(),
+		})
+	}
+}
+
+func (s *auditService) List(ctx context.Context, f repositories.AuditFilter) ([]models.AuditEvent, int64, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultAuditLimit
+	}
+	if f.Limit > maxAuditLimit {
+		f.Limit = maxAuditLimit
+	}
+	if f.Offset < 0 {
+		return nil, 0, fmt.Errorf("%w: offset must be non-negative", ErrInvalidQuery)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return nil, 0, fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+	return s.repo.List(ctx, f)
+}
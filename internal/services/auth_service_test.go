@@ -0,0 +1,118 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories/memory"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+func TestAuthRoleFollowsAdminEmails(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepository()
+	log := logger.New(logger.Options{Level: "error"})
+	authWith := func(admins ...string) services.AuthService {
+		return services.NewAuthService(config.Config{AdminEmails: admins}, users, log)
+	}
+	roleOf := func(svc services.AuthService, email string) string {
+		t.Helper()
+		u, err := users.FindByEmail(ctx, email)
+		if err != nil {
+			t.Fatalf("find %s: %v", email, err)
+		}
+		return svc.Role(u)
+	}
+
+	// Registered before anyone listed it.
+	if err := authWith().Register(ctx, "", "ops@example.com", "password123"); err != nil {
+		t.Fatal(err)
+	}
+	listed := authWith("ops@example.com")
+	if err := listed.Register(ctx, "", "new@example.com", "password123"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		svc   services.AuthService
+		email string
+		want  string
+	}{
+		{"existing account listed later", listed, "ops@example.com", models.RoleAdmin},
+		{"unlisted account", listed, "new@example.com", models.RoleUser},
+		{"removed from the list", authWith(), "ops@example.com", models.RoleUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := roleOf(tt.svc, tt.email); got != tt.want {
+				t.Errorf("role = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The list is never written back, so delisting really demotes.
+	u, _ := users.FindByEmail(ctx, "ops@example.com")
+	if u.Role != models.RoleUser {
+		t.Errorf("stored role = %q, want %q", u.Role, models.RoleUser)
+	}
+
+	// A role granted in the database holds without the list.
+	if err := users.SetRole(ctx, u.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if got := roleOf(authWith(), "ops@example.com"); got != models.RoleAdmin {
+		t.Errorf("role after grant = %q, want %q", got, models.RoleAdmin)
+	}
+}
+
+func TestLoginReportsRevokedTokens(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepository()
+	log := logger.New(logger.Options{Level: "error"})
+	authWith := func(expiry time.Duration) services.AuthService {
+		return services.NewAuthService(config.Config{JWTSecret: "test-secret", JWTExpiry: expiry}, users, log)
+	}
+	svc := authWith(time.Hour)
+	if err := svc.Register(ctx, "", "kim@example.com", "password123"); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name string
+		run  func() (bool, error)
+		want bool
+	}{
+		{"first login", login(svc), false},
+		{"second login", login(svc), true},
+		{"after logout", func() (bool, error) {
+			u, _ := users.FindByEmail(ctx, "kim@example.com")
+			if err := svc.Logout(ctx, u.ID); err != nil {
+				return false, err
+			}
+			return login(svc)()
+		}, false},
+		// With a shorter expiry the token issued above has already expired.
+		{"after expiry", login(authWith(time.Nanosecond)), false},
+	}
+	for _, s := range steps {
+		revoked, err := s.run()
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if revoked != s.want {
+			t.Errorf("%s: revoked = %v, want %v", s.name, revoked, s.want)
+		}
+	}
+}
+
+func login(svc services.AuthService) func() (bool, error) {
+	return func() (bool, error) {
+		_, _, revoked, err := svc.Login(context.Background(), "kim@example.com", "password123")
+		return revoked, err
+	}
+}
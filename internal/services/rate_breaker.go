@@ -0,0 +1,96 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states, as reported in RateStatus.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// circuitBreaker counts failed refreshes in a row. Once the threshold is
+// reached the circuit opens and the provider is left alone until the cooldown
+// has passed; the next refresh is then let through as a trial, which closes
+// the circuit on success and reopens it on failure.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     string
+	failures  int
+	openUntil time.Time
+	lastErr   string
+	lastFail  time.Time
+}
+
+type breakerStatus struct {
+	State     string
+	Failures  int
+	OpenUntil time.Time
+	LastError string
+	LastFail  time.Time
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{state: BreakerClosed}
+}
+
+// allow reports whether the provider may be called at now. An open circuit
+// whose cooldown has passed becomes half-open and lets calls through again.
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen {
+		if now.Before(b.openUntil) {
+			return false
+		}
+		b.state = BreakerHalfOpen
+	}
+	return true
+}
+
+// success closes the circuit and reports whether it was not closed before.
+func (b *circuitBreaker) success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	recovered := b.state != BreakerClosed
+	b.state = BreakerClosed
+	b.failures = 0
+	b.openUntil = time.Time{}
+	return recovered
+}
+
+// failure records a failed refresh. The circuit opens for cooldown when the
+// threshold is reached, when a half-open trial fails, or right away when trip
+// is set; openUntil is never earlier than notBefore. It reports whether the
+// circuit is now open.
+func (b *circuitBreaker) failure(now time.Time, err error, threshold int, cooldown time.Duration, trip bool, notBefore time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.lastErr = err.Error()
+	b.lastFail = now
+	if !trip && b.state != BreakerHalfOpen && b.failures < threshold {
+		return false
+	}
+	b.state = BreakerOpen
+	b.openUntil = now.Add(cooldown)
+	if notBefore.After(b.openUntil) {
+		b.openUntil = notBefore
+	}
+	return true
+}
+
+func (b *circuitBreaker) status() breakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return breakerStatus{
+		State:     b.state,
+		Failures:  b.failures,
+		OpenUntil: b.openUntil,
+		LastError: b.lastErr,
+		LastFail:  b.lastFail,
+	}
+}
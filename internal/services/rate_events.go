@@ -0,0 +1,67 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/events"
+)
+
+// RatesPublishedEvent is the data of a rates.published event.
+type RatesPublishedEvent struct {
+	Base      string    `json:"base"`
+	Provider  string    `json:"provider"`
+	FetchedAt time.Time `json:"fetched_at"`
+	// Changes lists the currencies whose published rate differs from the
+	// previous table, sorted by currency. Old is null for a new currency and
+	// New is null for one that is no longer quoted.
+	Changes []RateChange `json:"changes"`
+}
+
+type RateChange struct {
+	Currency string   `json:"currency"`
+	Old      *float64 `json:"old"`
+	New      *float64 `json:"new"`
+}
+
+// ratesPublishedEvent describes next replacing prev, nil before the first
+// table was published. Events of one base share a key, so a partitioned
+// sink keeps them in order.
+func ratesPublishedEvent(prev, next *rateSnapshot) (events.Event, error) {
+	data := RatesPublishedEvent{Base: next.base, Provider: next.provider, FetchedAt: next.fetchedAt}
+	rates, _ := next.view(next.base)
+	var old map[string]float64
+	if prev != nil {
+		old, _ = prev.view(next.base)
+	}
+	data.Changes = rateChanges(old, rates)
+	return events.New(events.TypeRatesPublished, next.base, data)
+}
+
+func rateChanges(old, rates map[string]float64) []RateChange {
+	changes := []RateChange{}
+	for cur, r := range rates {
+		if o, ok := old[cur]; !ok || !sameRate(o, r) {
+			c := RateChange{Currency: cur, New: ptr(r)}
+			if ok {
+				c.Old = ptr(o)
+			}
+			changes = append(changes, c)
+		}
+	}
+	for cur, o := range old {
+		if _, ok := rates[cur]; !ok {
+			changes = append(changes, RateChange{Currency: cur, Old: ptr(o)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Currency < changes[j].Currency })
+	return changes
+}
+
+// sameRate ignores differences from re-deriving cross rates.
+func sameRate(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-12*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func ptr(f float64) *float64 { return &f }
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/spksupakorn/Currency-Converter/internal/currency"
+)
+
+// RateFilter narrows a rate table. Empty fields do not filter. Region and
+// Type match currency metadata, so currencies without metadata only pass
+// when neither is set.
+type RateFilter struct {
+	Symbols []string
+	Exclude []string
+	Region  string
+	Type    string
+}
+
+func (f RateFilter) IsZero() bool {
+	return len(f.Symbols) == 0 && len(f.Exclude) == 0 && f.Region == "" && f.Type == ""
+}
+
+// Key is a canonical form of the filter, for cache validators.
+func (f RateFilter) Key() string {
+	return strings.Join(f.Symbols, ",") + "|" + strings.Join(f.Exclude, ",") + "|" + f.Region + "|" + f.Type
+}
+
+// FilterRates returns the rates that pass f in a new map, and the requested
+// symbols rates has no entry for, sorted. rates itself is not modified.
+func FilterRates(rates map[string]float64, f RateFilter) (map[string]float64, []string) {
+	if f.IsZero() {
+		return rates, nil
+	}
+
+	keep := func(code string) bool {
+		for _, ex := range f.Exclude {
+			if ex == code {
+				return false
+			}
+		}
+		if f.Region == "" && f.Type == "" {
+			return true
+		}
+		meta, ok := currency.Lookup(code)
+		if !ok {
+			return false
+		}
+		return (f.Region == "" || meta.Region == f.Region) && (f.Type == "" || meta.Type == f.Type)
+	}
+
+	var missing []string
+	var out map[string]float64
+	if len(f.Symbols) > 0 {
+		out = make(map[string]float64, len(f.Symbols))
+		for _, code := range f.Symbols {
+			r, ok := rates[code]
+			if !ok {
+				missing = append(missing, code)
+				continue
+			}
+			if keep(code) {
+				out[code] = r
+			}
+		}
+		sort.Strings(missing)
+		return out, missing
+	}
+
+	out = make(map[string]float64)
+	for code, r := range rates {
+		if keep(code) {
+			out[code] = r
+		}
+	}
+	return out, nil
+}
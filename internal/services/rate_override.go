@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+func (s *rateService) Refresh(ctx context.Context) (RefreshResult, error) {
+	start := time.Now()
+	if err := s.refresh(ctx, true); err != nil {
+		return RefreshResult{Duration: time.Since(start)}, err
+	}
+	select {
+	case s.refreshed <- struct{}{}:
+	default:
+	}
+	snap := s.snap.Load()
+	return RefreshResult{
+		Base:      snap.base,
+		Provider:  snap.provider,
+		Count:     len(snap.currencies),
+		FetchedAt: snap.fetchedAt,
+		Duration:  time.Since(start),
+	}, nil
+}
+
+func (s *rateService) SetOverride(ctx context.Context, o models.RateOverride) (models.RateOverride, error) {
+	now := time.Now().UTC()
+	o.Currency = normalizeCurrency(o.Currency)
+	o.Base = normalizeCurrency(o.Base)
+	o.Reason = strings.TrimSpace(o.Reason)
+	snap := s.snap.Load()
+	if o.Base == "" {
+		if snap == nil {
+			return o, fmt.Errorf("%w: rates are not available yet", ErrInvalidOverride)
+		}
+		o.Base = snap.base
+	}
+	switch {
+	case o.Currency == o.Base:
+		return o, fmt.Errorf("%w: currency and base must differ", ErrInvalidOverride)
+	case o.Rate <= 0 || math.IsNaN(o.Rate) || math.IsInf(o.Rate, 0):
+		return o, fmt.Errorf("%w: rate must be a positive number", ErrInvalidOverride)
+	case o.Reason == "":
+		return o, fmt.Errorf("%w: reason is required", ErrInvalidOverride)
+	case len(o.Reason) > 255:
+		return o, fmt.Errorf("%w: reason must be at most 255 characters", ErrInvalidOverride)
+	case !o.ExpiresAt.After(now):
+		return o, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidOverride)
+	case o.ExpiresAt.Sub(now) > maxOverrideTTL:
+		return o, fmt.Errorf("%w: expires_at must be within %s", ErrInvalidOverride, maxOverrideTTL)
+	}
+	if snap != nil {
+		if o.Currency == snap.base {
+			return o, fmt.Errorf("%w: %s is the base of the provider rates and cannot be overridden", ErrInvalidOverride, o.Currency)
+		}
+		if _, ok := snap.stored.Rates[o.Base]; !ok && o.Base != snap.base {
+			return o, fmt.Errorf("%w: unsupported base currency: %s", ErrInvalidOverride, o.Base)
+		}
+	}
+	o.ID = 0
+	o.ExpiresAt = o.ExpiresAt.UTC()
+	o.CreatedAt = now
+
+	if err := s.overridesRepo.Upsert(ctx, &o); err != nil {
+		return o, err
+	}
+	if err := s.loadOverrides(ctx, now); err != nil {
+		return o, err
+	}
+	s.republish(now)
+	s.broadcast(ctx)
+	s.log.Info("rate override set", logger.Fields{
+		"currency":   o.Currency,
+		"base":       o.Base,
+		"rate":       o.Rate,
+		"expires_at": o.ExpiresAt,
+		"reason":     o.Reason,
+	})
+	return o, nil
+}
+
+func (s *rateService) DeleteOverride(ctx context.Context, currency string) error {
+	currency = normalizeCurrency(currency)
+	found, err := s.overridesRepo.Delete(ctx, currency)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrOverrideNotFound
+	}
+	now := time.Now().UTC()
+	if err := s.loadOverrides(ctx, now); err != nil {
+		return err
+	}
+	s.republish(now)
+	s.broadcast(ctx)
+	s.log.Info("rate override deleted", logger.Fields{"currency": currency})
+	return nil
+}
+
+func (s *rateService) ListOverrides(ctx context.Context) ([]models.RateOverride, error) {
+	return s.overridesRepo.ListActive(ctx, time.Now().UTC())
+}
+
+// loadOverrides reads the active overrides into s.overrides. changedAt dates
+// the new set; when zero, the previous date is kept if nothing changed.
+func (s *rateService) loadOverrides(ctx context.Context, changedAt time.Time) error {
+	list, err := s.overridesRepo.ListActive(ctx, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	prev := s.overrides.Load()
+	if changedAt.IsZero() {
+		changedAt = time.Now().UTC()
+		if prev != nil && slices.EqualFunc(prev.list, list, sameOverride) {
+			changedAt = prev.changedAt
+		}
+	}
+	s.overrides.Store(&overrideSet{list: list, changedAt: changedAt})
+	return nil
+}
+
+func sameOverride(a, b models.RateOverride) bool {
+	return a.Currency == b.Currency && a.Base == b.Base && a.Rate == b.Rate && a.ExpiresAt.Equal(b.ExpiresAt)
+}
+
+// republish rebuilds the current snapshot from its provider data with the
+// current overrides.
+func (s *rateService) republish(now time.Time) {
+	for {
+		cur := s.snap.Load()
+		if cur == nil {
+			return
+		}
+		if s.snap.CompareAndSwap(cur, newRateSnapshot(cur.stored, s.overrides.Load(), now)) {
+			return
+		}
+	}
+}
+
+// expireOverrides replaces snap, one of whose overrides ran out before now,
+// with a snapshot without it. The override set changed at the moment of
+// expiry, which becomes the new Last-Modified.
+func (s *rateService) expireOverrides(snap *rateSnapshot, now time.Time) *rateSnapshot {
+	if set := s.overrides.Load(); set != nil {
+		next := &overrideSet{changedAt: snap.expiresAt}
+		for _, o := range set.list {
+			if o.ExpiresAt.After(now) {
+				next.list = append(next.list, o)
+			}
+		}
+		if len(next.list) < len(set.list) {
+			s.overrides.CompareAndSwap(set, next)
+		}
+	}
+	fresh := newRateSnapshot(snap.stored, s.overrides.Load(), now)
+	if !s.snap.CompareAndSwap(snap, fresh) {
+		return s.snap.Load()
+	}
+	s.log.Info("rate override expired", logger.Fields{"expired_at": snap.expiresAt})
+	return fresh
+}
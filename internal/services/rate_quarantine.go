@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// maxQuarantineList bounds ListQuarantines.
+const maxQuarantineList = 50
+
+// validateAndPublish publishes fetched rates that pass validation and
+// quarantines those with anomalies, leaving the current snapshot in place.
+// The caller holds s.refreshing.
+func (s *rateService) validateAndPublish(ctx context.Context, stored repositories.StoredRates) error {
+	cfg := s.settings()
+	anomalies, err := validateRates(stored, s.baseline(ctx), cfg)
+	if err != nil {
+		return err
+	}
+	if len(anomalies) == 0 {
+		return s.publish(ctx, stored, nil)
+	}
+
+	q := &models.RateQuarantine{
+		Base:      stored.Base,
+		Provider:  stored.Provider,
+		Rates:     stored.Rates,
+		Anomalies: anomalies,
+		FetchedAt: stored.UpdatedAt,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.quarantineRepo.Create(ctx, q); err != nil {
+		return err
+	}
+	s.pendingQuarantine.Store(uint64(q.ID))
+	currencies := make([]string, len(anomalies))
+	for i, a := range anomalies {
+		currencies[i] = a.Currency
+	}
+	s.log.Warn("rates quarantined", logger.Fields{
+		"quarantine_id": q.ID,
+		"base":          q.Base,
+		"currencies":    currencies,
+		"max_change":    cfg.RateMaxChange,
+	})
+	return fmt.Errorf("%w as #%d: %d currencies moved by more than %g%%",
+		ErrRatesQuarantined, q.ID, len(anomalies), cfg.RateMaxChange*100)
+}
+
+// baseline is the provider table new rates are compared with: the published
+// one, or the stored one before anything was published.
+func (s *rateService) baseline(ctx context.Context) repositories.StoredRates {
+	if snap := s.snap.Load(); snap != nil {
+		return snap.stored
+	}
+	stored, err := s.repo.GetAllRates(ctx)
+	if err != nil {
+		return repositories.StoredRates{}
+	}
+	return stored
+}
+
+// publish stores rates and makes them the current snapshot with the current
+// overrides applied. A rates.published event, and whatever also writes when
+// it is not nil, are written in the same transaction as the rates. Any
+// pending quarantine is older, so it is superseded. The caller holds
+// s.refreshing.
+func (s *rateService) publish(ctx context.Context, stored repositories.StoredRates, also func(ctx context.Context) error) error {
+	// Pick up overrides set on other instances since the last refresh.
+	if err := s.loadOverrides(ctx, time.Time{}); err != nil {
+		s.log.Warn("failed to load rate overrides", logger.Fields{"error": err.Error()})
+	}
+	snap := newRateSnapshot(stored, s.overrides.Load(), time.Now())
+	evt, err := ratesPublishedEvent(s.snap.Load(), snap)
+	if err != nil {
+		return err
+	}
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		if also != nil {
+			if err := also(ctx); err != nil {
+				return err
+			}
+		}
+		if err := s.repo.UpsertRates(ctx, stored); err != nil {
+			return err
+		}
+		return s.publisher.Publish(ctx, evt)
+	})
+	if err != nil {
+		return err
+	}
+	s.snap.Store(snap)
+	s.notifyPublished(snap)
+	s.broadcast(ctx)
+
+	if err := s.quarantineRepo.SupersedePending(ctx); err != nil {
+		s.log.Warn("failed to supersede quarantined rates", logger.Fields{"error": err.Error()})
+	}
+	s.pendingQuarantine.Store(0)
+	return nil
+}
+
+func (s *rateService) ListQuarantines(ctx context.Context, status string) ([]models.RateQuarantine, error) {
+	return s.quarantineRepo.List(ctx, status, maxQuarantineList)
+}
+
+func (s *rateService) ApproveQuarantine(ctx context.Context, id uint, byID *uint, byEmail string) (models.RateQuarantine, error) {
+	select {
+	case s.refreshing <- struct{}{}:
+		defer func() { <-s.refreshing }()
+	case <-ctx.Done():
+		return models.RateQuarantine{}, ctx.Err()
+	}
+
+	q, err := s.findPending(ctx, id)
+	if err != nil {
+		return q, err
+	}
+	// The approval is only recorded together with the rates it publishes,
+	// so a failed publish leaves the quarantine pending.
+	stored := repositories.StoredRates{Base: q.Base, Provider: q.Provider, Rates: q.Rates, UpdatedAt: q.FetchedAt}
+	decided := q
+	err = s.publish(ctx, stored, func(ctx context.Context) error {
+		return s.decide(ctx, &decided, models.QuarantineApproved, byID, byEmail)
+	})
+	if err != nil {
+		return q, err
+	}
+	q = decided
+	s.log.Info("quarantined rates approved", logger.Fields{"quarantine_id": q.ID, "base": q.Base, "by": byEmail})
+	return q, nil
+}
+
+func (s *rateService) RejectQuarantine(ctx context.Context, id uint, byID *uint, byEmail string) (models.RateQuarantine, error) {
+	q, err := s.findPending(ctx, id)
+	if err != nil {
+		return q, err
+	}
+	if err := s.decide(ctx, &q, models.QuarantineRejected, byID, byEmail); err != nil {
+		return q, err
+	}
+	s.pendingQuarantine.CompareAndSwap(uint64(id), 0)
+	s.log.Info("quarantined rates rejected", logger.Fields{"quarantine_id": q.ID, "base": q.Base, "by": byEmail})
+	return q, nil
+}
+
+// findPending returns quarantine id, or ErrQuarantineDecided with it when it
+// is no longer pending.
+func (s *rateService) findPending(ctx context.Context, id uint) (models.RateQuarantine, error) {
+	q, err := s.quarantineRepo.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.RateQuarantine{}, ErrQuarantineNotFound
+	}
+	if err != nil {
+		return models.RateQuarantine{}, err
+	}
+	if q.Status != models.QuarantinePending {
+		return *q, fmt.Errorf("%w: it was %s", ErrQuarantineDecided, q.Status)
+	}
+	return *q, nil
+}
+
+// decide moves q from pending to status, failing with ErrQuarantineDecided
+// if another request decided it first, and updates q to match.
+func (s *rateService) decide(ctx context.Context, q *models.RateQuarantine, status string, byID *uint, byEmail string) error {
+	d := repositories.QuarantineDecision{Status: status, ByID: byID, ByEmail: byEmail, At: time.Now().UTC()}
+	ok, err := s.quarantineRepo.Decide(ctx, q.ID, d)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrQuarantineDecided
+	}
+	q.Status, q.DecidedByID, q.DecidedByEmail, q.DecidedAt = d.Status, d.ByID, d.ByEmail, &d.At
+	return nil
+}
@@ -0,0 +1,99 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/database"
+	"github.com/spksupakorn/Currency-Converter/internal/events"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/internal/testutil"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// flakyPublisher fails every Publish while down is set, like an outbox
+// insert that hits a database error.
+type flakyPublisher struct {
+	down atomic.Bool
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, evt events.Event) error {
+	if p.down.Load() {
+		return errors.New("outbox unavailable")
+	}
+	return nil
+}
+
+func TestApproveQuarantineStaysPendingWhenPublishFails(t *testing.T) {
+	// The default table, then one with THB far off it.
+	p := newScriptedProvider(t, okRates, func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"result":           "success",
+			"base_code":        "USD",
+			"conversion_rates": map[string]float64{"USD": 1, "THB": 60},
+		})
+	})
+	cfg := testutil.Config(p.URL + "/")
+	cfg.RateMaxChange = 0.1
+	log := logger.New(logger.Options{Level: "error"})
+
+	// SQLite, so the decision and the rates share a real transaction.
+	db, err := database.New(config.Config{DBDriver: database.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "test.db")}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := db.MigrateDB(); err != nil {
+		t.Fatal(err)
+	}
+	repos := repositories.NewRepositories(db.ConnectDB(), db.Reader())
+	publisher := &flakyPublisher{}
+	svc := services.NewRateService(cfg, repos, publisher, nil, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go svc.RunBackgroundRefresh(ctx)
+	waitFor(t, "rates", func() bool { return svc.Status().Count > 0 })
+
+	if _, err := svc.Refresh(ctx); !errors.Is(err, services.ErrRatesQuarantined) {
+		t.Fatalf("refresh = %v, want the table quarantined", err)
+	}
+	id := svc.Status().PendingQuarantine
+
+	publisher.down.Store(true)
+	if _, err := svc.ApproveQuarantine(ctx, id, nil, "root@example.com"); err == nil {
+		t.Fatal("approve succeeded while publishing failed")
+	}
+	q, err := repos.Quarantine.FindByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Status != models.QuarantinePending || q.DecidedAt != nil {
+		t.Errorf("quarantine after a failed approval = %s decided at %v, want still pending", q.Status, q.DecidedAt)
+	}
+	stored, err := repos.Rates.GetAllRates(ctx)
+	if err != nil || stored.Rates["THB"] != 36.5 {
+		t.Errorf("stored THB = %v, %v, want the previous 36.5", stored.Rates["THB"], err)
+	}
+
+	// Once publishing works the same quarantine can still be approved.
+	publisher.down.Store(false)
+	q2, err := svc.ApproveQuarantine(ctx, id, nil, "root@example.com")
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if q2.Status != models.QuarantineApproved {
+		t.Errorf("status = %s, want approved", q2.Status)
+	}
+	rates, _, err := svc.GetRates(ctx, "USD")
+	if err != nil || rates["THB"] != 60 {
+		t.Errorf("THB = %v, %v, want the approved 60", rates["THB"], err)
+	}
+}
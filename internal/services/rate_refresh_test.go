@@ -0,0 +1,398 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/internal/events"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories/memory"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/internal/testutil"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// scriptedProvider answers each call with the next handler in script and
+// repeats the last one once the script runs out.
+type scriptedProvider struct {
+	*httptest.Server
+	mu     sync.Mutex
+	script []http.HandlerFunc
+	hits   atomic.Int64
+}
+
+func newScriptedProvider(t *testing.T, script ...http.HandlerFunc) *scriptedProvider {
+	p := &scriptedProvider{script: script}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(p.hits.Add(1))
+		p.mu.Lock()
+		h := p.script[min(n, len(p.script))-1]
+		p.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		h(w, r)
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func okRates(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"result":           "success",
+		"base_code":        "USD",
+		"conversion_rates": map[string]float64{"USD": 1, "THB": 36.5},
+	})
+}
+
+func failWith(status int, errorType string, header ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		for i := 0; i+1 < len(header); i += 2 {
+			w.Header().Set(header[i], header[i+1])
+		}
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]string{"result": "error", "error-type": errorType})
+	}
+}
+
+func startRateService(t *testing.T, p *scriptedProvider, opts ...testutil.Option) services.RateService {
+	t.Helper()
+	cfg := testutil.Config(p.URL + "/")
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	svc := services.NewRateService(cfg, memory.NewRepositories(), events.Discard, nil, logger.New(logger.Options{Level: "error"}))
+	go svc.RunBackgroundRefresh(ctx)
+	return svc
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRefreshRetriesTransientErrors(t *testing.T) {
+	p := newScriptedProvider(t, failWith(http.StatusBadGateway, "unavailable"), failWith(http.StatusServiceUnavailable, "unavailable"), okRates)
+	svc := startRateService(t, p)
+
+	waitFor(t, "rates", func() bool { return svc.Status().Count > 0 })
+	if got := p.hits.Load(); got != 3 {
+		t.Errorf("provider hits = %d, want 3", got)
+	}
+	if st := svc.Status(); st.ConsecutiveFailures != 0 || st.Breaker != services.BreakerClosed {
+		t.Errorf("status after recovery = %+v", st)
+	}
+}
+
+func TestRefreshReattemptsEarlyAfterFailure(t *testing.T) {
+	p := newScriptedProvider(t, failWith(http.StatusBadGateway, "unavailable"), failWith(http.StatusBadGateway, "unavailable"), okRates)
+	svc := startRateService(t, p, func(c *config.Config) {
+		c.RateRetryAttempts = 1
+		c.RateBreakerThreshold = 10
+	})
+
+	// One attempt per refresh, so only the early re-attempt schedule can
+	// get the rates in well before the hourly tick.
+	waitFor(t, "rates", func() bool { return svc.Status().Count > 0 })
+	if got := p.hits.Load(); got != 3 {
+		t.Errorf("provider hits = %d, want 3", got)
+	}
+}
+
+func TestRefreshCircuitOpensAfterRepeatedFailures(t *testing.T) {
+	p := newScriptedProvider(t, failWith(http.StatusInternalServerError, "unavailable"))
+	svc := startRateService(t, p, func(c *config.Config) {
+		c.RateRetryAttempts = 2
+		c.RateBreakerThreshold = 2
+	})
+
+	waitFor(t, "open circuit", func() bool { return svc.Status().Breaker == services.BreakerOpen })
+	time.Sleep(50 * time.Millisecond)
+	if got := p.hits.Load(); got != 4 {
+		t.Errorf("provider hits = %d, want 4 (2 refreshes of 2 attempts, then none)", got)
+	}
+	st := svc.Status()
+	if st.ConsecutiveFailures != 2 || st.LastError == "" {
+		t.Errorf("status = %+v", st)
+	}
+	if until := time.Until(st.BreakerOpenUntil); until < 50*time.Second {
+		t.Errorf("breaker open for %s, want about the cooldown", until)
+	}
+	if next := time.Until(st.NextRefreshAt); next < 50*time.Second {
+		t.Errorf("next refresh in %s, want no earlier than the circuit closing", next)
+	}
+}
+
+func TestRefreshErrorsHideTheAPIKey(t *testing.T) {
+	// Closing the connection fails the request in the transport, whose
+	// error quotes the request URL.
+	hangUp := func(w http.ResponseWriter, _ *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}
+	p := newScriptedProvider(t, hangUp)
+	svc := startRateService(t, p, func(c *config.Config) {
+		c.ExchangeAPIKey = "k3y-that-must-not-leak"
+		c.RateRetryAttempts = 1
+	})
+
+	waitFor(t, "a failed refresh", func() bool { return svc.Status().ConsecutiveFailures > 0 })
+	st := svc.Status()
+	if strings.Contains(st.LastError, "k3y-that-must-not-leak") || !strings.Contains(st.LastError, "/REDACTED/latest/USD") {
+		t.Errorf("last error = %q, want the key masked in the URL", st.LastError)
+	}
+}
+
+func TestRefreshCircuitHalfOpenTrial(t *testing.T) {
+	p := newScriptedProvider(t, failWith(http.StatusInternalServerError, "unavailable"), okRates)
+	svc := startRateService(t, p, func(c *config.Config) {
+		c.RateRetryAttempts = 1
+		c.RateBreakerThreshold = 1
+		c.RateBreakerCooldown = 20 * time.Millisecond
+	})
+
+	waitFor(t, "rates", func() bool { return svc.Status().Count > 0 })
+	if st := svc.Status(); st.Breaker != services.BreakerClosed || p.hits.Load() != 2 {
+		t.Errorf("breaker %s after %d hits, want closed after 2", st.Breaker, p.hits.Load())
+	}
+}
+
+func TestRefreshQuotaExceededTripsCircuit(t *testing.T) {
+	p := newScriptedProvider(t, failWith(http.StatusForbidden, "quota-reached"))
+	svc := startRateService(t, p)
+
+	waitFor(t, "open circuit", func() bool { return svc.Status().Breaker == services.BreakerOpen })
+	time.Sleep(50 * time.Millisecond)
+	if got := p.hits.Load(); got != 1 {
+		t.Errorf("provider hits = %d, want 1: an exhausted quota is not retried", got)
+	}
+	if st := svc.Status(); st.ConsecutiveFailures != 1 {
+		t.Errorf("consecutive failures = %d, want 1", st.ConsecutiveFailures)
+	}
+}
+
+func TestRefreshRespectsRetryAfter(t *testing.T) {
+	t.Run("short", func(t *testing.T) {
+		p := newScriptedProvider(t, failWith(http.StatusTooManyRequests, "", "Retry-After", "0"), okRates)
+		svc := startRateService(t, p)
+		waitFor(t, "rates", func() bool { return svc.Status().Count > 0 })
+		if got := p.hits.Load(); got != 2 {
+			t.Errorf("provider hits = %d, want 2", got)
+		}
+	})
+
+	t.Run("longer than the retry delay", func(t *testing.T) {
+		p := newScriptedProvider(t, failWith(http.StatusTooManyRequests, "", "Retry-After", "120"))
+		svc := startRateService(t, p)
+		waitFor(t, "failure", func() bool { return svc.Status().ConsecutiveFailures > 0 })
+		time.Sleep(50 * time.Millisecond)
+		if got := p.hits.Load(); got != 1 {
+			t.Errorf("provider hits = %d, want 1", got)
+		}
+		if next := time.Until(svc.Status().NextRefreshAt); next < 110*time.Second {
+			t.Errorf("next refresh in %s, want after Retry-After", next)
+		}
+	})
+}
+
+func TestRefreshWritesRatesPublishedEvent(t *testing.T) {
+	p := newScriptedProvider(t, okRates, func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"result":           "success",
+			"base_code":        "USD",
+			"conversion_rates": map[string]float64{"USD": 1, "THB": 36.6},
+		})
+	})
+	repos := memory.NewRepositories()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	svc := services.NewRateService(testutil.Config(p.URL+"/"), repos, events.NewOutboxPublisher(repos.Outbox), nil, logger.New(logger.Options{Level: "error"}))
+	go svc.RunBackgroundRefresh(ctx)
+	waitFor(t, "rates", func() bool { return svc.Status().Count > 0 })
+	if _, err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	pending, err := repos.Outbox.Pending(ctx, 10)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("outbox = %+v, %v", pending, err)
+	}
+	var evt struct {
+		events.Event
+		Data services.RatesPublishedEvent `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(pending[1].Payload), &evt); err != nil {
+		t.Fatalf("decode event: %v", err)
+	}
+	if evt.Type != events.TypeRatesPublished || evt.Key != "USD" || evt.ID != pending[1].EventID || evt.Data.Base != "USD" {
+		t.Errorf("event = %+v", evt)
+	}
+	ch := evt.Data.Changes
+	if len(ch) != 1 || ch[0].Currency != "THB" || ch[0].Old == nil || *ch[0].Old != 36.5 || ch[0].New == nil || *ch[0].New != 36.6 {
+		t.Errorf("changes = %+v", ch)
+	}
+}
+
+func TestRefreshSchedule(t *testing.T) {
+	t.Run("cron", func(t *testing.T) {
+		svc := startRateService(t, newScriptedProvider(t, okRates), func(c *config.Config) {
+			c.RateRefreshSchedule = "5 16 * * 1-5"
+		})
+		waitFor(t, "rates", func() bool { return svc.Status().Count > 0 })
+		st := svc.Status()
+		next := st.NextRefreshAt
+		if next.Hour() != 16 || next.Minute() != 5 || next.Weekday() == time.Saturday || next.Weekday() == time.Sunday ||
+			!next.After(time.Now()) || time.Until(next) > 4*24*time.Hour {
+			t.Errorf("next refresh at %s (%s)", next, next.Weekday())
+		}
+		if !st.MarketOpen || st.Schedule == "" {
+			t.Errorf("status = %+v", st)
+		}
+	})
+
+	// Holidays today and tomorrow close the market until the day after.
+	now := time.Now().UTC()
+	holidays := []string{now.Format("2006-01-02"), now.AddDate(0, 0, 1).Format("2006-01-02")}
+	reopen := time.Date(now.Year(), now.Month(), now.Day()+2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("skip while closed", func(t *testing.T) {
+		svc := startRateService(t, newScriptedProvider(t, okRates), func(c *config.Config) {
+			c.RateMarketHolidays = holidays
+		})
+		waitFor(t, "rates", func() bool { return svc.Status().Count > 0 })
+		if st := svc.Status(); !st.NextRefreshAt.Equal(reopen) || st.MarketOpen {
+			t.Errorf("next refresh at %s, market open %v; want %s, closed", st.NextRefreshAt, st.MarketOpen, reopen)
+		}
+	})
+
+	t.Run("slow while closed", func(t *testing.T) {
+		svc := startRateService(t, newScriptedProvider(t, okRates), func(c *config.Config) {
+			c.RateMarketHolidays = holidays
+			c.RateMarketClosedInterval = 6 * time.Hour
+		})
+		waitFor(t, "rates", func() bool { return svc.Status().Count > 0 })
+		if d := time.Until(svc.Status().NextRefreshAt); d < 5*time.Hour || d > 6*time.Hour {
+			t.Errorf("next refresh in %s, want 6h", d)
+		}
+	})
+}
+
+func TestFollowerReloadsLeaderRates(t *testing.T) {
+	p := newScriptedProvider(t, okRates, func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"result":           "success",
+			"base_code":        "USD",
+			"conversion_rates": map[string]float64{"USD": 1, "THB": 36.6},
+		})
+	})
+	cfg := testutil.Config(p.URL + "/")
+	cfg.RateSyncInterval = 10 * time.Millisecond
+	repos := memory.NewRepositories()
+	log := logger.New(logger.Options{Level: "error"})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	leader := services.NewRateService(cfg, repos, events.Discard, nil, log)
+	go leader.RunBackgroundRefresh(ctx)
+	go leader.RunSync(ctx)
+	follower := services.NewRateService(cfg, repos, events.Discard, nil, log)
+	go follower.RunSync(ctx)
+
+	thb := func(svc services.RateService) float64 {
+		rates, _, err := svc.GetRates(ctx, "USD")
+		if err != nil {
+			return 0
+		}
+		return rates["THB"]
+	}
+	waitFor(t, "follower to load the rates", func() bool { return follower.Status().Count > 0 })
+	if _, err := leader.Refresh(ctx); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	waitFor(t, "follower to reload the refreshed rates", func() bool { return thb(follower) == 36.6 })
+
+	_, err := leader.SetOverride(ctx, models.RateOverride{Currency: "THB", Rate: 35, Reason: "test", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("set override: %v", err)
+	}
+	waitFor(t, "follower to apply the override", func() bool { return thb(follower) == 35 })
+
+	// A follower elected after the leader fetched waits for the next
+	// scheduled refresh rather than calling the provider again.
+	go follower.RunBackgroundRefresh(ctx)
+	time.Sleep(50 * time.Millisecond)
+	if got := p.hits.Load(); got != 2 {
+		t.Errorf("provider hits = %d, want 2", got)
+	}
+	if next := follower.Status().NextRefreshAt; time.Until(next) < 30*time.Minute {
+		t.Errorf("follower refreshes next at %s", next)
+	}
+}
+
+func TestFollowerLoadsStoredRatesAtStart(t *testing.T) {
+	p := newScriptedProvider(t, okRates)
+	cfg := testutil.Config(p.URL + "/")
+	cfg.RateSyncInterval = 0
+	repos := memory.NewRepositories()
+	log := logger.New(logger.Options{Level: "error"})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	leader := services.NewRateService(cfg, repos, events.Discard, nil, log)
+	go leader.RunBackgroundRefresh(ctx)
+	waitFor(t, "leader to fetch the rates", func() bool { return leader.Status().Count > 0 })
+
+	// Neither a notifier nor a sync interval: only the start-up sync loads
+	// the rates.
+	follower := services.NewRateService(cfg, repos, events.Discard, nil, log)
+	go follower.RunSync(ctx)
+	waitFor(t, "follower to load the rates", func() bool { return follower.Status().Count > 0 })
+}
+
+// failingOverrides is an override store that cannot write.
+type failingOverrides struct {
+	repositories.RateOverrideRepository
+}
+
+func (failingOverrides) Upsert(context.Context, *models.RateOverride) error {
+	return errors.New("database is locked")
+}
+
+func TestSetOverrideTellsInvalidFromStoreErrors(t *testing.T) {
+	p := newScriptedProvider(t, okRates)
+	repos := memory.NewRepositories()
+	repos.Overrides = failingOverrides{repos.Overrides}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	svc := services.NewRateService(testutil.Config(p.URL+"/"), repos, events.Discard, nil, logger.New(logger.Options{Level: "error"}))
+	go svc.RunBackgroundRefresh(ctx)
+	waitFor(t, "rates", func() bool { return svc.Status().Count > 0 })
+
+	valid := models.RateOverride{Currency: "THB", Rate: 35, Reason: "test", ExpiresAt: time.Now().Add(time.Hour)}
+	invalid := valid
+	invalid.Rate = -1
+	if _, err := svc.SetOverride(ctx, invalid); !errors.Is(err, services.ErrInvalidOverride) {
+		t.Errorf("negative rate: err = %v, want ErrInvalidOverride", err)
+	}
+	if _, err := svc.SetOverride(ctx, valid); err == nil || errors.Is(err, services.ErrInvalidOverride) {
+		t.Errorf("store failure: err = %v, want a store error", err)
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/internal/schedule"
+)
+
+// maxClosedSkips bounds how many closed periods next steps over, so that a
+// calendar that never opens cannot stall the loop.
+const maxClosedSkips = 64
+
+// refreshSchedule decides when the background loop fetches rates: every
+// RATE_REFRESH_INTERVAL, or at RATE_REFRESH_SCHEDULE, moved out of the times
+// the market calendar is closed.
+type refreshSchedule struct {
+	interval time.Duration
+	cron     *schedule.Cron // nil: every interval
+	loc      *time.Location
+	calendar *schedule.Calendar
+	// closedInterval is how often rates are still fetched while the market
+	// is closed; 0 skips closed periods entirely.
+	closedInterval time.Duration
+}
+
+func newRefreshSchedule(cfg *config.Config) (*refreshSchedule, error) {
+	loc, err := time.LoadLocation(cfg.RateScheduleTimezone)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := schedule.NewCalendar(cfg.RateMarketCalendar, cfg.RateMarketHolidays, loc)
+	if err != nil {
+		return nil, err
+	}
+	s := &refreshSchedule{
+		interval:       cfg.RateRefreshInterval,
+		loc:            loc,
+		calendar:       cal,
+		closedInterval: cfg.RateMarketClosedInterval,
+	}
+	if cfg.RateRefreshSchedule != "" {
+		if s.cron, err = schedule.ParseCron(cfg.RateRefreshSchedule); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// sameRefreshSchedule reports whether a and b schedule refreshes alike.
+func sameRefreshSchedule(a, b config.Config) bool {
+	if len(a.RateMarketHolidays) != len(b.RateMarketHolidays) {
+		return false
+	}
+	for i := range a.RateMarketHolidays {
+		if a.RateMarketHolidays[i] != b.RateMarketHolidays[i] {
+			return false
+		}
+	}
+	return a.RateRefreshInterval == b.RateRefreshInterval &&
+		a.RateRefreshSchedule == b.RateRefreshSchedule &&
+		a.RateScheduleTimezone == b.RateScheduleTimezone &&
+		a.RateMarketCalendar == b.RateMarketCalendar &&
+		a.RateMarketClosedInterval == b.RateMarketClosedInterval
+}
+
+// regular is the next run after from, ignoring the calendar.
+func (s *refreshSchedule) regular(from time.Time) time.Time {
+	if s.cron == nil {
+		return from.Add(s.interval)
+	}
+	return s.cron.Next(from.In(s.loc))
+}
+
+// next is when the refresh after one due at from should run. A run that
+// falls while the market is closed moves to the first regular run after it
+// opens again, or, with a closed interval, happens at most that often.
+func (s *refreshSchedule) next(from time.Time) time.Time {
+	t := s.regular(from)
+	for i := 0; i < maxClosedSkips && !t.IsZero() && !s.calendar.Open(t); i++ {
+		reopen := s.calendar.NextOpen(t)
+		if s.closedInterval > 0 {
+			slow := from.Add(s.closedInterval)
+			if slow.Before(t) {
+				slow = t
+			}
+			if reopen.IsZero() || slow.Before(reopen) {
+				return slow
+			}
+		}
+		if reopen.IsZero() {
+			break
+		}
+		if s.cron == nil {
+			return reopen
+		}
+		t = s.cron.Next(reopen.Add(-time.Minute).In(s.loc))
+	}
+	return t.UTC()
+}
+
+// open reports whether the market is open at t.
+func (s *refreshSchedule) open(t time.Time) bool {
+	return s.calendar.Open(t)
+}
+
+func (s *refreshSchedule) String() string {
+	every := "every " + s.interval.String()
+	if s.cron != nil {
+		every = fmt.Sprintf("cron %q %s", s.cron.String(), s.loc)
+	}
+	return fmt.Sprintf("%s, market %s", every, s.calendar)
+}
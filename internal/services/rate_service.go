@@ -1,200 +1,622 @@
-package services
-
-import (
-	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"net/http"
-	"strings"
-	"sync"
-	"time"
-
-	"github.com/spksupakorn/Currency-Converter/config"
-	"github.com/spksupakorn/Currency-Converter/internal/repositories"
-	"github.com/spksupakorn/Currency-Converter/pkg/logger"
-)
-
-type RateService interface {
-	StartBackgroundRefresh(ctx context.Context)
-	GetRates(base string) (baseOut string, rates map[string]float64, updatedAt time.Time, err error)
-	Convert(from, to string, amount float64) (rate float64, result float64, updatedAt time.Time, err error)
-}
-
-type rateService struct {
-	cfg    config.Config
-	repo   repositories.RateRepository
-	log    *logger.Logger
-	client *http.Client
-
-	mu         sync.RWMutex
-	cacheBase  string
-	cacheRates map[string]float64
-	cacheAt    time.Time
-}
-
-func NewRateService(cfg config.Config, repo repositories.RateRepository, log *logger.Logger) RateService {
-	return &rateService{
-		cfg:    cfg,
-		repo:   repo,
-		log:    log,
-		client: &http.Client{Timeout: cfg.HTTPClientTimeout},
-	}
-}
-
-func (s *rateService) StartBackgroundRefresh(ctx context.Context) {
-	// Initial load
-	go func() {
-		if err := s.refresh(ctx); err != nil {
-			s.log.Error("initial rate refresh failed", logger.Fields{"error": err.Error()})
-		}
-	}()
-
-	ticker := time.NewTicker(s.cfg.RateRefreshInterval)
-	go func() {
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				if err := s.refresh(ctx); err != nil {
-					s.log.Error("rate refresh failed", logger.Fields{"error": err.Error()})
-				}
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
-}
-
-func (s *rateService) refresh(ctx context.Context) error {
-	base := s.cfg.RateBaseCurrency
-	base = strings.ToUpper(strings.TrimSpace(base))
-	if base == "" {
-		base = "USD"
-	}
-
-	url := fmt.Sprintf(s.cfg.ExchangeAPIURL+"%s/latest/%s", s.cfg.ExchangeAPIKey, base)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("rates api error: %s", resp.Status)
-	}
-
-	var out struct {
-		BaseCode        string             `json:"base_code"`
-		ConversionRates map[string]float64 `json:"conversion_rates"`
-	}
-	dec := json.NewDecoder(resp.Body)
-	if err := dec.Decode(&out); err == nil && out.BaseCode != "" && len(out.ConversionRates) > 0 {
-		if out.ConversionRates == nil {
-			out.ConversionRates = map[string]float64{}
-		}
-		out.ConversionRates[out.BaseCode] = 1.0
-		now := time.Now().UTC()
-		if err := s.repo.UpsertRates(out.ConversionRates, now); err != nil {
-			return err
-		}
-
-		s.mu.Lock()
-		s.cacheBase = out.BaseCode
-		s.cacheRates = out.ConversionRates
-		s.cacheAt = now
-		s.mu.Unlock()
-
-		s.log.Info("rates refreshed", logger.Fields{"base": out.BaseCode, "count": len(out.ConversionRates)})
-		return nil
-	}
-
-	return errors.New("failed to decode rates response")
-}
-
-func (s *rateService) GetRates(base string) (string, map[string]float64, time.Time, error) {
-	base = normalizeCurrency(base)
-	s.mu.RLock()
-	rates := s.cacheRates
-	cacheBase := s.cacheBase
-	cacheAt := s.cacheAt
-	s.mu.RUnlock()
-
-	if len(rates) == 0 {
-		// Fallback to DB
-		dbRates, updatedAt, err := s.repo.GetAllRates()
-		if err != nil || len(dbRates) == 0 {
-			return "", nil, time.Time{}, errors.New("rates are not available yet")
-		}
-		rates = dbRates
-		cacheBase = s.cfg.RateBaseCurrency
-		cacheAt = updatedAt
-	}
-
-	// If requested base equals cache base, return as-is
-	if base == "" || base == cacheBase {
-		// Copy to avoid mutation
-		out := make(map[string]float64, len(rates))
-		for k, v := range rates {
-			out[k] = v
-		}
-		out[cacheBase] = 1.0
-		return cacheBase, out, cacheAt, nil
-	}
-
-	// Derive rates for requested base: rate(base->X) = rate(cacheBase->X) / rate(cacheBase->base)
-	baseRate, ok := rates[base]
-	if !ok || baseRate == 0 {
-		return "", nil, time.Time{}, fmt.Errorf("unsupported base currency: %s", base)
-	}
-	out := make(map[string]float64, len(rates))
-	for cur, r := range rates {
-		out[cur] = r / baseRate
-	}
-	out[base] = 1.0
-	return base, out, cacheAt, nil
-}
-
-func (s *rateService) Convert(from, to string, amount float64) (float64, float64, time.Time, error) {
-	from = normalizeCurrency(from)
-	to = normalizeCurrency(to)
-	if from == "" || to == "" {
-		return 0, 0, time.Time{}, errors.New("from and to currencies are required")
-	}
-	if amount < 0 {
-		return 0, 0, time.Time{}, errors.New("amount must be non-negative")
-	}
-
-	s.mu.RLock()
-	rates := s.cacheRates
-	cacheAt := s.cacheAt
-	s.mu.RUnlock()
-
-	if len(rates) == 0 {
-		dbRates, updatedAt, err := s.repo.GetAllRates()
-		if err != nil || len(dbRates) == 0 {
-			return 0, 0, time.Time{}, errors.New("exchange rates are not available")
-		}
-		rates = dbRates
-		cacheAt = updatedAt
-	}
-
-	// Convert via cacheBase: rate(from->to) = (rate(cacheBase->to) / rate(cacheBase->from))
-	rFrom, okFrom := rates[from]
-	rTo, okTo := rates[to]
-	if !okFrom || rFrom == 0 {
-		return 0, 0, time.Time{}, fmt.Errorf("unsupported currency: %s", from)
-	}
-	if !okTo {
-		return 0, 0, time.Time{}, fmt.Errorf("unsupported currency: %s", to)
-	}
-
-	rate := rTo / rFrom
-	result := amount * rate
-	return rate, result, cacheAt, nil
-}
-
-func normalizeCurrency(s string) string {
-	return strings.ToUpper(strings.TrimSpace(s))
-}
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/database"
+	"github.com/spksupakorn/Currency-Converter/internal/events"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/internal/schedule"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// ErrRatesStale is returned instead of rates older than RATE_MAX_AGE when
+// RATE_STALE_POLICY is refuse.
+var ErrRatesStale = errors.New("rates are stale")
+
+// ErrOverrideNotFound is returned when deleting an override that does not
+// exist.
+var ErrOverrideNotFound = errors.New("override not found")
+
+// ErrInvalidOverride wraps the reasons SetOverride refuses an override, as
+// opposed to a store failure.
+var ErrInvalidOverride = errors.New("invalid override")
+
+// maxOverrideTTL bounds how far ahead an override may expire, so a forgotten
+// one cannot pin a rate indefinitely.
+const maxOverrideTTL = 30 * 24 * time.Hour
+
+type RateService interface {
+	// RunBackgroundRefresh fetches rates on schedule until ctx is cancelled
+	// and returns once the refresh in flight, if any, has finished.
+	RunBackgroundRefresh(ctx context.Context)
+	// RunSync reloads rates and overrides written by other instances, so
+	// that followers serve what the leader fetched, until ctx is cancelled.
+	RunSync(ctx context.Context)
+	// GetRates returns rates quoted against base. The map is shared between
+	// callers and must not be modified.
+	GetRates(ctx context.Context, base string) (rates map[string]float64, info RateInfo, err error)
+	Convert(ctx context.Context, from, to string, amount float64) (rate float64, result float64, info RateInfo, err error)
+	Status() RateStatus
+	UpdateSettings(old, new config.Config) error
+	// OnPublish registers fn to be called with every rate table published
+	// by a refresh or an approved quarantine. fn must not block.
+	OnPublish(fn func(PublishedRates))
+
+	// Refresh fetches rates now, bypassing the circuit breaker, and returns
+	// once they are published or the fetch has failed.
+	Refresh(ctx context.Context) (RefreshResult, error)
+	// SetOverride pins a rate until o.ExpiresAt, replacing any override for
+	// the same currency; o.Base defaults to the snapshot base.
+	SetOverride(ctx context.Context, o models.RateOverride) (models.RateOverride, error)
+	DeleteOverride(ctx context.Context, currency string) error
+	ListOverrides(ctx context.Context) ([]models.RateOverride, error)
+
+	// ListQuarantines returns quarantined rate tables with status, or all
+	// when empty, newest first.
+	ListQuarantines(ctx context.Context, status string) ([]models.RateQuarantine, error)
+	// ApproveQuarantine publishes a pending quarantined table as if it had
+	// just been fetched; RejectQuarantine discards it.
+	ApproveQuarantine(ctx context.Context, id uint, byID *uint, byEmail string) (models.RateQuarantine, error)
+	RejectQuarantine(ctx context.Context, id uint, byID *uint, byEmail string) (models.RateQuarantine, error)
+}
+
+// RefreshResult describes the rates published by a manual refresh.
+type RefreshResult struct {
+	Base      string
+	Provider  string
+	Count     int
+	FetchedAt time.Time
+	Duration  time.Duration
+}
+
+// PublishedRates is a newly published rate table, with overrides applied.
+// Rates is shared between listeners and must not be modified.
+type PublishedRates struct {
+	Base      string
+	Provider  string
+	FetchedAt time.Time
+	Rates     map[string]float64
+}
+
+// RateInfo describes where served rates came from and how fresh they are.
+type RateInfo struct {
+	Base          string
+	Provider      string
+	FetchedAt     time.Time
+	NextRefreshAt time.Time
+	Age           time.Duration
+	Stale         bool
+	// Overrides lists the currencies whose rate is pinned by an override;
+	// OverriddenAt is when the overrides last changed and OverridesExpireAt
+	// when the first of them runs out.
+	Overrides         []string
+	OverriddenAt      time.Time
+	OverridesExpireAt time.Time
+}
+
+// RateStatus describes the state of the in-memory rate cache.
+type RateStatus struct {
+	Base            string
+	Provider        string
+	Count           int
+	UpdatedAt       time.Time
+	RefreshInterval time.Duration
+	// NextRefreshAt is when the background loop will next fetch rates; zero
+	// before it has started.
+	NextRefreshAt time.Time
+	// Schedule describes when rates are refreshed and MarketOpen whether the
+	// market calendar has the market open now.
+	Schedule   string
+	MarketOpen bool
+	// MaxAge is how old the current rates may get before they are stale.
+	MaxAge    time.Duration
+	Stale     bool
+	Overrides int
+	// PendingQuarantine is the ID of the fetched table awaiting approval
+	// on this instance, 0 when there is none.
+	PendingQuarantine uint
+
+	// Breaker is the provider circuit state, one of BreakerClosed,
+	// BreakerOpen or BreakerHalfOpen.
+	Breaker             string
+	BreakerOpenUntil    time.Time
+	ConsecutiveFailures int
+	LastError           string
+	LastFailureAt       time.Time
+}
+
+type rateService struct {
+	cfg            atomic.Pointer[config.Config]
+	repo           repositories.RateRepository
+	overridesRepo  repositories.RateOverrideRepository
+	quarantineRepo repositories.RateQuarantineRepository
+	tx             repositories.Transactor
+	publisher      events.Publisher
+	notifier       database.Notifier
+	log            *logger.Logger
+	client         *http.Client
+
+	// snap is the current rate snapshot; nil until the first load.
+	snap   atomic.Pointer[rateSnapshot]
+	nextAt atomic.Int64 // unix nanoseconds, 0 when unscheduled
+	// sched is the refresh schedule, replaced when its settings change.
+	sched atomic.Pointer[refreshSchedule]
+
+	breaker *circuitBreaker
+	// overrides is the active override set, applied to every snapshot.
+	overrides atomic.Pointer[overrideSet]
+	// refreshing is held for the duration of a refresh, so that a manual
+	// refresh and the loop never publish out of order.
+	refreshing chan struct{}
+	// pendingQuarantine is the ID of the quarantine awaiting approval.
+	pendingQuarantine atomic.Uint64
+
+	listenersMu sync.Mutex
+	listeners   []func(PublishedRates)
+
+	// reconfigured wakes the refresh loop after UpdateSettings; refreshed
+	// tells it that a manual refresh succeeded.
+	reconfigured chan struct{}
+	refreshed    chan struct{}
+	// looping is set while the refresh loop runs on this instance.
+	looping atomic.Bool
+}
+
+// NewRateService publishes a rates.published event to publisher for every
+// rate table it publishes, in the transaction that stores the table, and
+// tells the other instances through notifier, which may be nil.
+func NewRateService(cfg config.Config, repos repositories.Repositories, publisher events.Publisher, notifier database.Notifier, log *logger.Logger) RateService {
+	s := &rateService{
+		repo:           repos.Rates,
+		overridesRepo:  repos.Overrides,
+		quarantineRepo: repos.Quarantine,
+		tx:             repos.Tx,
+		publisher:      publisher,
+		notifier:       notifier,
+		log:            log,
+		client:         &http.Client{Timeout: cfg.HTTPClientTimeout},
+		breaker:        newCircuitBreaker(),
+		refreshing:     make(chan struct{}, 1),
+		reconfigured:   make(chan struct{}, 1),
+		refreshed:      make(chan struct{}, 1),
+	}
+	s.cfg.Store(&cfg)
+	sched, err := newRefreshSchedule(&cfg)
+	if err != nil {
+		// Validated config always builds; fall back to the plain interval.
+		log.Error("invalid rate refresh schedule", logger.Fields{"error": err.Error()})
+		sched = &refreshSchedule{interval: cfg.RateRefreshInterval, loc: time.UTC}
+		sched.calendar, _ = schedule.NewCalendar(schedule.CalendarAlways, nil, time.UTC)
+	}
+	s.sched.Store(sched)
+	return s
+}
+
+// settings returns the current configuration; cfg may be swapped by
+// UpdateSettings at any time. The result is shared and must not be
+// modified; it is not copied because the read path calls this per request.
+func (s *rateService) settings() *config.Config {
+	return s.cfg.Load()
+}
+
+// UpdateSettings applies reloaded settings. The refresh loop resets its
+// ticker to the new interval and refreshes right away if the base changed.
+func (s *rateService) UpdateSettings(old, new config.Config) error {
+	rescheduled := !sameRefreshSchedule(old, new)
+	if rescheduled {
+		sched, err := newRefreshSchedule(&new)
+		if err != nil {
+			return err
+		}
+		s.sched.Store(sched)
+	}
+	s.cfg.Store(&new)
+	if !rescheduled && old.RateBaseCurrency == new.RateBaseCurrency {
+		return nil
+	}
+	select {
+	case s.reconfigured <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *rateService) OnPublish(fn func(PublishedRates)) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+func (s *rateService) notifyPublished(snap *rateSnapshot) {
+	s.listenersMu.Lock()
+	listeners := s.listeners
+	s.listenersMu.Unlock()
+	if len(listeners) == 0 {
+		return
+	}
+	rates, _ := snap.view(snap.base)
+	p := PublishedRates{Base: snap.base, Provider: snap.provider, FetchedAt: snap.fetchedAt, Rates: rates}
+	for _, fn := range listeners {
+		fn(p)
+	}
+}
+
+// RunBackgroundRefresh is the refresh loop. It fetches rates at the times
+// the refresh schedule gives, and besides keeps a retry timer: after a
+// failed refresh the provider is tried again early, on a schedule of its own
+// that backs off per consecutive failure and honours Retry-After and an
+// open circuit.
+func (s *rateService) RunBackgroundRefresh(ctx context.Context) {
+	s.looping.Store(true)
+	defer s.looping.Store(false)
+
+	sched := s.sched.Load()
+	tickAt := sched.next(time.Now())
+	// A new leader whose predecessor fetched recently waits for the run
+	// after that fetch instead of calling the provider again at once.
+	fresh := false
+	if snap, _, err := s.snapshot(ctx); err == nil && snap.base == s.settings().RateBaseCurrency {
+		if next := sched.next(snap.fetchedAt); next.After(time.Now()) {
+			tickAt, fresh = next, true
+		}
+	}
+	tick := time.NewTimer(time.Until(tickAt))
+	defer tick.Stop()
+
+	retry := time.NewTimer(time.Hour)
+	retry.Stop()
+	defer retry.Stop()
+	var retryAt time.Time
+
+	attempt := func() {
+		err := s.refresh(ctx, false)
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err == nil, errors.Is(err, ErrRatesQuarantined):
+			// Refetching quarantined rates early would most likely
+			// quarantine them again; wait for an admin or the next tick.
+			retry.Stop()
+			retryAt = time.Time{}
+		default:
+			delay := s.retryDelay(err)
+			retryAt = time.Now().Add(delay)
+			if !sched.open(retryAt) && sched.closedInterval == 0 {
+				// The market is closed by then; the first run after it
+				// opens again fetches the rates.
+				retry.Stop()
+				retryAt = time.Time{}
+			} else {
+				retry.Reset(delay)
+			}
+			s.log.Error("rate refresh failed", logger.Fields{
+				"error":    err.Error(),
+				"retry_in": delay.String(),
+			})
+		}
+		s.scheduleNext(tickAt, retryAt)
+	}
+	// reschedule moves the next tick to the first run after from.
+	reschedule := func(from time.Time) {
+		tickAt = sched.next(from)
+		if now := time.Now(); tickAt.Before(now) {
+			// Fell behind by more than a whole period: run once now
+			// rather than catching up on every missed run.
+			tickAt = sched.next(now)
+		}
+		tick.Reset(time.Until(tickAt))
+		s.scheduleNext(tickAt, retryAt)
+	}
+
+	s.scheduleNext(tickAt, retryAt)
+	if !fresh {
+		attempt()
+	}
+	for {
+		select {
+		case <-tick.C:
+			reschedule(tickAt)
+			attempt()
+		case <-retry.C:
+			retryAt = time.Time{}
+			attempt()
+		case <-s.refreshed:
+			retry.Stop()
+			retryAt = time.Time{}
+			s.scheduleNext(tickAt, retryAt)
+		case <-s.reconfigured:
+			if next := s.sched.Load(); next != sched {
+				sched = next
+				reschedule(time.Now())
+				s.log.Info("rate refresh schedule changed", logger.Fields{
+					"schedule":        sched.String(),
+					"next_refresh_at": tickAt,
+				})
+			}
+			if snap := s.snap.Load(); snap != nil && snap.base != s.settings().RateBaseCurrency {
+				attempt()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// retryDelay is how long to wait before retrying after a failed refresh:
+// RATE_FAILURE_RETRY_INTERVAL doubled per consecutive failure, capped at the
+// refresh interval, but never before a Retry-After or an open circuit allows.
+func (s *rateService) retryDelay(err error) time.Duration {
+	cfg := s.settings()
+	st := s.breaker.status()
+	delay := backoff(cfg.RateFailureRetryInterval, cfg.RateRefreshInterval, max(st.Failures, 1))
+	var ue *upstreamError
+	if errors.As(err, &ue) && ue.RetryAfter > delay {
+		delay = ue.RetryAfter
+	}
+	if st.State == BreakerOpen {
+		delay = max(delay, time.Until(st.OpenUntil))
+	}
+	return delay
+}
+
+// scheduleNext publishes the earlier of the next tick and the next retry.
+func (s *rateService) scheduleNext(tickAt, retryAt time.Time) {
+	next := tickAt
+	if !retryAt.IsZero() && retryAt.Before(next) {
+		next = retryAt
+	}
+	s.nextAt.Store(next.UnixNano())
+}
+
+func (s *rateService) nextRefreshAt() time.Time {
+	n := s.nextAt.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
+
+// refresh fetches the latest rates through the circuit breaker, or around it
+// when manual, validates them and publishes them with the current overrides
+// applied. Rates that fail validation do not count against the provider.
+func (s *rateService) refresh(ctx context.Context, manual bool) error {
+	select {
+	case s.refreshing <- struct{}{}:
+		defer func() { <-s.refreshing }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	cfg := s.settings()
+	base := cfg.RateBaseCurrency
+	base = strings.ToUpper(strings.TrimSpace(base))
+	if base == "" {
+		base = "USD"
+	}
+
+	if !s.breaker.allow(time.Now()) && !manual {
+		return fmt.Errorf("%w until %s", errCircuitOpen, s.breaker.status().OpenUntil.UTC().Format(time.RFC3339))
+	}
+	stored, err := s.fetchWithRetry(ctx, *cfg, base)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		var ue *upstreamError
+		var notBefore time.Time
+		quota := false
+		if errors.As(err, &ue) {
+			quota = ue.Kind == upstreamQuotaReached
+			if ue.RetryAfter > 0 {
+				notBefore = time.Now().Add(ue.RetryAfter)
+			}
+		}
+		if s.breaker.failure(time.Now(), err, cfg.RateBreakerThreshold, cfg.RateBreakerCooldown, quota, notBefore) {
+			st := s.breaker.status()
+			s.log.Warn("rate provider circuit open", logger.Fields{
+				"failures":   st.Failures,
+				"open_until": st.OpenUntil,
+				"error":      err.Error(),
+			})
+		}
+		return err
+	}
+	if s.breaker.success() {
+		s.log.Info("rate provider circuit closed", logger.Fields{"provider": stored.Provider})
+	}
+
+	// Rates fetched before shutdown are still stored.
+	if err := s.validateAndPublish(context.WithoutCancel(ctx), stored); err != nil {
+		return err
+	}
+	s.log.Info("rates refreshed", logger.Fields{"base": stored.Base, "count": len(stored.Rates)})
+	return nil
+}
+
+// providerName identifies the upstream by host, e.g. v6.exchangerate-api.com.
+func providerName(apiURL string) string {
+	u, err := url.Parse(apiURL)
+	if err != nil || u.Host == "" {
+		return apiURL
+	}
+	return u.Host
+}
+
+// snapshot returns the current snapshot, loading the last stored rates from
+// the database when nothing has been fetched yet. It applies the staleness
+// policy to whichever snapshot it returns.
+func (s *rateService) snapshot(ctx context.Context) (*rateSnapshot, RateInfo, error) {
+	snap := s.snap.Load()
+	if snap == nil {
+		stored, err := s.repo.GetAllRates(ctx)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, RateInfo{}, ctxErr
+		}
+		if err != nil || len(stored.Rates) == 0 {
+			return nil, RateInfo{}, errors.New("rates are not available yet")
+		}
+		if stored.Base == "" {
+			// Written before the base was recorded: the configured base was
+			// the only one ever used.
+			stored.Base = s.settings().RateBaseCurrency
+		}
+		if s.overrides.Load() == nil {
+			if err := s.loadOverrides(ctx, time.Time{}); err != nil {
+				s.log.Warn("failed to load rate overrides", logger.Fields{"error": err.Error()})
+			}
+		}
+		snap = newRateSnapshot(stored, s.overrides.Load(), time.Now())
+		// A refresh that finished in the meantime wins.
+		if !s.snap.CompareAndSwap(nil, snap) {
+			snap = s.snap.Load()
+		}
+	}
+
+	now := time.Now()
+	if !snap.expiresAt.IsZero() && !now.Before(snap.expiresAt) {
+		snap = s.expireOverrides(snap, now)
+	}
+	info := s.info(snap, now)
+	if info.Stale {
+		switch s.settings().RateStalePolicy {
+		case config.StalePolicyRefuse:
+			return nil, info, ErrRatesStale
+		case config.StalePolicyWarn:
+			if snap.staleWarned.CompareAndSwap(false, true) {
+				s.log.Warn("serving stale rates", logger.Fields{
+					"fetched_at":  snap.fetchedAt,
+					"age_seconds": int64(info.Age.Seconds()),
+					"provider":    snap.provider,
+				})
+			}
+		}
+	}
+	return snap, info, nil
+}
+
+func (s *rateService) info(snap *rateSnapshot, now time.Time) RateInfo {
+	age := now.Sub(snap.fetchedAt)
+	staleAt := s.staleAt(snap)
+	return RateInfo{
+		Base:              snap.base,
+		Provider:          snap.provider,
+		FetchedAt:         snap.fetchedAt,
+		NextRefreshAt:     s.nextRefreshAt(),
+		Age:               age,
+		Stale:             !staleAt.IsZero() && now.After(staleAt),
+		Overrides:         snap.overridden,
+		OverriddenAt:      snap.overriddenAt,
+		OverridesExpireAt: snap.expiresAt,
+	}
+}
+
+// staleAt is when snap goes stale: RATE_MAX_AGE after it was fetched, or,
+// when that is unset, once the second scheduled refresh after the fetch is
+// due, so that one missed refresh is tolerated and closed markets are not.
+func (s *rateService) staleAt(snap *rateSnapshot) time.Time {
+	if maxAge := s.settings().RateMaxAge; maxAge > 0 {
+		return snap.fetchedAt.Add(maxAge)
+	}
+	sched := s.sched.Load()
+	if m := snap.stale.Load(); m != nil && m.sched == sched {
+		return m.at
+	}
+	at := sched.next(sched.next(snap.fetchedAt))
+	snap.stale.Store(&staleMark{sched: sched, at: at})
+	return at
+}
+
+func (s *rateService) GetRates(ctx context.Context, base string) (map[string]float64, RateInfo, error) {
+	base = normalizeCurrency(base)
+	snap, info, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, info, err
+	}
+	if base == "" {
+		base = snap.base
+	}
+	rates, ok := snap.view(base)
+	if !ok {
+		return nil, RateInfo{}, fmt.Errorf("unsupported base currency: %s", base)
+	}
+	info.Base = base
+	return rates, info, nil
+}
+
+func (s *rateService) Convert(ctx context.Context, from, to string, amount float64) (float64, float64, RateInfo, error) {
+	from = normalizeCurrency(from)
+	to = normalizeCurrency(to)
+	if from == "" || to == "" {
+		return 0, 0, RateInfo{}, errors.New("from and to currencies are required")
+	}
+	if amount < 0 {
+		return 0, 0, RateInfo{}, errors.New("amount must be non-negative")
+	}
+
+	snap, info, err := s.snapshot(ctx)
+	if err != nil {
+		return 0, 0, info, err
+	}
+
+	// Convert via the snapshot base: rate(from->to) = rate(base->to) / rate(base->from)
+	rFrom, okFrom := snap.rate(from)
+	rTo, okTo := snap.rate(to)
+	if !okFrom || rFrom == 0 {
+		return 0, 0, RateInfo{}, fmt.Errorf("unsupported currency: %s", from)
+	}
+	if !okTo {
+		return 0, 0, RateInfo{}, fmt.Errorf("unsupported currency: %s", to)
+	}
+
+	rate := rTo / rFrom
+	result := amount * rate
+	return rate, result, info, nil
+}
+
+func (s *rateService) Status() RateStatus {
+	cfg := s.settings()
+	st := RateStatus{
+		RefreshInterval: cfg.RateRefreshInterval,
+		NextRefreshAt:   s.nextRefreshAt(),
+		MaxAge:          cfg.RateMaxAge,
+	}
+	sched := s.sched.Load()
+	st.Schedule = sched.String()
+	st.MarketOpen = sched.open(time.Now())
+	b := s.breaker.status()
+	st.Breaker = b.State
+	st.BreakerOpenUntil = b.OpenUntil
+	st.ConsecutiveFailures = b.Failures
+	st.LastError = b.LastError
+	st.LastFailureAt = b.LastFail
+	st.PendingQuarantine = uint(s.pendingQuarantine.Load())
+	if snap := s.snap.Load(); snap != nil {
+		info := s.info(snap, time.Now())
+		st.Base = snap.base
+		st.Provider = snap.provider
+		st.Count = len(snap.currencies)
+		st.UpdatedAt = snap.fetchedAt
+		st.Stale = info.Stale
+		if st.MaxAge == 0 {
+			st.MaxAge = s.staleAt(snap).Sub(snap.fetchedAt)
+		}
+		st.Overrides = len(snap.overridden)
+	}
+	return st
+}
+
+func normalizeCurrency(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
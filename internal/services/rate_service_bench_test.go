@@ -0,0 +1,81 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/events"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories/memory"
+	"github.com/spksupakorn/Currency-Converter/internal/services"
+	"github.com/spksupakorn/Currency-Converter/internal/testutil"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// benchRates returns a provider-sized table of 160 currencies.
+func benchRates() map[string]float64 {
+	rates := map[string]float64{"USD": 1, "EUR": 0.92, "THB": 36.5}
+	for i := 0; len(rates) < 160; i++ {
+		rates[fmt.Sprintf("%c%c%c", 'A'+i/26%26, 'A'+i%26, 'X')] = 1 + float64(i)/10
+	}
+	return rates
+}
+
+func newLoadedRateService(b *testing.B) services.RateService {
+	b.Helper()
+	provider := testutil.NewRateProvider("USD", benchRates())
+	b.Cleanup(provider.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Cleanup(cancel)
+	svc := services.NewRateService(testutil.Config(provider.URL()), memory.NewRepositories(), events.Discard, nil, logger.New(logger.Options{Level: "error"}))
+	go svc.RunBackgroundRefresh(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for svc.Status().Count == 0 {
+		if time.Now().After(deadline) {
+			b.Fatal("rates not loaded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return svc
+}
+
+func BenchmarkGetRatesCacheBase(b *testing.B) {
+	svc := newLoadedRateService(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, _, err := svc.GetRates(ctx, "USD"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetRatesDerivedBase(b *testing.B) {
+	svc := newLoadedRateService(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, _, err := svc.GetRates(ctx, "EUR"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkConvert(b *testing.B) {
+	svc := newLoadedRateService(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, _, _, err := svc.Convert(ctx, "EUR", "THB", 100); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+)
+
+// rateSnapshot is one immutable set of rates, quoted against base. A new
+// snapshot is built on every refresh and published with an atomic pointer
+// swap, so readers never lock. Only the per-base views are filled in later,
+// each exactly once.
+type rateSnapshot struct {
+	base      string
+	provider  string
+	fetchedAt time.Time
+
+	// stored is the provider data the snapshot was built from, kept so that
+	// a change of overrides can be applied without another fetch.
+	stored repositories.StoredRates
+	// overridden lists the currencies whose rate was pinned by an override,
+	// sorted, and expiresAt is when the first of them runs out. overriddenAt
+	// is when the set of overrides last changed.
+	overridden   []string
+	overriddenAt time.Time
+	expiresAt    time.Time
+
+	// currencies is sorted; rates[i] is the price of currencies[i] in base.
+	currencies []string
+	rates      []float64
+	index      map[string]int
+
+	views sync.Map // base currency -> *rateView
+
+	// staleWarned is set once the stale policy has logged this snapshot.
+	staleWarned atomic.Bool
+	// stale caches when the snapshot goes stale under a refresh schedule.
+	stale atomic.Pointer[staleMark]
+}
+
+type staleMark struct {
+	sched *refreshSchedule
+	at    time.Time
+}
+
+type rateView struct {
+	once  sync.Once
+	rates map[string]float64
+}
+
+// overrideSet is the list of active overrides and when it last changed.
+type overrideSet struct {
+	list      []models.RateOverride
+	changedAt time.Time
+}
+
+// newRateSnapshot builds a snapshot from stored, with the overrides in set
+// that are still active at now applied on top.
+func newRateSnapshot(stored repositories.StoredRates, set *overrideSet, now time.Time) *rateSnapshot {
+	base, rates := stored.Base, stored.Rates
+	s := &rateSnapshot{
+		base:       base,
+		provider:   stored.Provider,
+		fetchedAt:  stored.UpdatedAt,
+		stored:     stored,
+		currencies: make([]string, 0, len(rates)+1),
+		index:      make(map[string]int, len(rates)+1),
+	}
+	if set != nil {
+		s.overriddenAt = set.changedAt
+		if len(set.list) > 0 {
+			rates = s.applyOverrides(rates, set, now)
+		}
+	}
+	for cur := range rates {
+		s.currencies = append(s.currencies, cur)
+	}
+	if _, ok := rates[base]; !ok {
+		s.currencies = append(s.currencies, base)
+	}
+	sort.Strings(s.currencies)
+	s.rates = make([]float64, len(s.currencies))
+	for i, cur := range s.currencies {
+		s.index[cur] = i
+		s.rates[i] = rates[cur]
+	}
+	s.rates[s.index[base]] = 1.0
+	return s
+}
+
+// applyOverrides returns a copy of rates with every active override applied.
+// An override quoted against another base is converted through the provider
+// rate of that base; one whose base has no rate, or that targets the snapshot
+// base itself, cannot be applied and is skipped.
+func (s *rateSnapshot) applyOverrides(rates map[string]float64, set *overrideSet, now time.Time) map[string]float64 {
+	out := make(map[string]float64, len(rates)+len(set.list))
+	for cur, r := range rates {
+		out[cur] = r
+	}
+	for _, o := range set.list {
+		if !o.ExpiresAt.After(now) || o.Currency == s.base {
+			continue
+		}
+		baseRate := 1.0
+		if o.Base != s.base {
+			var ok bool
+			if baseRate, ok = rates[o.Base]; !ok || baseRate == 0 {
+				continue
+			}
+		}
+		out[o.Currency] = o.Rate * baseRate
+		s.overridden = append(s.overridden, o.Currency)
+		if s.expiresAt.IsZero() || o.ExpiresAt.Before(s.expiresAt) {
+			s.expiresAt = o.ExpiresAt
+		}
+	}
+	sort.Strings(s.overridden)
+	return out
+}
+
+// rate returns the price of cur in the snapshot base.
+func (s *rateSnapshot) rate(cur string) (float64, bool) {
+	i, ok := s.index[cur]
+	if !ok {
+		return 0, false
+	}
+	return s.rates[i], true
+}
+
+// view returns every rate quoted against base. The map is built on first use
+// and shared by all later callers, so it must not be modified.
+func (s *rateSnapshot) view(base string) (map[string]float64, bool) {
+	baseRate, ok := s.rate(base)
+	if !ok || baseRate == 0 {
+		return nil, false
+	}
+	v, ok := s.views.Load(base)
+	if !ok {
+		v, _ = s.views.LoadOrStore(base, &rateView{})
+	}
+	rv := v.(*rateView)
+	rv.once.Do(func() {
+		m := make(map[string]float64, len(s.currencies))
+		for i, cur := range s.currencies {
+			m[cur] = s.rates[i] / baseRate
+		}
+		m[base] = 1.0
+		rv.rates = m
+	})
+	return rv.rates, true
+}
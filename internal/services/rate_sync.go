@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// RatesChangedChannel tells every instance that the stored rates or the
+// overrides changed, so they reload them instead of waiting for the poll.
+const RatesChangedChannel = "rates_changed"
+
+// RunSync keeps the snapshot of this instance in step with rates and
+// overrides written by others: once at start, on every notification, when
+// the database has a notifier, and every RATE_SYNC_INTERVAL.
+func (s *rateService) RunSync(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	// A follower would otherwise serve nothing until the first tick or
+	// notification, and with neither it never would.
+	s.syncLogged(ctx)
+	if s.notifier != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A reconnect may have missed notifications, so it reloads too.
+			reload := func() { s.syncLogged(ctx) }
+			err := s.notifier.Listen(ctx, RatesChangedChannel, reload, func(string) { reload() })
+			if err != nil {
+				s.log.Error("rate change listener stopped", logger.Fields{"error": err.Error()})
+			}
+		}()
+	}
+
+	interval := s.settings().RateSyncInterval
+	if interval <= 0 {
+		<-ctx.Done()
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.syncLogged(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *rateService) syncLogged(ctx context.Context) {
+	if err := s.sync(ctx); err != nil && ctx.Err() == nil {
+		s.log.Warn("failed to reload rates", logger.Fields{"error": err.Error()})
+	}
+}
+
+// sync reloads the stored rates and the overrides, and swaps in a new
+// snapshot when either changed. Listeners are not called: alerts were
+// evaluated by the instance that published the rates.
+func (s *rateService) sync(ctx context.Context) error {
+	select {
+	case s.refreshing <- struct{}{}:
+		defer func() { <-s.refreshing }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// Right after a notification a replica may not have the write yet.
+	ctx = repositories.ReadPrimary(ctx)
+	stored, err := s.repo.GetAllRates(ctx)
+	if err != nil || len(stored.Rates) == 0 {
+		return err
+	}
+	if stored.Base == "" {
+		stored.Base = s.settings().RateBaseCurrency
+	}
+	prev := s.overrides.Load()
+	if err := s.loadOverrides(ctx, time.Time{}); err != nil {
+		return err
+	}
+	set := s.overrides.Load()
+	cur := s.snap.Load()
+	sameRates := cur != nil && cur.base == stored.Base && cur.fetchedAt.Equal(stored.UpdatedAt)
+	if sameRates && prev != nil && prev.changedAt.Equal(set.changedAt) {
+		return nil
+	}
+	if sameRates {
+		s.republish(time.Now())
+		return nil
+	}
+
+	snap := newRateSnapshot(stored, set, time.Now())
+	s.snap.Store(snap)
+	if !s.looping.Load() {
+		// Without a refresh loop here, the leader fetches next when the
+		// schedule says it will.
+		s.scheduleNext(s.sched.Load().next(snap.fetchedAt), time.Time{})
+	}
+	s.log.Info("rates reloaded", logger.Fields{"base": snap.base, "fetched_at": snap.fetchedAt, "count": len(snap.currencies)})
+	return nil
+}
+
+// broadcast tells the other instances to reload the rates.
+func (s *rateService) broadcast(ctx context.Context) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.Notify(context.WithoutCancel(ctx), RatesChangedChannel, s.settings().RateBaseCurrency); err != nil {
+		s.log.Warn("failed to broadcast rate change", logger.Fields{"error": err.Error()})
+	}
+}
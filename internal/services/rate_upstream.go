@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// errCircuitOpen is returned by refresh while the provider is being left
+// alone after repeated failures.
+var errCircuitOpen = errors.New("rate provider circuit is open")
+
+// Provider error types (the error-type field of exchangerate-api errors).
+const (
+	upstreamQuotaReached = "quota-reached"
+	upstreamRateLimited  = "rate-limited"
+)
+
+// upstreamError is a failed call to the rate provider.
+type upstreamError struct {
+	Status int
+	// Kind is the provider's error-type, or rate-limited for a bare 429.
+	Kind string
+	// RetryAfter is the provider's Retry-After, zero when not sent.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *upstreamError) Error() string {
+	msg := "rates api error"
+	if e.Status != 0 {
+		msg += ": " + strconv.Itoa(e.Status) + " " + http.StatusText(e.Status)
+	}
+	if e.Kind != "" {
+		msg += " (" + e.Kind + ")"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *upstreamError) Unwrap() error { return e.Err }
+
+// retryable reports whether calling again soon may succeed. Transport
+// errors, 429s and 5xx are; an exhausted quota, a bad key or a bad request
+// will not change within one refresh.
+func (e *upstreamError) retryable() bool {
+	switch {
+	case e.Kind == upstreamQuotaReached:
+		return false
+	case e.Status == 0, e.Status == http.StatusTooManyRequests, e.Status >= 500:
+		return true
+	}
+	return false
+}
+
+// fetchWithRetry calls the provider up to RATE_RETRY_ATTEMPTS times, waiting
+// a jittered, doubling delay between attempts. A Retry-After longer than
+// RATE_RETRY_MAX_DELAY ends the refresh instead of blocking it; the refresh
+// loop schedules the next attempt after it.
+func (s *rateService) fetchWithRetry(ctx context.Context, cfg config.Config, base string) (repositories.StoredRates, error) {
+	for attempt := 1; ; attempt++ {
+		stored, err := s.fetch(ctx, cfg, base)
+		if err == nil {
+			return stored, nil
+		}
+		if ctx.Err() != nil {
+			return stored, err
+		}
+		var ue *upstreamError
+		if !errors.As(err, &ue) || !ue.retryable() || attempt >= cfg.RateRetryAttempts {
+			return stored, err
+		}
+		wait := backoff(cfg.RateRetryBaseDelay, cfg.RateRetryMaxDelay, attempt)
+		if ue.RetryAfter > 0 {
+			if ue.RetryAfter > cfg.RateRetryMaxDelay {
+				return stored, err
+			}
+			wait = ue.RetryAfter
+		}
+		s.log.Warn("rate fetch failed, retrying", logger.Fields{
+			"attempt": attempt,
+			"wait_ms": wait.Milliseconds(),
+			"error":   err.Error(),
+		})
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return stored, ctx.Err()
+		}
+	}
+}
+
+// fetch makes one call to the provider's latest endpoint.
+func (s *rateService) fetch(ctx context.Context, cfg config.Config, base string) (repositories.StoredRates, error) {
+	endpoint := fmt.Sprintf(cfg.ExchangeAPIURL+"%s/latest/%s", cfg.ExchangeAPIKey, base)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return repositories.StoredRates{}, redactKey(err, cfg.ExchangeAPIKey)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return repositories.StoredRates{}, &upstreamError{Err: redactKey(err, cfg.ExchangeAPIKey)}
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Result          string             `json:"result"`
+		ErrorType       string             `json:"error-type"`
+		BaseCode        string             `json:"base_code"`
+		ConversionRates map[string]float64 `json:"conversion_rates"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&out)
+
+	if resp.StatusCode >= 300 || out.Result == "error" {
+		ue := &upstreamError{Status: resp.StatusCode, Kind: out.ErrorType}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if ue.Kind == "" {
+				ue.Kind = upstreamRateLimited
+			}
+			ue.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+		return repositories.StoredRates{}, ue
+	}
+	if decodeErr != nil || out.BaseCode == "" || len(out.ConversionRates) == 0 {
+		// A truncated or garbled body is worth another try.
+		return repositories.StoredRates{}, &upstreamError{Err: errors.New("failed to decode rates response")}
+	}
+
+	out.ConversionRates[out.BaseCode] = 1.0
+	return repositories.StoredRates{
+		Base:      out.BaseCode,
+		Provider:  providerName(cfg.ExchangeAPIURL),
+		Rates:     out.ConversionRates,
+		UpdatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// redactKey masks the API key in the request URL that net/http puts into
+// its errors, since they end up in logs and in the admin API.
+func redactKey(err error, key string) error {
+	var ue *url.Error
+	if key == "" || !errors.As(err, &ue) {
+		return err
+	}
+	return &url.Error{Op: ue.Op, URL: strings.ReplaceAll(ue.URL, key, "REDACTED"), Err: ue.Err}
+}
+
+// backoff returns base doubled for every attempt after the first, capped at
+// limit, with up to half of it taken off at random so that replicas failing
+// together do not retry together.
+func backoff(base, limit time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	if d > limit {
+		d = limit
+	}
+	half := d / 2
+	return d - half + rand.N(half+1)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date; it returns zero when the header is missing or invalid.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+)
+
+// ErrRatesRejected is returned when fetched rates fail validation: a value
+// that is not a positive number, or a missing RATE_REQUIRED_CURRENCIES
+// entry. The last good snapshot stays published.
+var ErrRatesRejected = errors.New("rates rejected")
+
+// ErrRatesQuarantined is returned when fetched rates moved by more than
+// RATE_MAX_CHANGE and were quarantined for an admin to approve or reject.
+var ErrRatesQuarantined = errors.New("rates quarantined")
+
+var (
+	ErrQuarantineNotFound = errors.New("quarantine not found")
+	// ErrQuarantineDecided is returned when deciding a quarantine that is no
+	// longer pending.
+	ErrQuarantineDecided = errors.New("quarantine is no longer pending")
+)
+
+// validateRates checks fetched rates before they are published. Invalid
+// values and missing required currencies reject the table; moves beyond
+// cfg.RateMaxChange relative to baseline, the previously published table,
+// are returned as anomalies. baseline may be empty, and is only compared
+// when quoted in the same base.
+func validateRates(fetched, baseline repositories.StoredRates, cfg *config.Config) ([]models.RateAnomaly, error) {
+	if len(fetched.Rates) == 0 {
+		return nil, fmt.Errorf("%w: the provider returned no rates", ErrRatesRejected)
+	}
+	var invalid, missing []string
+	for cur, rate := range fetched.Rates {
+		if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+			invalid = append(invalid, cur)
+		}
+	}
+	for _, cur := range cfg.RateRequiredCurrencies {
+		if _, ok := fetched.Rates[cur]; !ok && cur != fetched.Base {
+			missing = append(missing, cur)
+		}
+	}
+	if len(invalid) > 0 || len(missing) > 0 {
+		var problems []string
+		if len(invalid) > 0 {
+			slices.Sort(invalid)
+			problems = append(problems, "invalid rate for "+strings.Join(invalid, ", "))
+		}
+		if len(missing) > 0 {
+			problems = append(problems, "missing required "+strings.Join(missing, ", "))
+		}
+		return nil, fmt.Errorf("%w: %s", ErrRatesRejected, strings.Join(problems, "; "))
+	}
+
+	if cfg.RateMaxChange <= 0 || baseline.Base != fetched.Base {
+		return nil, nil
+	}
+	var anomalies []models.RateAnomaly
+	for cur, rate := range fetched.Rates {
+		prev, ok := baseline.Rates[cur]
+		if !ok || prev <= 0 {
+			continue
+		}
+		if change := (rate - prev) / prev; math.Abs(change) > cfg.RateMaxChange {
+			anomalies = append(anomalies, models.RateAnomaly{Currency: cur, Previous: prev, Current: rate, Change: change})
+		}
+	}
+	slices.SortFunc(anomalies, func(a, b models.RateAnomaly) int { return strings.Compare(a.Currency, b.Currency) })
+	return anomalies, nil
+}
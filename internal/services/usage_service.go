@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+const (
+	usageBatchSize       = 200
+	usageCleanupInterval = time.Hour
+)
+
+type UsageService interface {
+	// Run writes recorded usage in batches until ctx is cancelled, and what
+	// is still buffered then before it returns.
+	Run(ctx context.Context)
+	Record(rec models.UsageRecord)
+	Summarize(ctx context.Context, filter repositories.UsageFilter) ([]models.UsageSummary, error)
+}
+
+type usageService struct {
+	cfg  config.Config
+	repo repositories.UsageRepository
+	log  *logger.Logger
+
+	queue chan models.UsageRecord
+}
+
+func NewUsageService(cfg config.Config, repo repositories.UsageRepository, log *logger.Logger) UsageService {
+	size := cfg.UsageBufferSize
+	if size <= 0 {
+		size = 1
+	}
+	return &usageService{
+		cfg:   cfg,
+		repo:  repo,
+		log:   log,
+		queue: make(chan models.UsageRecord, size),
+	}
+}
+
+// Record enqueues rec without blocking. When the buffer is full the record is
+// dropped and logged: conversions must never wait on the usage ledger.
+func (s *usageService) Record(rec models.UsageRecord) {
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now().UTC()
+	}
+	select {
+	case s.queue <- rec:
+	default:
+		s.log.Warn("usage buffer full, dropping record", logger.Fields{
+			"user_id": rec.UserID,
+			"from":    rec.FromCurrency,
+			"to":      rec.ToCurrency,
+		})
+	}
+}
+
+func (s *usageService) Summarize(ctx context.Context, f repositories.UsageFilter) ([]models.UsageSummary, error) {
+	for _, g := range f.GroupBy {
+		if !slices.Contains([]string{repositories.UsageGroupDay, repositories.UsageGroupPair, repositories.UsageGroupUser}, g) {
+			return nil, fmt.Errorf("%w: unsupported group_by: %s", ErrInvalidQuery, g)
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+	return s.repo.Summarize(ctx, f)
+}
+
+// Run drains the buffer into the database and, when USAGE_RETENTION is set,
+// runs the retention cleanup. Pending records are flushed
+// when ctx is cancelled.
+func (s *usageService) Run(ctx context.Context) {
+	flushEvery := s.cfg.UsageFlushInterval
+	if flushEvery <= 0 {
+		flushEvery = 5 * time.Second
+	}
+	flushTicker := time.NewTicker(flushEvery)
+	defer flushTicker.Stop()
+	cleanupTicker := time.NewTicker(usageCleanupInterval)
+	defer cleanupTicker.Stop()
+
+	s.cleanup(ctx)
+
+	batch := make([]models.UsageRecord, 0, usageBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		// Detached from ctx so the final flush on shutdown still succeeds.
+		if err := s.repo.CreateBatch(context.WithoutCancel(ctx), batch); err != nil {
+			s.log.Error("failed to write usage records", logger.Fields{"count": len(batch), "error": err.Error()})
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case rec := <-s.queue:
+			batch = append(batch, rec)
+			if len(batch) >= usageBatchSize {
+				flush()
+			}
+		case <-flushTicker.C:
+			flush()
+		case <-cleanupTicker.C:
+			s.cleanup(ctx)
+		case <-ctx.Done():
+			for {
+				select {
+				case rec := <-s.queue:
+					batch = append(batch, rec)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *usageService) cleanup(ctx context.Context) {
+	if s.cfg.UsageRetention <= 0 {
+		return
+	}
+	cutoff := time.Now().UTC().Add(-s.cfg.UsageRetention)
+	n, err := s.repo.DeleteBefore(ctx, cutoff)
+	if err != nil {
+		s.log.Error("usage retention cleanup failed", logger.Fields{"error": err.Error()})
+		return
+	}
+	if n > 0 {
+		s.log.Info("usage retention cleanup", logger.Fields{"deleted": n, "cutoff": cutoff})
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spksupakorn/Currency-Converter/internal/models"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// webhookBatchSize bounds the deliveries loaded per dispatcher pass.
+const webhookBatchSize = 50
+
+const (
+	HeaderWebhookID        = "X-Webhook-Id"
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+// SignWebhook returns the X-Webhook-Signature of a call: the hex HMAC-SHA256,
+// keyed with the rule's secret, of the timestamp header, a dot and the body.
+// Receivers recompute it and should reject old timestamps to stop replays.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// errWebhookAddress is returned for webhooks that resolve to an address on
+// this host or its internal network.
+var errWebhookAddress = errors.New("webhook address is private, loopback or link-local")
+
+// blockedWebhookAddr reports whether ip may not receive webhooks: only
+// public addresses may, unless ip is in one of the allowed networks.
+func blockedWebhookAddr(ip netip.Addr, allowed []netip.Prefix) bool {
+	ip = ip.Unmap()
+	for _, p := range allowed {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast()
+}
+
+type webhookClient struct {
+	client *http.Client
+}
+
+// newWebhookClient checks every address it connects to, after DNS
+// resolution, so a webhook host cannot be re-pointed at an internal address
+// once its URL was accepted. It never uses a proxy, which would hide the
+// address.
+func newWebhookClient(timeout time.Duration, allowed []netip.Prefix) *webhookClient {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if blockedWebhookAddr(ap.Addr(), allowed) {
+				return fmt.Errorf("%w: %s", errWebhookAddress, ap.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &webhookClient{client: &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// A redirect is answered as a failure rather than followed, so a
+		// signed event only ever reaches the configured URL.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// send posts d to the webhook of rule and returns the response status; any
+// status but 2xx is an error.
+func (w *webhookClient) send(ctx context.Context, rule *models.AlertRule, d *models.AlertDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rule.WebhookURL, strings.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Currency-Converter-Webhook/1")
+	req.Header.Set(HeaderWebhookID, strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set(HeaderWebhookEvent, d.Event)
+	req.Header.Set(HeaderWebhookTimestamp, ts)
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(rule.WebhookSecret, ts, []byte(d.Payload)))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// RunDispatcher sends due deliveries every WEBHOOK_POLL_INTERVAL, and right
+// away when an alert fires.
+func (s *alertService) RunDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.WebhookPollInterval)
+	defer ticker.Stop()
+	for {
+		s.dispatchDue(ctx)
+		select {
+		case <-ticker.C:
+		case <-s.deliver:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *alertService) dispatchDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := s.repo.DueDeliveries(ctx, time.Now().UTC(), webhookBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.log.Error("failed to load due webhook deliveries", logger.Fields{"error": err.Error()})
+			}
+			return
+		}
+		for i := range due {
+			d := &due[i]
+			rule, err := s.repo.FindRule(ctx, d.UserID, d.RuleID)
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				d.Status, d.LastError = models.DeliveryDead, "alert rule was deleted"
+				if err := s.repo.UpdateDelivery(ctx, d); err != nil {
+					s.log.Error("failed to update webhook delivery", logger.Fields{"delivery_id": d.ID, "error": err.Error()})
+				}
+				continue
+			case err != nil:
+				if ctx.Err() == nil {
+					s.log.Error("failed to load alert rule", logger.Fields{"rule_id": d.RuleID, "error": err.Error()})
+				}
+				return
+			}
+			s.attempt(ctx, rule, d, s.cfg.WebhookMaxAttempts)
+		}
+		if len(due) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// attempt makes one call for d and records the outcome: delivered, pending
+// with the next attempt backed off, or dead after maxAttempts.
+func (s *alertService) attempt(ctx context.Context, rule *models.AlertRule, d *models.AlertDelivery, maxAttempts int) {
+	status, err := s.webhook.send(ctx, rule, d)
+	if ctx.Err() != nil {
+		// Shutting down: the attempt does not count and is made again later.
+		return
+	}
+	now := time.Now().UTC()
+	d.Attempts++
+	d.ResponseStatus = status
+	if err == nil {
+		d.Status, d.LastError, d.DeliveredAt = models.DeliveryDelivered, "", &now
+	} else {
+		d.LastError = err.Error()
+		if len(d.LastError) > 255 {
+			d.LastError = d.LastError[:255]
+		}
+		if d.Attempts >= maxAttempts {
+			d.Status = models.DeliveryDead
+			s.log.Warn("webhook delivery dead-lettered", logger.Fields{
+				"delivery_id": d.ID,
+				"rule_id":     d.RuleID,
+				"attempts":    d.Attempts,
+				"error":       d.LastError,
+			})
+		} else {
+			d.NextAttemptAt = now.Add(backoff(s.cfg.WebhookRetryBaseDelay, s.cfg.WebhookRetryMaxDelay, d.Attempts))
+		}
+	}
+	if err := s.repo.UpdateDelivery(ctx, d); err != nil {
+		s.log.Error("failed to update webhook delivery", logger.Fields{"delivery_id": d.ID, "error": err.Error()})
+	}
+}
@@ -0,0 +1,221 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spksupakorn/Currency-Converter/config"
+	"github.com/spksupakorn/Currency-Converter/internal/lifecycle"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories"
+	"github.com/spksupakorn/Currency-Converter/internal/repositories/memory"
+	"github.com/spksupakorn/Currency-Converter/internal/router"
+	"github.com/spksupakorn/Currency-Converter/pkg/logger"
+)
+
+// DefaultRates are served by the harness provider unless overridden.
+var DefaultRates = map[string]float64{"USD": 1, "THB": 36.5, "EUR": 0.92, "JPY": 150.25, "GBP": 0.79}
+
+// Harness is the full gin engine from router.NewRouterWithRepositories, wired
+// to in-memory repositories and a fake rate provider.
+type Harness struct {
+	T        testing.TB
+	Engine   *gin.Engine
+	Config   config.Config
+	Settings *config.Reloader
+	Repos    repositories.Repositories
+	Provider *RateProvider
+}
+
+// Option adjusts the configuration before the engine is built.
+type Option func(*config.Config)
+
+// Config returns a valid configuration for tests, pointing at providerURL.
+func Config(providerURL string) config.Config {
+	return config.Config{
+		Env:  "test",
+		Port: 8080,
+		// Never opened: the harness uses in-memory repositories.
+		DBDriver:            "sqlite",
+		DBPath:              ":memory:",
+		JWTSecret:           "test-secret",
+		JWTExpiry:           time.Hour,
+		AuthCacheTTL:        time.Minute,
+		AuthCacheSize:       100,
+		ExchangeAPIURL:      providerURL,
+		ExchangeAPIKey:      "test-key",
+		RateBaseCurrency:    "USD",
+		RateRefreshInterval: time.Hour,
+		RateStalePolicy:     config.StalePolicyWarn,
+		HTTPClientTimeout:   5 * time.Second,
+		RateLimitRequests:   1000,
+		RateLimitWindow:     time.Minute,
+		LogLevel:            "error",
+		LogRequestSample:    1,
+		UsageBufferSize:     1024,
+		UsageFlushInterval:  10 * time.Millisecond,
+
+		RateRetryAttempts:        3,
+		RateRetryBaseDelay:       time.Millisecond,
+		RateRetryMaxDelay:        10 * time.Millisecond,
+		RateFailureRetryInterval: 10 * time.Millisecond,
+		RateBreakerThreshold:     3,
+		RateBreakerCooldown:      time.Minute,
+
+		AlertMaxRules:         5,
+		WebhookTimeout:        time.Second,
+		WebhookMaxAttempts:    3,
+		WebhookRetryBaseDelay: time.Millisecond,
+		WebhookRetryMaxDelay:  10 * time.Millisecond,
+		WebhookPollInterval:   10 * time.Millisecond,
+		// Test receivers listen on loopback.
+		WebhookAllowedNetworks: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")},
+
+		EventsPollInterval: 10 * time.Millisecond,
+		EventsRetention:    time.Hour,
+
+		LeaderElection:   true,
+		LeaderLeaseTTL:   300 * time.Millisecond,
+		InstanceID:       "test",
+		RateSyncInterval: 50 * time.Millisecond,
+
+		ShutdownTimeout: 5 * time.Second,
+	}
+}
+
+func New(t testing.TB, opts ...Option) *Harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	provider := NewRateProvider("USD", DefaultRates)
+	t.Cleanup(provider.Close)
+
+	cfg := Config(provider.URL())
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid test config: %v", err)
+	}
+
+	log := logger.New(logger.Options{Env: cfg.Env, Level: cfg.LogLevel})
+	settings := config.NewReloader(cfg, func() (config.Config, error) { return cfg, nil })
+	repos := memory.NewRepositories()
+
+	// Stopped before the provider closes, so no worker calls it afterwards.
+	lc := lifecycle.New(log)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if err := lc.Stop(ctx); err != nil {
+			t.Errorf("stop workers: %v", err)
+		}
+	})
+
+	engine := gin.New()
+	router.UseMiddleware(engine, log, settings, lc)
+	if err := router.NewRouterWithRepositories(memory.Database{}, repos, log, settings, engine, lc); err != nil {
+		t.Fatalf("router: %v", err)
+	}
+
+	return &Harness{
+		T:        t,
+		Engine:   engine,
+		Config:   cfg,
+		Settings: settings,
+		Repos:    repos,
+		Provider: provider,
+	}
+}
+
+// Do sends a request through the engine. body, when not nil, is sent as JSON;
+// token, when not empty, as a bearer token.
+func (h *Harness) Do(method, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	h.T.Helper()
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			h.T.Fatalf("marshal body: %v", err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, r)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return h.Send(req)
+}
+
+// Send serves a prepared request, for tests that need custom headers.
+func (h *Harness) Send(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.Engine.ServeHTTP(rec, req)
+	return rec
+}
+
+// Decode unmarshals a JSON response body into v.
+func (h *Harness) Decode(rec *httptest.ResponseRecorder, v interface{}) {
+	h.T.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		h.T.Fatalf("decode response %q: %v", rec.Body.String(), err)
+	}
+}
+
+// Register creates an account and fails the test on any error.
+func (h *Harness) Register(email, password string) {
+	h.T.Helper()
+	rec := h.Do(http.MethodPost, "/api/v1/auth/register", map[string]string{"email": email, "password": password}, "")
+	if rec.Code != http.StatusCreated {
+		h.T.Fatalf("register %s: status %d: %s", email, rec.Code, rec.Body.String())
+	}
+}
+
+// Login returns an access token and fails the test on any error.
+func (h *Harness) Login(email, password string) string {
+	h.T.Helper()
+	rec := h.Do(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": email, "password": password}, "")
+	if rec.Code != http.StatusOK {
+		h.T.Fatalf("login %s: status %d: %s", email, rec.Code, rec.Body.String())
+	}
+	var out struct {
+		AccessToken string `json:"access_token"`
+	}
+	h.Decode(rec, &out)
+	return out.AccessToken
+}
+
+// WaitReady polls /readyz until the initial rate refresh has completed.
+func (h *Harness) WaitReady() {
+	h.T.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if h.Do(http.MethodGet, "/readyz", nil, "").Code == http.StatusOK {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	h.T.Fatalf("service not ready after 5s")
+}
+
+// WaitFor polls cond until it holds, failing the test after 5s.
+func (h *Harness) WaitFor(what string, cond func() bool) {
+	h.T.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			h.T.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,92 @@
+// Package testutil boots the full HTTP stack against in-memory repositories
+// and a fake exchange rate provider, for end-to-end tests.
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// RateProvider is a fake of the exchangerate-api "latest" endpoint
+// (GET /{key}/latest/{base}).
+type RateProvider struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	rates  map[string]map[string]float64
+	status int
+	hits   atomic.Int64
+}
+
+// NewRateProvider starts a provider serving rates for base. Close it with
+// Close (the harness does this automatically).
+func NewRateProvider(base string, rates map[string]float64) *RateProvider {
+	p := &RateProvider{rates: map[string]map[string]float64{}, status: http.StatusOK}
+	p.SetRates(base, rates)
+	p.Server = httptest.NewServer(http.HandlerFunc(p.serve))
+	return p
+}
+
+// URL returns the value to use for EXCHANGE_API_URL.
+func (p *RateProvider) URL() string {
+	return p.Server.URL + "/"
+}
+
+func (p *RateProvider) SetRates(base string, rates map[string]float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cp := make(map[string]float64, len(rates))
+	for k, v := range rates {
+		cp[k] = v
+	}
+	p.rates[base] = cp
+}
+
+// FailWith makes every following request answer with status; pass
+// http.StatusOK to recover.
+func (p *RateProvider) FailWith(status int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = status
+}
+
+// Hits returns the number of requests served so far.
+func (p *RateProvider) Hits() int64 {
+	return p.hits.Load()
+}
+
+func (p *RateProvider) serve(w http.ResponseWriter, r *http.Request) {
+	p.hits.Add(1)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[1] != "latest" {
+		http.NotFound(w, r)
+		return
+	}
+	base := parts[2]
+
+	p.mu.Lock()
+	status := p.status
+	rates, ok := p.rates[base]
+	p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]string{"result": "error", "error-type": "unavailable"})
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"result": "error", "error-type": "unsupported-code"})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"result":           "success",
+		"base_code":        base,
+		"conversion_rates": rates,
+	})
+}
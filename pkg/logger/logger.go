@@ -1,80 +1,203 @@
-package logger
-
-import (
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
-)
-
-type Fields map[string]interface{}
-
-type Logger struct {
-	*zap.Logger
-}
-
-func New(env string) *Logger {
-	var cfg zap.Config
-	if env == "production" {
-		cfg = zap.NewProductionConfig()
-		cfg.EncoderConfig.TimeKey = "ts"
-	} else {
-		cfg = zap.NewDevelopmentConfig()
-	}
-
-	core, err := cfg.Build()
-	if err != nil {
-		panic(err)
-	}
-	return &Logger{core}
-}
-
-func (l *Logger) WithFields(fields Fields) *Logger {
-	zf := make([]zap.Field, 0, len(fields))
-	for k, v := range fields {
-		zf = append(zf, zap.Any(k, v))
-	}
-	return &Logger{l.Logger.With(zf...)}
-}
-
-func (l *Logger) Info(msg string, fields ...Fields) {
-	l.Logger.Info(msg, convert(fields)...)
-}
-
-func (l *Logger) Error(msg string, fields ...Fields) {
-	l.Logger.Error(msg, convert(fields)...)
-}
-
-func (l *Logger) Fatal(msg string, fields ...Fields) {
-	l.Logger.Fatal(msg, convert(fields)...)
-}
-
-func convert(fs []Fields) []zap.Field {
-	if len(fs) == 0 {
-		return nil
-	}
-	fields := make([]zap.Field, 0, len(fs))
-	for _, f := range fs {
-		for k, v := range f {
-			fields = append(fields, zap.Any(k, v))
-		}
-	}
-	return fields
-}
-
-func Field(k string, v interface{}) zap.Field {
-	return zap.Any(k, v)
-}
-
-func Level(lv string) zapcore.Level {
-	switch lv {
-	case "debug":
-		return zapcore.DebugLevel
-	case "info":
-		return zapcore.InfoLevel
-	case "warn":
-		return zapcore.WarnLevel
-	case "error":
-		return zapcore.ErrorLevel
-	default:
-		return zapcore.InfoLevel
-	}
-}
+package logger
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type Fields map[string]interface{}
+
+type Logger struct {
+	*zap.Logger
+}
+
+// Options controls how the root logger is built. Empty Level and Format fall
+// back to defaults derived from Env.
+type Options struct {
+	Env    string
+	Level  string
+	Format string
+}
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are matched case-insensitively anywhere in a field name,
+// after stripping "-" and "_", so "X-Api-Key", "api_key" and "apiKey" all
+// match. sensitiveSuffixes only match at the end, which keeps fields such as
+// "token_version" readable while still masking "access_token".
+var (
+	sensitiveKeys = []string{
+		"authorization",
+		"password",
+		"passwd",
+		"secret",
+		"apikey",
+		"cookie",
+	}
+	sensitiveSuffixes = []string{
+		"token",
+		"dsn",
+	}
+)
+
+type ctxKey struct{}
+
+func New(opts Options) *Logger {
+	var cfg zap.Config
+	if opts.Env == "production" {
+		cfg = zap.NewProductionConfig()
+		cfg.EncoderConfig.TimeKey = "ts"
+	} else {
+		cfg = zap.NewDevelopmentConfig()
+	}
+	if opts.Level != "" {
+		cfg.Level = zap.NewAtomicLevelAt(Level(opts.Level))
+	}
+	switch opts.Format {
+	case "json":
+		cfg.Encoding = "json"
+		cfg.EncoderConfig.EncodeLevel = zapcore.LowercaseLevelEncoder
+	case "console":
+		cfg.Encoding = "console"
+	}
+
+	core, err := cfg.Build()
+	if err != nil {
+		panic(err)
+	}
+	return &Logger{core}
+}
+
+// NewContext returns a copy of ctx carrying l.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the request-scoped logger stored in ctx, or fallback
+// when there is none.
+func FromContext(ctx context.Context, fallback *Logger) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(ctxKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	return fallback
+}
+
+func (l *Logger) WithFields(fields Fields) *Logger {
+	return &Logger{l.Logger.With(convert([]Fields{fields})...)}
+}
+
+func (l *Logger) Debug(msg string, fields ...Fields) {
+	l.Logger.Debug(msg, convert(fields)...)
+}
+
+func (l *Logger) Info(msg string, fields ...Fields) {
+	l.Logger.Info(msg, convert(fields)...)
+}
+
+func (l *Logger) Warn(msg string, fields ...Fields) {
+	l.Logger.Warn(msg, convert(fields)...)
+}
+
+func (l *Logger) Error(msg string, fields ...Fields) {
+	l.Logger.Error(msg, convert(fields)...)
+}
+
+func (l *Logger) Fatal(msg string, fields ...Fields) {
+	l.Logger.Fatal(msg, convert(fields)...)
+}
+
+func convert(fs []Fields) []zap.Field {
+	if len(fs) == 0 {
+		return nil
+	}
+	fields := make([]zap.Field, 0, len(fs))
+	for _, f := range fs {
+		for k, v := range f {
+			fields = append(fields, Field(k, v))
+		}
+	}
+	return fields
+}
+
+// Field builds a zap field, masking the value when the key looks sensitive.
+func Field(k string, v interface{}) zap.Field {
+	return zap.Any(k, redact(k, v))
+}
+
+func redact(k string, v interface{}) interface{} {
+	if IsSensitive(k) {
+		return redacted
+	}
+	switch m := v.(type) {
+	case Fields:
+		return redactMap(m)
+	case map[string]interface{}:
+		return redactMap(m)
+	case map[string]string:
+		out := make(map[string]string, len(m))
+		for mk, mv := range m {
+			if IsSensitive(mk) {
+				mv = redacted
+			}
+			out[mk] = mv
+		}
+		return out
+	case http.Header:
+		out := make(http.Header, len(m))
+		for mk, mv := range m {
+			if IsSensitive(mk) {
+				mv = []string{redacted}
+			}
+			out[mk] = mv
+		}
+		return out
+	}
+	return v
+}
+
+func redactMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = redact(k, v)
+	}
+	return out
+}
+
+// IsSensitive reports whether a field with the given key must not be logged
+// verbatim.
+func IsSensitive(key string) bool {
+	k := strings.ToLower(key)
+	k = strings.NewReplacer("-", "", "_", "").Replace(k)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	for _, s := range sensitiveSuffixes {
+		if strings.HasSuffix(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func Level(lv string) zapcore.Level {
+	switch lv {
+	case "debug":
+		return zapcore.DebugLevel
+	case "info":
+		return zapcore.InfoLevel
+	case "warn":
+		return zapcore.WarnLevel
+	case "error":
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}